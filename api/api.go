@@ -20,6 +20,7 @@
 package api
 
 import (
+	"fmt"
 	"io"
 	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
@@ -27,7 +28,7 @@ import (
 )
 
 // MediaServer combines minimal interfaces for browsing and playing songs from remote server.
-// Mediaserver can additionally implement RemoteController, and Cacher.
+// Mediaserver can additionally implement RemoteController, Transcoder, PlaylistEditor, Favoriter and Cacher.
 type MediaServer interface {
 	Streamer
 	Browser
@@ -45,6 +46,56 @@ type Streamer interface {
 	Download(Song *models.Song) (io.ReadCloser, interfaces.AudioFormat, error)
 }
 
+// Transcoder is a Streamer that can transcode songs on server before streaming them.
+type Transcoder interface {
+	// SetQuality sets quality profile for streams that are started after this.
+	SetQuality(profile config.QualityProfile)
+}
+
+// PlaylistEditor can modify playlists on server. Songs in playlist are referred by their index,
+// since playlist can contain same song multiple times.
+type PlaylistEditor interface {
+	// CreatePlaylist creates new playlist with given songs.
+	CreatePlaylist(name string, songs []models.Id) (*models.Playlist, error)
+
+	// RenamePlaylist sets new name for playlist.
+	RenamePlaylist(playlist models.Id, name string) error
+
+	// DeletePlaylist deletes playlist.
+	DeletePlaylist(playlist models.Id) error
+
+	// AddPlaylistSongs appends songs to the end of playlist.
+	AddPlaylistSongs(playlist models.Id, songs []models.Id) error
+
+	// RemovePlaylistSongs removes songs in given indices from playlist.
+	RemovePlaylistSongs(playlist models.Id, indices []int) error
+
+	// MovePlaylistSong moves song in index to newIndex.
+	MovePlaylistSong(playlist models.Id, index, newIndex int) error
+
+	// SetPlaylistSongs replaces all songs in playlist.
+	SetPlaylistSongs(playlist models.Id, songs []models.Id) error
+}
+
+// Favoriter can mark songs as favorite on server.
+type Favoriter interface {
+	// SetFavorite adds songs to favorites, or removes them from favorites. If only some of the songs
+	// fail, it returns FavoriteError.
+	SetFavorite(songs []models.Id, favorite bool) error
+}
+
+// FavoriteError is returned when setting favorite failed for some songs, while rest of them were updated.
+type FavoriteError struct {
+	// Failed contains songs that were not updated
+	Failed []models.Id
+	// Err is error for first failed song
+	Err error
+}
+
+func (f *FavoriteError) Error() string {
+	return fmt.Sprintf("%d songs failed: %v", len(f.Failed), f.Err)
+}
+
 // Browser implements item-based viewing for music artists,albums,playlists etc.
 type Browser interface {
 
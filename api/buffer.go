@@ -19,215 +19,468 @@
 package api
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
 )
 
-func MimeToAudioFormat(mimeType string) (format interfaces.AudioFormat, err error) {
-	format = interfaces.AudioFormatNil
-	switch mimeType {
-	case "audio/mpeg":
-		format = interfaces.AudioFormatMp3
-	case "audio/flac":
-		format = interfaces.AudioFormatFlac
-	case "audio/ogg":
-		format = interfaces.AudioFormatOgg
-	case "audio/wav":
-		format = interfaces.AudioFormatWav
-
-	default:
-		err = fmt.Errorf("unidentified audio format: %s", mimeType)
-	}
-	return
-}
+// size of single read from http body
+const downloadChunkSize = 32 * 1024
 
 // StreamBuffer is a buffer that reads whole http body in the background and copies it to local buffer.
+// Data that has been read is kept in buffer, which allows seeking backwards in the stream. Buffer is kept
+// in memory up to config.Player.HttpBufferingLimitMem, after which it is moved to temporary file.
+// If connection drops, download is resumed with http range request from the last downloaded byte.
+// StreamBuffer implements io.ReadSeekCloser.
 type StreamBuffer struct {
-	lock           *sync.Mutex
-	url            string
-	headers        map[string]string
-	params         map[string]string
-	client         *http.Client
-	buff           *bytes.Buffer
-	bitrate        int
-	req            *http.Request
-	resp           *http.Response
-	cancelDownload chan bool
+	lock *sync.Mutex
+	// dataAvailable is signaled every time more data is downloaded or download is stopped
+	dataAvailable *sync.Cond
+	url           string
+	headers       map[string]string
+	params        map[string]string
+	client        *http.Client
+	data          *spillBuffer
+	position      int
+	// length of the stream, 0 if not known
+	length       int
+	downloadDone bool
+	// downloadErr is set if download was interrupted and could not be resumed
+	downloadErr error
+	closed      bool
+	// closing is closed when stream is closed
+	closing     chan struct{}
+	bitrate     int
+	contentType string
+	resp        *http.Response
 }
 
+// Read reads data from buffer. If there is no more data downloaded yet, Read blocks until there is
+// more data available or download is complete. If download failed, Read returns error after reading
+// all data that was downloaded.
 func (s *StreamBuffer) Read(p []byte) (n int, err error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	n, err = s.buff.Read(p)
+	for s.position >= s.data.Len() && !s.downloadDone {
+		s.dataAvailable.Wait()
+	}
+	if s.closed {
+		return 0, io.EOF
+	}
+	if s.position >= s.data.Len() {
+		if s.downloadErr != nil {
+			return 0, s.downloadErr
+		}
+		return 0, io.EOF
+	}
+	n, err = s.data.ReadAt(p, s.position)
+	s.position += n
 	return
 }
 
+// Seek sets position for next Read. Seeking past data that is not yet downloaded is allowed,
+// in which case next Read blocks until data is available.
+func (s *StreamBuffer) Seek(offset int64, whence int) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var position int64
+	switch whence {
+	case io.SeekStart:
+		position = offset
+	case io.SeekCurrent:
+		position = int64(s.position) + offset
+	case io.SeekEnd:
+		if s.downloadDone {
+			position = int64(s.data.Len()) + offset
+		} else if s.length > 0 {
+			position = int64(s.length) + offset
+		} else {
+			return int64(s.position), errors.New("seek from end: stream length unknown")
+		}
+	default:
+		return int64(s.position), fmt.Errorf("invalid whence: %d", whence)
+	}
+	if position < 0 {
+		return int64(s.position), fmt.Errorf("negative position: %d", position)
+	}
+	s.position = int(position)
+	return position, nil
+}
+
+// Close stops download and removes temporary file, if any.
 func (s *StreamBuffer) Close() error {
 	logrus.Debug("Close stream download")
-	return s.resp.Body.Close()
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.downloadDone = true
+	close(s.closing)
+	s.dataAvailable.Broadcast()
+	resp := s.resp
+	err := s.data.Close()
+	s.lock.Unlock()
+
+	if resp != nil {
+		bodyErr := resp.Body.Close()
+		if err == nil {
+			err = bodyErr
+		}
+	}
+	return err
 }
 
+// Len returns number of bytes that are downloaded but not yet read.
 func (s *StreamBuffer) Len() int {
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	return s.buff.Len()
+	return s.unread()
 }
 
 func (s *StreamBuffer) SecondsBuffered() int {
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	buffered := s.buff.Len()
-	return buffered / s.bitrate
+	if s.bitrate == 0 {
+		return 0
+	}
+	return s.unread() / s.bitrate
+}
+
+// Bitrate returns bitrate of the stream in kbit/s, estimated from its length and duration. If length is not
+// known, return 0.
+func (s *StreamBuffer) Bitrate() int {
+	return s.bitrate * 8 / 1000
 }
 
+func (s *StreamBuffer) unread() int {
+	if s.position >= s.data.Len() {
+		return 0
+	}
+	return s.data.Len() - s.position
+}
+
+// AudioFormat detects format from the beginning of the stream. Content-Type is used only if format cannot be
+// detected from content. This blocks until enough data is downloaded.
 func (s *StreamBuffer) AudioFormat() (format interfaces.AudioFormat, err error) {
-	if s.resp != nil {
-		return MimeToAudioFormat(s.resp.Header.Get("Content-Type"))
+	s.lock.Lock()
+	for s.data.Len() < sniffLength && !s.downloadDone {
+		s.dataAvailable.Wait()
 	}
-	return interfaces.AudioFormatNil, errors.New("no http response")
+	if s.closed {
+		s.lock.Unlock()
+		return interfaces.AudioFormatNil, errors.New("stream closed")
+	}
+	header := make([]byte, sniffLength)
+	n, err := s.data.ReadAt(header, 0)
+	s.lock.Unlock()
+	if err != nil && err != io.EOF {
+		return interfaces.AudioFormatNil, fmt.Errorf("read stream header: %v", err)
+	}
+	return detectAudioFormat(header[:n], s.contentType)
 }
 
+// NewStreamDownload starts downloading stream in the background. It returns once there is
+// config.Player.HttpBufferingS seconds of audio buffered, or whole stream is downloaded.
 func NewStreamDownload(url string, headers map[string]string, params map[string]string,
 	client *http.Client, duration int) (*StreamBuffer, error) {
 	stream := &StreamBuffer{
-		lock:           &sync.Mutex{},
-		url:            url,
-		headers:        headers,
-		params:         params,
-		bitrate:        duration,
-		buff:           bytes.NewBuffer(make([]byte, 0, 1024)),
-		cancelDownload: make(chan bool),
+		lock:    &sync.Mutex{},
+		url:     url,
+		headers: headers,
+		params:  params,
+		data:    &spillBuffer{memLimit: config.AppConfig.Player.HttpBufferingLimitMem * 1024 * 1024},
+		closing: make(chan struct{}),
 	}
+	stream.dataAvailable = sync.NewCond(stream.lock)
 	if client == nil {
 		client = http.DefaultClient
 	}
 	stream.client = client
 
-	var err error
-	stream.req, err = http.NewRequest(http.MethodGet, url, nil)
+	resp, err := stream.request(0)
 	if err != nil {
-		return stream, fmt.Errorf("init http request: %v", err)
+		return nil, err
 	}
-
-	for k, v := range headers {
-		stream.req.Header.Add(k, v)
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("http request error, statuscode: %d", resp.StatusCode)
 	}
+	stream.resp = resp
+	stream.contentType = resp.Header.Get("Content-Type")
 
-	if params != nil {
-		q := stream.req.URL.Query()
-		for i, v := range params {
-			q.Add(i, v)
+	length, err := strconv.Atoi(resp.Header.Get("Content-Length"))
+	if err == nil && length > 0 {
+		stream.length = length
+		if duration > 0 {
+			stream.bitrate = length / duration
 		}
-		stream.req.URL.RawQuery = q.Encode()
 	}
 
-	stream.resp, err = stream.client.Do(stream.req)
-	if err != nil {
-		return stream, fmt.Errorf("make http request: %v", err)
+	go stream.download()
 
+	stream.lock.Lock()
+	for !stream.downloadDone && stream.data.Len() < stream.bitrate*config.AppConfig.Player.HttpBufferingS {
+		stream.dataAvailable.Wait()
+	}
+	err = stream.downloadErr
+	stream.lock.Unlock()
+	if err != nil {
+		stream.Close()
+		return nil, fmt.Errorf("initial buffer failed: %v", err)
 	}
-	if stream.resp.StatusCode != 200 {
-		return stream, fmt.Errorf("http request error, statuscode: %d", stream.resp.StatusCode)
+	return stream, nil
+}
 
+// request makes http request for the stream, starting from given byte offset.
+func (s *StreamBuffer) request(offset int) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("init http request: %v", err)
+	}
+	for k, v := range s.headers {
+		req.Header.Add(k, v)
+	}
+	if s.params != nil {
+		q := req.URL.Query()
+		for i, v := range s.params {
+			q.Add(i, v)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
 	}
 
-	sLength := stream.resp.Header.Get("Content-Length")
-	length, err := strconv.Atoi(sLength)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("make http request: %v", err)
+	}
+	return resp, nil
+}
 
-	stream.bitrate = length / duration
+// download reads http body until stream is complete or closed. Interrupted download is resumed.
+func (s *StreamBuffer) download() {
+	logrus.Debug("Start buffered stream")
+	buf := make([]byte, downloadChunkSize)
 	for {
-		if stream.buff.Len() > stream.bitrate*config.AppConfig.Player.HttpBufferingS {
-			break
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			logrus.Debug("Stop buffered stream")
+			return
+		}
+		body := s.resp.Body
+		s.lock.Unlock()
+
+		n, err := body.Read(buf)
+
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			logrus.Debug("Stop buffered stream")
+			return
+		}
+		if n > 0 {
+			if _, writeErr := s.data.Write(buf[:n]); writeErr != nil {
+				s.lock.Unlock()
+				s.stopDownload(fmt.Errorf("write buffer: %v", writeErr))
+				return
+			}
+			s.dataAvailable.Broadcast()
 		}
-		failed := stream.readData()
-		if failed {
-			return stream, fmt.Errorf("initial buffer failed")
+		downloaded := s.data.Len()
+		s.lock.Unlock()
+
+		if err == nil {
+			continue
+		}
+		if err == io.EOF && (s.length == 0 || downloaded >= s.length) {
+			logrus.Debugf("buffer download complete")
+			s.stopDownload(nil)
+			return
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		logrus.Warningf("stream download interrupted at %d/%d bytes: %v", downloaded, s.length, err)
+		if !s.resume(downloaded) {
+			s.stopDownload(fmt.Errorf("download interrupted: %v", err))
+			return
 		}
 	}
-	go stream.bufferBackground()
-	return stream, err
 }
 
-func (s *StreamBuffer) bufferBackground() {
-	logrus.Debug("Start buffered stream")
-	timer := time.NewTimer(time.Millisecond)
-	defer timer.Stop()
-loop:
-	for {
+// resume reconnects to server and continues download from given offset. Return false if reconnecting
+// failed or stream was closed.
+func (s *StreamBuffer) resume(offset int) bool {
+	s.lock.Lock()
+	body := s.resp.Body
+	s.lock.Unlock()
+	body.Close()
+	delay := config.StreamReconnectDelay
+	for i := 0; i < config.StreamReconnectCount; i++ {
 		select {
-		case <-timer.C:
-			if s.buff.Len()/1024/1024 > config.AppConfig.Player.HttpBufferingLimitMem {
-				logrus.Tracef("Buffer is full")
-				timer.Reset(time.Second)
-			} else {
-				if !s.readData() {
-					timer.Reset(time.Second)
-				} else {
-					break loop
-				}
+		case <-s.closing:
+			return false
+		case <-time.After(delay):
+		}
+		delay *= 2
+
+		resp, err := s.request(offset)
+		if err == nil {
+			err = skipToOffset(resp, offset)
+			if err != nil {
+				resp.Body.Close()
 			}
-		case <-s.cancelDownload:
-			logrus.Debug("Stop buffered stream")
-			break loop
 		}
-	}
+		if err != nil {
+			logrus.Warningf("resume stream download (attempt %d): %v", i+1, err)
+			continue
+		}
 
-	close(s.cancelDownload)
-	s.cancelDownload = nil
+		s.lock.Lock()
+		closed := s.closed
+		if !closed {
+			s.resp = resp
+		}
+		s.lock.Unlock()
+		if closed {
+			resp.Body.Close()
+			return false
+		}
+		logrus.Debugf("resumed stream download at %d bytes", offset)
+		return true
+	}
+	return false
 }
 
-func (s *StreamBuffer) readData() bool {
-	var nHttp int
-	var nBuff int
-	var err error
-	buf := make([]byte, s.bitrate*5)
-
+// stopDownload marks download as completed, or failed if err is not nil.
+func (s *StreamBuffer) stopDownload(err error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	nHttp, err = s.resp.Body.Read(buf)
-	stop := false
-	if err != nil {
-		if err == io.EOF {
-			if nHttp == 0 {
-				logrus.Debugf("buffer download complete")
-				stop = true
-			} else {
-				// pass
-			}
-		} else {
-			logrus.Errorf("buffer read bytes from body: %v", err)
-			stop = true
+	s.downloadDone = true
+	s.downloadErr = err
+	s.dataAvailable.Broadcast()
+}
+
+// skipToOffset ensures response body begins at given offset. If server ignored range request and
+// responded with whole content, skip to offset.
+func skipToOffset(resp *http.Response, offset int) error {
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, err := contentRangeStart(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return err
 		}
+		if start != offset {
+			return fmt.Errorf("server responded with range starting at %d, expected %d", start, offset)
+		}
+		return nil
+	case http.StatusOK:
+		_, err := io.CopyN(ioutil.Discard, resp.Body, int64(offset))
+		if err != nil {
+			return fmt.Errorf("skip to offset %d: %v", offset, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("http request error, statuscode: %d", resp.StatusCode)
 	}
+}
+
+// contentRangeStart parses first byte position from Content-Range header, e.g. 'bytes 100-999/1000'.
+func contentRangeStart(header string) (int, error) {
+	if !strings.HasPrefix(header, "bytes ") {
+		return 0, fmt.Errorf("invalid content-range: '%s'", header)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(header, "bytes "), "-", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid content-range: '%s'", header)
+	}
+	start, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid content-range: '%s'", header)
+	}
+	return start, nil
+}
 
-	buf = buf[0:nHttp]
-	if nHttp > 0 {
-		nBuff, err = s.buff.Write(buf)
+// spillBuffer is an append-only buffer that keeps data in memory until it grows larger than memLimit,
+// after which all data is moved to temporary file. SpillBuffer is not safe for concurrent use.
+type spillBuffer struct {
+	memLimit int
+	mem      []byte
+	file     *os.File
+	size     int
+}
+
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	if b.file == nil && len(b.mem)+len(p) > b.memLimit {
+		file, err := ioutil.TempFile("", config.AppNameLower+"-stream-")
 		if err != nil {
-			if err == io.EOF {
-			} else {
-				logrus.Warningf("Copy buffer: %v", err)
-			}
+			return 0, fmt.Errorf("create temporary file: %v", err)
 		}
-		if nBuff != nHttp {
-			logrus.Warningf("incomplete buffer read: have %d B, want %d B", nBuff, nHttp)
+		logrus.Debugf("Stream buffer exceeds memory limit, move buffer to %s", file.Name())
+		if _, err := file.Write(b.mem); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return 0, fmt.Errorf("write temporary file: %v", err)
 		}
+		b.file = file
+		b.mem = nil
+	}
+	if b.file != nil {
+		n, err := b.file.WriteAt(p, int64(b.size))
+		b.size += n
+		return n, err
 	}
-	size := s.buff.Len()
-	if size > 0 && s.bitrate > 0 {
-		logrus.Tracef("Buffer: %d KiB, %d sec, bitrate %d bit/s", size/1024, size/s.bitrate, s.bitrate)
-	} else {
-		logrus.Tracef("Empty buffer, size %d B, bitrate %d", size, s.bitrate)
+	b.mem = append(b.mem, p...)
+	b.size += len(p)
+	return len(p), nil
+}
 
+// ReadAt reads data starting from offset. Unlike io.ReaderAt, ReadAt returns available data without error,
+// if p is longer than remaining data.
+func (b *spillBuffer) ReadAt(p []byte, offset int) (int, error) {
+	if offset >= b.size {
+		return 0, io.EOF
+	}
+	if len(p) > b.size-offset {
+		p = p[:b.size-offset]
+	}
+	if b.file != nil {
+		return b.file.ReadAt(p, int64(offset))
+	}
+	return copy(p, b.mem[offset:]), nil
+}
+
+// Len returns size of the data.
+func (b *spillBuffer) Len() int {
+	return b.size
+}
+
+// Close frees buffer and removes temporary file.
+func (b *spillBuffer) Close() error {
+	b.mem = nil
+	b.size = 0
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	err := b.file.Close()
+	b.file = nil
+	if removeErr := os.Remove(name); removeErr != nil && err == nil {
+		err = removeErr
 	}
-	return stop
+	return err
 }
@@ -0,0 +1,215 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"tryffel.net/go/jellycli/config"
+)
+
+// streamServer serves content with range requests. It drops connection in the middle of response
+// for first drops requests.
+type streamServer struct {
+	content     []byte
+	dropAfter   int
+	ignoreRange bool
+	failResume  bool
+
+	lock   sync.Mutex
+	drops  int
+	ranges []string
+}
+
+func (s *streamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rangeHeader := r.Header.Get("Range")
+	s.lock.Lock()
+	s.ranges = append(s.ranges, rangeHeader)
+	drop := s.drops > 0
+	if drop {
+		s.drops--
+	}
+	s.lock.Unlock()
+
+	start := 0
+	if rangeHeader != "" {
+		if s.failResume {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if !s.ignoreRange {
+			fmt.Sscanf(rangeHeader, "bytes=%d-", &start)
+		}
+	}
+
+	body := s.content[start:]
+	w.Header().Set("Content-Type", "audio/flac")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if start > 0 {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(s.content)-1, len(s.content)))
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	if drop {
+		w.Write(body[:s.dropAfter])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	w.Write(body)
+}
+
+func (s *streamServer) requests() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.ranges
+}
+
+func testContent(size int) []byte {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+func TestStreamBuffer(t *testing.T) {
+	oldConfig, oldDelay := config.AppConfig, config.StreamReconnectDelay
+	defer func() { config.AppConfig, config.StreamReconnectDelay = oldConfig, oldDelay }()
+	config.StreamReconnectDelay = time.Millisecond
+	content := testContent(1024 * 1024)
+	tests := []struct {
+		name        string
+		drops       int
+		ignoreRange bool
+		failResume  bool
+		spill       bool
+		// number of requests made
+		wantRequests int
+		wantErr      bool
+	}{
+		{name: "complete", wantRequests: 1},
+		{name: "resume", drops: 1, wantRequests: 2},
+		{name: "resume twice", drops: 2, wantRequests: 3},
+		{name: "server ignores range", drops: 1, ignoreRange: true, wantRequests: 2},
+		{name: "resume fails", drops: 1, failResume: true, wantRequests: 1 + config.StreamReconnectCount,
+			wantErr: true},
+		{name: "spill to file", drops: 1, spill: true, wantRequests: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := 20
+			if tt.spill {
+				limit = 0
+			}
+			config.AppConfig = &config.Config{Player: config.Player{HttpBufferingS: 1, HttpBufferingLimitMem: limit}}
+
+			handler := &streamServer{content: content, dropAfter: 300 * 1024, drops: tt.drops,
+				ignoreRange: tt.ignoreRange, failResume: tt.failResume}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			stream, err := NewStreamDownload(server.URL, nil, nil, server.Client(), 10)
+			if err != nil {
+				t.Fatalf("new stream: %v", err)
+			}
+			defer stream.Close()
+
+			got, err := ioutil.ReadAll(stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("read stream, error: %v, want error: %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !bytes.Equal(got, content[:len(got)]) {
+					t.Errorf("read stream: content differs")
+				}
+				return
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("read stream: content differs, got %d bytes, want %d", len(got), len(content))
+			}
+
+			requests := handler.requests()
+			if len(requests) != tt.wantRequests {
+				t.Errorf("requests, got: %v, want: %d requests", requests, tt.wantRequests)
+			}
+			for _, header := range requests[1:] {
+				if !strings.HasPrefix(header, "bytes=") {
+					t.Errorf("resume request without range: '%s'", header)
+				}
+			}
+
+			// seek back to already read data
+			if _, err := stream.Seek(1000, io.SeekStart); err != nil {
+				t.Fatalf("seek: %v", err)
+			}
+			got, err = ioutil.ReadAll(stream)
+			if err != nil || !bytes.Equal(got, content[1000:]) {
+				t.Errorf("read after seek, got: %d bytes, %v, want: %d bytes", len(got), err, len(content)-1000)
+			}
+
+			stream.lock.Lock()
+			file := stream.data.file
+			stream.lock.Unlock()
+			if (file != nil) != tt.spill {
+				t.Fatalf("buffer file exists, got: %t, want: %t", file != nil, tt.spill)
+			}
+			if err := stream.Close(); err != nil {
+				t.Errorf("close stream: %v", err)
+			}
+			if file != nil {
+				if _, err := os.Stat(file.Name()); !os.IsNotExist(err) {
+					t.Errorf("buffer file not removed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func Test_contentRangeStart(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int
+		wantErr bool
+	}{
+		{header: "bytes 100-999/1000", want: 100},
+		{header: "bytes 0-999/*", want: 0},
+		{header: "bytes */1000", wantErr: true},
+		{header: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, err := contentRangeStart(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("contentRangeStart() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("contentRangeStart() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
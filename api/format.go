@@ -0,0 +1,138 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"mime"
+	"tryffel.net/go/jellycli/interfaces"
+)
+
+// sniffLength is number of bytes needed from beginning of stream to detect its format
+const sniffLength = 64
+
+// errUnknownFormat is returned when format cannot be detected from content
+var errUnknownFormat = errors.New("unknown audio format")
+
+// mimeTypes maps mime types, including common variants, to audio formats
+var mimeTypes = map[string]interfaces.AudioFormat{
+	"audio/mpeg":         interfaces.AudioFormatMp3,
+	"audio/mp3":          interfaces.AudioFormatMp3,
+	"audio/mpeg3":        interfaces.AudioFormatMp3,
+	"audio/x-mpeg":       interfaces.AudioFormatMp3,
+	"audio/x-mp3":        interfaces.AudioFormatMp3,
+	"audio/flac":         interfaces.AudioFormatFlac,
+	"audio/x-flac":       interfaces.AudioFormatFlac,
+	"audio/ogg":          interfaces.AudioFormatOgg,
+	"audio/x-ogg":        interfaces.AudioFormatOgg,
+	"audio/vorbis":       interfaces.AudioFormatOgg,
+	"audio/x-vorbis+ogg": interfaces.AudioFormatOgg,
+	"application/ogg":    interfaces.AudioFormatOgg,
+	"audio/wav":          interfaces.AudioFormatWav,
+	"audio/x-wav":        interfaces.AudioFormatWav,
+	"audio/wave":         interfaces.AudioFormatWav,
+	"audio/vnd.wave":     interfaces.AudioFormatWav,
+}
+
+// MimeToAudioFormat returns audio format for Content-Type. Parameters, e.g. charset, are ignored.
+func MimeToAudioFormat(mimeType string) (format interfaces.AudioFormat, err error) {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return interfaces.AudioFormatNil, fmt.Errorf("unidentified audio format: %s", mimeType)
+	}
+	format, ok := mimeTypes[mediaType]
+	if !ok {
+		return interfaces.AudioFormatNil, fmt.Errorf("unidentified audio format: %s", mimeType)
+	}
+	return format, nil
+}
+
+// SniffAudioFormat detects audio format from magic bytes in the beginning of the stream. Formats and codecs
+// that cannot be decoded return an error that describes them.
+func SniffAudioFormat(header []byte) (interfaces.AudioFormat, error) {
+	switch {
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return interfaces.AudioFormatFlac, nil
+	case bytes.HasPrefix(header, []byte("OggS")):
+		return sniffOgg(header)
+	case bytes.HasPrefix(header, []byte("ID3")):
+		return interfaces.AudioFormatMp3, nil
+	case len(header) >= 12 && bytes.HasPrefix(header, []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return interfaces.AudioFormatWav, nil
+	case len(header) >= 8 && bytes.Equal(header[4:8], []byte("ftyp")):
+		return interfaces.AudioFormatNil, errors.New("mp4 container (aac or alac) is not supported")
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0:
+		// mpeg frame sync, layer is in bits 1-2: 01 is layer III, 00 is used by aac
+		switch (header[1] >> 1) & 0x3 {
+		case 1:
+			return interfaces.AudioFormatMp3, nil
+		case 0:
+			return interfaces.AudioFormatNil, errors.New("aac stream is not supported")
+		default:
+			return interfaces.AudioFormatNil, errors.New("mpeg audio layer I / II is not supported")
+		}
+	}
+	return interfaces.AudioFormatNil, errUnknownFormat
+}
+
+// sniffOgg detects codec from first packet of ogg stream. If header is too short, assume vorbis.
+func sniffOgg(header []byte) (interfaces.AudioFormat, error) {
+	if len(header) < 27 {
+		return interfaces.AudioFormatOgg, nil
+	}
+	// page header is 27 bytes and segment table, first packet follows
+	start := 27 + int(header[26])
+	if len(header) < start+8 {
+		return interfaces.AudioFormatOgg, nil
+	}
+	packet := header[start:]
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x01vorbis")):
+		return interfaces.AudioFormatOgg, nil
+	case bytes.HasPrefix(packet, []byte("OpusHead")):
+		return interfaces.AudioFormatNil, errors.New("opus codec in ogg container is not supported")
+	case bytes.HasPrefix(packet, []byte("\x7fFLAC")):
+		return interfaces.AudioFormatNil, errors.New("flac codec in ogg container is not supported")
+	default:
+		return interfaces.AudioFormatNil, errors.New("ogg container with unknown codec")
+	}
+}
+
+// detectAudioFormat detects format from content. Content-Type is used only if content is not recognized.
+func detectAudioFormat(header []byte, contentType string) (interfaces.AudioFormat, error) {
+	hint, hintErr := MimeToAudioFormat(contentType)
+	format, err := SniffAudioFormat(header)
+	if err == nil {
+		if hintErr == nil && hint != format {
+			logrus.Warningf("stream content-type is %s, but content is %s", contentType, format)
+		}
+		return format, nil
+	}
+	if err != errUnknownFormat {
+		return interfaces.AudioFormatNil, err
+	}
+	if hintErr != nil {
+		return interfaces.AudioFormatNil, fmt.Errorf("unknown audio format, content-type: %s", contentType)
+	}
+	logrus.Debugf("cannot detect audio format from content, use content-type %s", contentType)
+	return hint, nil
+}
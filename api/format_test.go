@@ -0,0 +1,124 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"testing"
+	"tryffel.net/go/jellycli/interfaces"
+)
+
+// oggPage returns beginning of ogg stream with one segment, which contains packet.
+func oggPage(packet string) []byte {
+	header := make([]byte, 27, 64)
+	copy(header, "OggS")
+	header[26] = 1
+	header = append(header, byte(len(packet)))
+	return append(header, packet...)
+}
+
+func TestMimeToAudioFormat(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     interfaces.AudioFormat
+		wantErr  bool
+	}{
+		{mimeType: "audio/mpeg", want: interfaces.AudioFormatMp3},
+		{mimeType: "audio/mp3", want: interfaces.AudioFormatMp3},
+		{mimeType: "audio/x-flac", want: interfaces.AudioFormatFlac},
+		{mimeType: "audio/vorbis", want: interfaces.AudioFormatOgg},
+		{mimeType: "audio/x-wav", want: interfaces.AudioFormatWav},
+		{mimeType: "Audio/FLAC; charset=binary", want: interfaces.AudioFormatFlac},
+		{mimeType: "application/octet-stream", wantErr: true},
+		{mimeType: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mimeType, func(t *testing.T) {
+			got, err := MimeToAudioFormat(tt.mimeType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MimeToAudioFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("MimeToAudioFormat() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniffAudioFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []byte
+		want    interfaces.AudioFormat
+		wantErr bool
+	}{
+		{name: "flac", header: []byte("fLaC\x00\x00\x00\x22"), want: interfaces.AudioFormatFlac},
+		{name: "vorbis", header: oggPage("\x01vorbis\x00\x00\x00\x00"), want: interfaces.AudioFormatOgg},
+		{name: "short ogg", header: []byte("OggS\x00\x02"), want: interfaces.AudioFormatOgg},
+		{name: "opus", header: oggPage("OpusHead\x01\x02"), wantErr: true},
+		{name: "ogg flac", header: oggPage("\x7fFLAC\x01\x00\x00\x01"), wantErr: true},
+		{name: "id3", header: []byte("ID3\x04\x00\x00\x00\x00\x00\x00"), want: interfaces.AudioFormatMp3},
+		{name: "mpeg frame", header: []byte{0xFF, 0xFB, 0x90, 0x64}, want: interfaces.AudioFormatMp3},
+		{name: "aac", header: []byte{0xFF, 0xF1, 0x50, 0x80}, wantErr: true},
+		{name: "wav", header: []byte("RIFF\x24\x08\x00\x00WAVEfmt "), want: interfaces.AudioFormatWav},
+		{name: "riff without wave", header: []byte("RIFF\x24\x08\x00\x00AVI LIST"), wantErr: true},
+		{name: "mp4", header: []byte("\x00\x00\x00\x20ftypM4A "), wantErr: true},
+		{name: "html", header: []byte("<html><body>error</body></html>"), wantErr: true},
+		{name: "empty", header: []byte{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SniffAudioFormat(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SniffAudioFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SniffAudioFormat() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_detectAudioFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      []byte
+		contentType string
+		want        interfaces.AudioFormat
+		wantErr     bool
+	}{
+		{name: "content wins", header: []byte("fLaC"), contentType: "audio/mpeg", want: interfaces.AudioFormatFlac},
+		{name: "octet stream", header: []byte("fLaC"), contentType: "application/octet-stream",
+			want: interfaces.AudioFormatFlac},
+		{name: "content-type as hint", header: []byte{0x00, 0x01}, contentType: "audio/mpeg",
+			want: interfaces.AudioFormatMp3},
+		{name: "unsupported codec", header: oggPage("OpusHead\x01\x02"), contentType: "audio/ogg", wantErr: true},
+		{name: "unknown", header: []byte("<html>"), contentType: "text/html", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectAudioFormat(tt.header, tt.contentType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detectAudioFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("detectAudioFormat() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
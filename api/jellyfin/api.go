@@ -72,6 +72,9 @@ type Jellyfin struct {
 	socketState socketState
 
 	remoteControlEnabled bool
+
+	qualityLock sync.Mutex
+	quality     config.QualityProfile
 }
 
 func (jf *Jellyfin) AuthOk() error {
@@ -28,6 +28,36 @@ import (
 	"tryffel.net/go/jellycli/util"
 )
 
+// directPlayCodecs restricts codecs that are played directly in containers that can hold several codecs.
+// E.g. ogg files with opus codec must be transcoded.
+var directPlayCodecs = map[interfaces.AudioFormat]string{
+	interfaces.AudioFormatOgg: "vorbis",
+}
+
+// directPlayContainer returns container for universal audio endpoint, in format 'container|codec',
+// if codec is restricted.
+func directPlayContainer(format interfaces.AudioFormat) string {
+	if codec, ok := directPlayCodecs[format]; ok {
+		return format.String() + "|" + codec
+	}
+	return format.String()
+}
+
+// directPlayProfiles returns device profile for formats that player can decode.
+func directPlayProfiles() []map[string]string {
+	profiles := make([]map[string]string, len(interfaces.SupportedAudioFormats))
+	for i, v := range interfaces.SupportedAudioFormats {
+		profiles[i] = map[string]string{
+			"Type":      "Audio",
+			"Container": v.String(),
+		}
+		if codec, ok := directPlayCodecs[v]; ok {
+			profiles[i]["AudioCodec"] = codec
+		}
+	}
+	return profiles
+}
+
 func (jf *Jellyfin) Download(song *models.Song) (io.ReadCloser, interfaces.AudioFormat, error) {
 	return jf.Stream(song)
 }
@@ -36,23 +66,31 @@ func (jf *Jellyfin) Stream(song *models.Song) (rc io.ReadCloser, format interfac
 	format = interfaces.AudioFormatNil
 	params := jf.defaultParams()
 	ptr := params.ptr()
-	ptr["MaxStreamingBitrate"] = "140000000"
 	ptr["AudioSamplingRate"] = fmt.Sprint(config.AudioSamplingRate)
-	formats := ""
-	for i, v := range interfaces.SupportedAudioFormats {
-		if i > 0 {
-			formats += ","
-		}
-		formats += v.String()
-	}
-	ptr["Container"] = formats
+	jf.qualityLock.Lock()
+	params.setTranscoding(jf.quality)
+	jf.qualityLock.Unlock()
 	// Every new request requires new playsession
 	jf.SessionId = util.RandomKey(20)
 	ptr["PlaySessionId"] = jf.SessionId
 	url := jf.host + "/Audio/" + song.Id.String() + "/universal"
 	var stream *api.StreamBuffer
 	stream, err = api.NewStreamDownload(url, map[string]string{"X-Emby-Token": jf.token}, *params, jf.client, song.Duration)
-	rc = stream
+	if err != nil {
+		return nil, format, err
+	}
 	format, err = stream.AudioFormat()
+	if err != nil {
+		stream.Close()
+		return nil, interfaces.AudioFormatNil, err
+	}
+	rc = stream
 	return
 }
+
+// SetQuality sets quality profile for streams.
+func (jf *Jellyfin) SetQuality(profile config.QualityProfile) {
+	jf.qualityLock.Lock()
+	defer jf.qualityLock.Unlock()
+	jf.quality = profile
+}
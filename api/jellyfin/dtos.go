@@ -236,6 +236,10 @@ type song struct {
 	DiscNumber     int      `json:"ParentIndexNumber"`
 	Artists        []nameId `json:"ArtistItems"`
 
+	// NormalizationGain is gain in dB, newer servers. Older servers only report LUFS.
+	NormalizationGain *float64 `json:"NormalizationGain"`
+	Lufs              *float64 `json:"LUFS"`
+
 	UserData userData `json:"UserData"`
 }
 
@@ -267,7 +271,20 @@ func (s *song) toSong() *models.Song {
 		DiscNumber: s.DiscNumber,
 		Artists:    artists,
 		Favorite:   s.UserData.IsFavorite,
+		PlayCount:  s.UserData.PlayCount,
+		TrackGain:  s.gain(),
+	}
+}
+
+// gain returns song normalization gain in dB
+func (s *song) gain() float64 {
+	if s.NormalizationGain != nil {
+		return *s.NormalizationGain
+	}
+	if s.Lufs != nil {
+		return normalizationTargetLufs - *s.Lufs
 	}
+	return 0
 }
 
 type collections struct {
@@ -0,0 +1,58 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package jellyfin
+
+import (
+	"fmt"
+	"io"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/models"
+)
+
+// SetFavorite adds songs to favorites, or removes them from favorites. Every song is tried, and songs
+// that failed are returned in api.FavoriteError.
+func (jf *Jellyfin) SetFavorite(songs []models.Id, favorite bool) error {
+	var failed *api.FavoriteError
+	for _, id := range songs {
+		url := fmt.Sprintf("/Users/%s/FavoriteItems/%s", jf.userId, id)
+		var resp io.ReadCloser
+		var err error
+		if favorite {
+			resp, err = jf.post(url, nil, jf.defaultParams())
+		} else {
+			resp, err = jf.delete(url, jf.defaultParams())
+		}
+		if resp != nil {
+			resp.Close()
+		}
+		if err != nil {
+			if failed == nil {
+				failed = &api.FavoriteError{Err: fmt.Errorf("set favorite: %v", err)}
+			}
+			failed.Failed = append(failed.Failed, id)
+			continue
+		}
+		// cached song has old favorite status
+		jf.cache.Delete(id)
+	}
+	if failed != nil {
+		return failed
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package jellyfin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/models"
+)
+
+func TestJellyfin_SetFavorite(t *testing.T) {
+	lock := sync.Mutex{}
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		lock.Unlock()
+		if strings.HasSuffix(r.URL.Path, "/failing") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c, _ := NewCache()
+	jf := &Jellyfin{host: server.URL, client: server.Client(), cache: c, userId: "user-1"}
+
+	tests := []struct {
+		name     string
+		songs    []models.Id
+		favorite bool
+		want     []string
+		// songs that fail
+		wantFailed []models.Id
+	}{
+		{
+			name:     "add favorites",
+			songs:    []models.Id{"song-1", "song-2"},
+			favorite: true,
+			want:     []string{"POST /Users/user-1/FavoriteItems/song-1", "POST /Users/user-1/FavoriteItems/song-2"},
+		},
+		{
+			name:     "remove favorite",
+			songs:    []models.Id{"song-1"},
+			favorite: false,
+			want:     []string{"DELETE /Users/user-1/FavoriteItems/song-1"},
+		},
+		{
+			name:       "continue after failure",
+			songs:      []models.Id{"failing", "song-1"},
+			favorite:   true,
+			want:       []string{"POST /Users/user-1/FavoriteItems/failing", "POST /Users/user-1/FavoriteItems/song-1"},
+			wantFailed: []models.Id{"failing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lock.Lock()
+			requests = nil
+			lock.Unlock()
+
+			c.Put("song-1", &models.Song{Id: "song-1"}, true)
+			for _, id := range tt.wantFailed {
+				c.Put(id, &models.Song{Id: id}, true)
+			}
+			err := jf.SetFavorite(tt.songs, tt.favorite)
+			if tt.wantFailed == nil && err != nil {
+				t.Fatalf("set favorite: %v", err)
+			}
+			if tt.wantFailed != nil {
+				favoriteErr, ok := err.(*api.FavoriteError)
+				if !ok {
+					t.Fatalf("expected api.FavoriteError, got: %v", err)
+				}
+				if !reflect.DeepEqual(favoriteErr.Failed, tt.wantFailed) {
+					t.Errorf("failed songs, got: %v, want: %v", favoriteErr.Failed, tt.wantFailed)
+				}
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if !reflect.DeepEqual(requests, tt.want) {
+				t.Errorf("requests, got: %v, want: %v", requests, tt.want)
+			}
+			if c.GetSong("song-1") != nil {
+				t.Errorf("song with old favorite status is still cached")
+			}
+			for _, id := range tt.wantFailed {
+				if c.GetSong(id) == nil {
+					t.Errorf("failed song %s removed from cache", id)
+				}
+			}
+		})
+	}
+}
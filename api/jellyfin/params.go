@@ -20,6 +20,8 @@ package jellyfin
 
 import (
 	"strconv"
+	"strings"
+	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
 	"tryffel.net/go/jellycli/models"
 )
@@ -37,6 +39,47 @@ func (p *params) setPaging(paging interfaces.Paging) {
 	ptr["StartIndex"] = strconv.Itoa(paging.Offset())
 }
 
+// maxStreamingBitrate is bitrate limit in bit/s, when quality profile has no limit
+const maxStreamingBitrate = 140000000
+
+// transcodingCodecs maps quality profile codec to jellyfin audio codec
+var transcodingCodecs = map[string]string{
+	"mp3":  "mp3",
+	"ogg":  "vorbis",
+	"flac": "flac",
+}
+
+// setTranscoding sets parameters for universal audio endpoint. Songs in given codec are streamed as is, if
+// they are within bitrate limit, and everything else is transcoded. Profile without codec allows any format
+// that player supports, and other formats, e.g. opus or aac, are transcoded to flac, or to mp3 if bitrate
+// is limited.
+func (p *params) setTranscoding(profile config.QualityProfile) {
+	ptr := p.ptr()
+	bitrate := maxStreamingBitrate
+	if profile.MaxBitrateKbps > 0 {
+		bitrate = profile.MaxBitrateKbps * 1000
+	}
+	ptr["MaxStreamingBitrate"] = strconv.Itoa(bitrate)
+
+	transcoding := profile.Codec
+	if _, ok := transcodingCodecs[transcoding]; ok {
+		ptr["Container"] = directPlayContainer(interfaces.AudioFormat(transcoding))
+	} else {
+		containers := make([]string, len(interfaces.SupportedAudioFormats))
+		for i, v := range interfaces.SupportedAudioFormats {
+			containers[i] = directPlayContainer(v)
+		}
+		ptr["Container"] = strings.Join(containers, ",")
+		transcoding = "flac"
+		if profile.MaxBitrateKbps > 0 {
+			transcoding = "mp3"
+		}
+	}
+	ptr["TranscodingContainer"] = transcoding
+	ptr["TranscodingProtocol"] = "http"
+	ptr["AudioCodec"] = transcodingCodecs[transcoding]
+}
+
 func (p *params) setLimit(n int) {
 	(*p)["Limit"] = strconv.Itoa(n)
 }
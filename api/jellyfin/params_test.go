@@ -21,6 +21,7 @@ package jellyfin
 import (
 	"reflect"
 	"testing"
+	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
 )
 
@@ -53,3 +54,54 @@ func Test_params_setPaging(t *testing.T) {
 		})
 	}
 }
+
+func Test_params_setTranscoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile config.QualityProfile
+		want    params
+	}{
+		{
+			name:    "original",
+			profile: config.QualityProfile{Name: "lossless"},
+			want: params{
+				"MaxStreamingBitrate":  "140000000",
+				"Container":            "flac,mp3,ogg|vorbis,wav",
+				"TranscodingContainer": "flac",
+				"TranscodingProtocol":  "http",
+				"AudioCodec":           "flac",
+			},
+		},
+		{
+			name:    "limited original",
+			profile: config.QualityProfile{Name: "limited", MaxBitrateKbps: 500},
+			want: params{
+				"MaxStreamingBitrate":  "500000",
+				"Container":            "flac,mp3,ogg|vorbis,wav",
+				"TranscodingContainer": "mp3",
+				"TranscodingProtocol":  "http",
+				"AudioCodec":           "mp3",
+			},
+		},
+		{
+			name:    "transcode",
+			profile: config.QualityProfile{Name: "mobile", Codec: "ogg", MaxBitrateKbps: 128},
+			want: params{
+				"MaxStreamingBitrate":  "128000",
+				"Container":            "ogg|vorbis",
+				"TranscodingContainer": "ogg",
+				"TranscodingProtocol":  "http",
+				"AudioCodec":           "vorbis",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := params{}
+			p.setTranscoding(tt.profile)
+			if !reflect.DeepEqual(p, tt.want) {
+				t.Errorf("setTranscoding, got: %v, want: %v", p, tt.want)
+			}
+		})
+	}
+}
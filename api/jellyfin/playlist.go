@@ -0,0 +1,210 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package jellyfin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"tryffel.net/go/jellycli/models"
+)
+
+// playlistEntry is an item in playlist. Jellyfin refers to playlist items by entry id,
+// since same song can be in playlist multiple times.
+type playlistEntry struct {
+	Id      string `json:"Id"`
+	EntryId string `json:"PlaylistItemId"`
+}
+
+type playlistEntries struct {
+	Entries []playlistEntry `json:"Items"`
+}
+
+// CreatePlaylist creates new audio playlist.
+func (jf *Jellyfin) CreatePlaylist(name string, songs []models.Id) (*models.Playlist, error) {
+	data := map[string]interface{}{
+		"Name":      name,
+		"Ids":       idsToStrings(songs),
+		"UserId":    jf.userId,
+		"MediaType": mediaTypeSong,
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("json: %v", err)
+	}
+	resp, err := jf.post("/Playlists", &body, jf.defaultParams())
+	if resp != nil {
+		defer resp.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create playlist: %v", err)
+	}
+
+	dto := struct {
+		Id string `json:"Id"`
+	}{}
+	err = json.NewDecoder(resp).Decode(&dto)
+	if err != nil {
+		return nil, fmt.Errorf("decode json: %v", err)
+	}
+	return &models.Playlist{Id: models.Id(dto.Id), Name: name, SongCount: len(songs)}, nil
+}
+
+// RenamePlaylist renames playlist.
+func (jf *Jellyfin) RenamePlaylist(playlist models.Id, name string) error {
+	body, err := json.Marshal(map[string]string{"Name": name})
+	if err != nil {
+		return fmt.Errorf("json: %v", err)
+	}
+	resp, err := jf.post("/Playlists/"+playlist.String(), &body, jf.defaultParams())
+	jf.playlistChanged(playlist, resp)
+	if err != nil {
+		return fmt.Errorf("rename playlist: %v", err)
+	}
+	return nil
+}
+
+// DeletePlaylist deletes playlist.
+func (jf *Jellyfin) DeletePlaylist(playlist models.Id) error {
+	resp, err := jf.delete("/Items/"+playlist.String(), jf.defaultParams())
+	jf.playlistChanged(playlist, resp)
+	if err != nil {
+		return fmt.Errorf("delete playlist: %v", err)
+	}
+	return nil
+}
+
+// AddPlaylistSongs appends songs to playlist.
+func (jf *Jellyfin) AddPlaylistSongs(playlist models.Id, songs []models.Id) error {
+	params := *jf.defaultParams()
+	params["Ids"] = strings.Join(idsToStrings(songs), ",")
+	resp, err := jf.post(fmt.Sprintf("/Playlists/%s/Items", playlist), nil, &params)
+	jf.playlistChanged(playlist, resp)
+	if err != nil {
+		return fmt.Errorf("add songs to playlist: %v", err)
+	}
+	return nil
+}
+
+// RemovePlaylistSongs removes songs in given indices from playlist.
+func (jf *Jellyfin) RemovePlaylistSongs(playlist models.Id, indices []int) error {
+	entries, err := jf.playlistEntries(playlist)
+	if err != nil {
+		return err
+	}
+	remove := make([]playlistEntry, len(indices))
+	for i, index := range indices {
+		if index < 0 || index >= len(entries) {
+			return fmt.Errorf("remove playlist song: invalid index %d, playlist has %d songs", index, len(entries))
+		}
+		remove[i] = entries[index]
+	}
+	return jf.removePlaylistEntries(playlist, remove)
+}
+
+// MovePlaylistSong moves song in index to newIndex.
+func (jf *Jellyfin) MovePlaylistSong(playlist models.Id, index, newIndex int) error {
+	entries, err := jf.playlistEntries(playlist)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= len(entries) {
+		return fmt.Errorf("move playlist song: invalid index %d, playlist has %d songs", index, len(entries))
+	}
+	if newIndex < 0 || newIndex >= len(entries) {
+		return fmt.Errorf("move playlist song: invalid new index %d, playlist has %d songs", newIndex, len(entries))
+	}
+
+	url := fmt.Sprintf("/Playlists/%s/Items/%s/Move/%d", playlist, entries[index].EntryId, newIndex)
+	resp, err := jf.post(url, nil, jf.defaultParams())
+	jf.playlistChanged(playlist, resp)
+	if err != nil {
+		return fmt.Errorf("move playlist song: %v", err)
+	}
+	return nil
+}
+
+// SetPlaylistSongs replaces all songs in playlist. New songs are added before old entries are removed,
+// so playlist is not emptied if adding fails.
+func (jf *Jellyfin) SetPlaylistSongs(playlist models.Id, songs []models.Id) error {
+	entries, err := jf.playlistEntries(playlist)
+	if err != nil {
+		return err
+	}
+	if len(songs) > 0 {
+		err = jf.AddPlaylistSongs(playlist, songs)
+		if err != nil {
+			return err
+		}
+	}
+	if len(entries) > 0 {
+		return jf.removePlaylistEntries(playlist, entries)
+	}
+	return nil
+}
+
+func (jf *Jellyfin) removePlaylistEntries(playlist models.Id, entries []playlistEntry) error {
+	ids := make([]string, len(entries))
+	for i, v := range entries {
+		ids[i] = v.EntryId
+	}
+	params := *jf.defaultParams()
+	params["EntryIds"] = strings.Join(ids, ",")
+	resp, err := jf.delete(fmt.Sprintf("/Playlists/%s/Items", playlist), &params)
+	jf.playlistChanged(playlist, resp)
+	if err != nil {
+		return fmt.Errorf("remove songs from playlist: %v", err)
+	}
+	return nil
+}
+
+// playlistEntries returns playlist entries in playlist order.
+func (jf *Jellyfin) playlistEntries(playlist models.Id) ([]playlistEntry, error) {
+	resp, err := jf.get(fmt.Sprintf("/Playlists/%s/Items", playlist), jf.defaultParams())
+	if resp != nil {
+		defer resp.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get playlist entries: %v", err)
+	}
+
+	dto := playlistEntries{}
+	err = json.NewDecoder(resp).Decode(&dto)
+	if err != nil {
+		return nil, fmt.Errorf("decode json: %v", err)
+	}
+	return dto.Entries, nil
+}
+
+// playlistChanged closes response and drops outdated playlist from cache.
+func (jf *Jellyfin) playlistChanged(playlist models.Id, resp io.ReadCloser) {
+	if resp != nil {
+		resp.Close()
+	}
+	jf.cache.Delete(playlist)
+}
+
+func idsToStrings(ids []models.Id) []string {
+	out := make([]string, len(ids))
+	for i, v := range ids {
+		out[i] = v.String()
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package jellyfin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"tryffel.net/go/jellycli/models"
+)
+
+func TestJellyfin_editPlaylistSongs(t *testing.T) {
+	entries := `{"Items": [
+		{"Id": "song-1", "PlaylistItemId": "entry-1"},
+		{"Id": "song-2", "PlaylistItemId": "entry-2"},
+		{"Id": "song-1", "PlaylistItemId": "entry-3"}
+	]}`
+
+	lock := sync.Mutex{}
+	var requests []string
+	failPost := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.URL.Query().Get("EntryIds"))
+		if r.Method == http.MethodGet {
+			w.Write([]byte(entries))
+		} else if r.Method == http.MethodPost && failPost {
+			w.WriteHeader(http.StatusInternalServerError)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	c, _ := NewCache()
+	jf := &Jellyfin{host: server.URL, client: server.Client(), cache: c}
+
+	tests := []struct {
+		name     string
+		edit     func() error
+		failPost bool
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name: "remove duplicate song",
+			edit: func() error { return jf.RemovePlaylistSongs("pl-1", []int{2}) },
+			want: []string{"GET /Playlists/pl-1/Items ", "DELETE /Playlists/pl-1/Items entry-3"},
+		},
+		{
+			name: "remove multiple",
+			edit: func() error { return jf.RemovePlaylistSongs("pl-1", []int{0, 1}) },
+			want: []string{"GET /Playlists/pl-1/Items ", "DELETE /Playlists/pl-1/Items entry-1,entry-2"},
+		},
+		{
+			name:    "remove invalid index",
+			edit:    func() error { return jf.RemovePlaylistSongs("pl-1", []int{3}) },
+			want:    []string{"GET /Playlists/pl-1/Items "},
+			wantErr: true,
+		},
+		{
+			name: "move",
+			edit: func() error { return jf.MovePlaylistSong("pl-1", 0, 2) },
+			want: []string{"GET /Playlists/pl-1/Items ", "POST /Playlists/pl-1/Items/entry-1/Move/2 "},
+		},
+		{
+			name:    "move to invalid index",
+			edit:    func() error { return jf.MovePlaylistSong("pl-1", 0, 3) },
+			want:    []string{"GET /Playlists/pl-1/Items "},
+			wantErr: true,
+		},
+		{
+			name: "set songs",
+			edit: func() error { return jf.SetPlaylistSongs("pl-1", []models.Id{"song-2"}) },
+			want: []string{"GET /Playlists/pl-1/Items ", "POST /Playlists/pl-1/Items ",
+				"DELETE /Playlists/pl-1/Items entry-1,entry-2,entry-3"},
+		},
+		{
+			name:     "set songs, add fails",
+			edit:     func() error { return jf.SetPlaylistSongs("pl-1", []models.Id{"song-2"}) },
+			failPost: true,
+			want:     []string{"GET /Playlists/pl-1/Items ", "POST /Playlists/pl-1/Items "},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lock.Lock()
+			requests = nil
+			failPost = tt.failPost
+			lock.Unlock()
+
+			err := tt.edit()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("edit playlist, error = %v, wantErr %v", err, tt.wantErr)
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if !reflect.DeepEqual(requests, tt.want) {
+				t.Errorf("requests, got: %v, want: %v", requests, tt.want)
+			}
+		})
+	}
+}
@@ -60,6 +60,14 @@ func (jf *Jellyfin) post(url string, body *[]byte, params *params) (io.ReadClose
 	return nil, err
 }
 
+func (jf *Jellyfin) delete(url string, params *params) (io.ReadCloser, error) {
+	resp, err := jf.makeRequest("DELETE", url, nil, params, nil)
+	if resp != nil {
+		return resp.Body, err
+	}
+	return nil, err
+}
+
 //Construct request
 // Set authorization header and build url query
 // Make request, parse response code and raise error if needed. Else return response body
@@ -148,6 +148,17 @@ func (jf *Jellyfin) parseInboudMessage(buff *[]byte) error {
 				}
 			case "ToggleMute":
 				jf.player.ToggleMute()
+			case "SetRepeatMode":
+				switch args["RepeatMode"] {
+				case "RepeatNone":
+					jf.player.SetRepeat(interfaces.RepeatModeOff)
+				case "RepeatOne":
+					jf.player.SetRepeat(interfaces.RepeatModeOne)
+				case "RepeatAll":
+					jf.player.SetRepeat(interfaces.RepeatModeAll)
+				default:
+					logrus.Error("Invalid repeat mode parameter: ", args["RepeatMode"])
+				}
 			default:
 				logrus.Warning("unknown socket command: ", name)
 			}
@@ -158,7 +169,7 @@ func (jf *Jellyfin) parseInboudMessage(buff *[]byte) error {
 		rawCmd := dataMap["Command"]
 		cmd, ok := rawCmd.(string)
 		if ok {
-			err = jf.pushCommand(cmd)
+			err = jf.pushCommand(cmd, dataMap)
 		}
 	} else if cmd == "play" {
 		var items []string
@@ -189,7 +200,7 @@ func (jf *Jellyfin) parseInboudMessage(buff *[]byte) error {
 	return err
 }
 
-func (jf *Jellyfin) pushCommand(cmd string) error {
+func (jf *Jellyfin) pushCommand(cmd string, data map[string]interface{}) error {
 	if jf.player == nil {
 		return nil
 	}
@@ -211,6 +222,13 @@ func (jf *Jellyfin) pushCommand(cmd string) error {
 	case "Stop":
 		jf.player.StopMedia()
 		jf.queue.ClearQueue(true)
+	case "Seek":
+		ticks, ok := data["SeekPositionTicks"].(float64)
+		if !ok {
+			logrus.Error("Received seek command, but position is not a number: ", data)
+		} else {
+			jf.player.SetPosition(interfaces.AudioTick(int64(ticks) * 1000 / ticksToSecond))
+		}
 	default:
 		logrus.Info("Unknown websocket playstate command: ", cmd)
 	}
@@ -36,6 +36,8 @@ import (
 
 const (
 	ticksToSecond = int64(10000000)
+	// loudness that jellyfin normalizes songs to
+	normalizationTargetLufs = -18.0
 )
 
 type infoResponse struct {
@@ -87,6 +89,8 @@ type playbackStarted struct {
 	PlaylistLength      int64
 	PlaylistIndex       int
 	ShuffleMode         string
+	RepeatMode          string
+	PlaybackRate        float64
 	Queue               []queueItem `json:"NowPlayingQueue"`
 }
 
@@ -119,7 +123,7 @@ func (jf *Jellyfin) ReportProgress(state *interfaces.ApiPlaybackState) error {
 
 	started := playbackStarted{
 		QueueableMediaTypes: []string{"Audio"},
-		CanSeek:             false,
+		CanSeek:             true,
 		ItemId:              state.ItemId,
 		MediaSourceId:       state.ItemId,
 		PositionTicks:       int64(state.Position) * ticksToSecond,
@@ -130,6 +134,7 @@ func (jf *Jellyfin) ReportProgress(state *interfaces.ApiPlaybackState) error {
 		PlaySessionId:       jf.SessionId,
 		LiveStreamId:        "",
 		PlaylistLength:      int64(state.PlaylistLength) * ticksToSecond,
+		PlaybackRate:        state.Speed,
 		Queue:               idsToQueue(state.Queue),
 	}
 
@@ -139,6 +144,15 @@ func (jf *Jellyfin) ReportProgress(state *interfaces.ApiPlaybackState) error {
 		started.ShuffleMode = "Sorted"
 	}
 
+	switch state.Repeat {
+	case interfaces.RepeatModeOne:
+		started.RepeatMode = "RepeatOne"
+	case interfaces.RepeatModeAll:
+		started.RepeatMode = "RepeatAll"
+	default:
+		started.RepeatMode = "RepeatNone"
+	}
+
 	if state.Event == interfaces.EventStart {
 		url = "/Sessions/Playing"
 		report = started
@@ -213,6 +227,14 @@ func (jf *Jellyfin) ReportCapabilities() error {
 		"ToggleMute",
 		"SetVolume",
 		"SetShuffleQueue",
+		"SetRepeatMode",
+	}
+	data["DeviceProfile"] = map[string]interface{}{
+		"Name":               config.AppName,
+		"DirectPlayProfiles": directPlayProfiles(),
+		"TranscodingProfiles": []map[string]string{
+			{"Type": "Audio", "Container": "mp3", "AudioCodec": "mp3", "Protocol": "http", "Context": "Streaming"},
+		},
 	}
 	data["SupportsMediaControl"] = jf.remoteControlEnabled
 	data["SupportsPersistentIdentifier"] = false
@@ -92,6 +92,10 @@ var MockSongs = []*models.Song{
 		Album:       "album-1",
 		DiscNumber:  0,
 		AlbumArtist: "artist-1",
+		TrackGain:   -6.5,
+		AlbumGain:   -7.25,
+		TrackPeak:   0.98,
+		AlbumPeak:   1.05,
 	},
 	{
 		Id:          "song-3",
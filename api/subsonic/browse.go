@@ -20,6 +20,7 @@ package subsonic
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 	"tryffel.net/go/jellycli/interfaces"
 	"tryffel.net/go/jellycli/models"
@@ -46,6 +47,19 @@ func (s *Subsonic) getFavorites() error {
 	return nil
 }
 
+// SetFavorite stars or unstars songs.
+func (s *Subsonic) SetFavorite(songs []models.Id, favorite bool) error {
+	url := "/star"
+	if !favorite {
+		url = "/unstar"
+	}
+	_, err := s.getList(url, nil, listParams{"id": idsToStrings(songs)})
+	if err != nil {
+		return fmt.Errorf("set favorite: %v", err)
+	}
+	return nil
+}
+
 func (s *Subsonic) GetArtists(query *interfaces.QueryOpts) (artists []*models.Artist, n int, err error) {
 	if query.Filter.Favorite {
 		err := s.getFavorites()
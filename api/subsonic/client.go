@@ -17,7 +17,7 @@
  */
 
 // Package subsonic contains remote server implementation for Subsonic-compatible servers.
-// Implemented: api.Browser.
+// Implemented: api.Browser, api.Transcoder, api.PlaylistEditor.
 // Subsonic-protocol does not support api.RemoteController.
 package subsonic
 
@@ -30,6 +30,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 	"tryffel.net/go/jellycli/api"
 	"tryffel.net/go/jellycli/config"
@@ -57,6 +58,9 @@ type Subsonic struct {
 
 	currentSong   models.Id
 	songScrobbled bool
+
+	qualityLock sync.Mutex
+	quality     config.QualityProfile
 }
 
 func (s *Subsonic) Stream(Song *models.Song) (io.ReadCloser, interfaces.AudioFormat, error) {
@@ -68,6 +72,9 @@ func (s *Subsonic) Stream(Song *models.Song) (io.ReadCloser, interfaces.AudioFor
 	(*params)["u"] = s.user
 	(*params)["c"] = s.client
 	(*params)["v"] = s.apiversion
+	s.qualityLock.Lock()
+	params.setTranscoding(s.quality)
+	s.qualityLock.Unlock()
 
 	url := s.host + "/rest/stream"
 
@@ -77,13 +84,24 @@ func (s *Subsonic) Stream(Song *models.Song) (io.ReadCloser, interfaces.AudioFor
 	}
 
 	format, err := stream.AudioFormat()
-	return stream, format, err
+	if err != nil {
+		stream.Close()
+		return nil, interfaces.AudioFormatNil, err
+	}
+	return stream, format, nil
 }
 
 func (s *Subsonic) Download(Song *models.Song) (io.ReadCloser, interfaces.AudioFormat, error) {
 	return s.Stream(Song)
 }
 
+// SetQuality sets quality profile for streams. Subsonic server must have transcoding configured for codec.
+func (s *Subsonic) SetQuality(profile config.QualityProfile) {
+	s.qualityLock.Lock()
+	defer s.qualityLock.Unlock()
+	s.quality = profile
+}
+
 func (s *Subsonic) GetInfo() (*models.ServerInfo, error) {
 	info := &models.ServerInfo{
 		ServerType: "Subsonic",
@@ -162,6 +180,11 @@ func NewSubsonic(conf *config.Subsonic, provider config.KeyValueProvider) (*Subs
 }
 
 func (s *Subsonic) get(url string, params *params) (*response, error) {
+	return s.getList(url, params, nil)
+}
+
+// getList makes get request with parameters that can have multiple values, e.g. song ids.
+func (s *Subsonic) getList(url string, params *params, lists listParams) (*response, error) {
 	fullUrl := s.host + "/rest" + url
 	start := time.Now()
 	req, _ := http.NewRequest(http.MethodGet, fullUrl, nil)
@@ -179,6 +202,11 @@ func (s *Subsonic) get(url string, params *params) (*response, error) {
 			q.Add(key, value)
 		}
 	}
+	for key, values := range lists {
+		for _, value := range values {
+			q.Add(key, value)
+		}
+	}
 
 	req.URL.RawQuery = q.Encode()
 
@@ -241,6 +269,9 @@ func (s *Subsonic) login(provider config.KeyValueProvider) error {
 
 type params map[string]string
 
+// listParams are parameters that are repeated for each value.
+type listParams map[string][]string
+
 func (p *params) setId(id string) {
 	(*p)["id"] = id
 }
@@ -250,6 +281,16 @@ func (p *params) setPaging(paging interfaces.Paging) {
 	(*p)["size"] = strconv.Itoa(paging.PageSize)
 }
 
+// setTranscoding sets stream format and max bitrate. If profile has no codec, server decides format.
+func (p *params) setTranscoding(profile config.QualityProfile) {
+	if profile.Codec != "" {
+		(*p)["format"] = profile.Codec
+	}
+	if profile.MaxBitrateKbps > 0 {
+		(*p)["maxBitRate"] = strconv.Itoa(profile.MaxBitrateKbps)
+	}
+}
+
 func (s *Subsonic) GetConfig() config.Backend {
 	return &config.Subsonic{
 		Url:      s.host,
@@ -185,6 +185,18 @@ type child struct {
 	ArtistId   string `json:"artistId"`
 	Type       string `json:"type"`
 	SongCount  int    `json:"songCount"`
+	PlayCount  int    `json:"playCount"`
+	Starred    string `json:"starred"`
+
+	ReplayGain *replayGain `json:"replayGain"`
+}
+
+// replayGain is reported by OpenSubsonic servers
+type replayGain struct {
+	TrackGain float64 `json:"trackGain"`
+	AlbumGain float64 `json:"albumGain"`
+	TrackPeak float64 `json:"trackPeak"`
+	AlbumPeak float64 `json:"albumPeak"`
 }
 
 func (c *child) toAlbum() *models.Album {
@@ -203,7 +215,7 @@ func (c *child) toAlbum() *models.Album {
 }
 
 func (c *child) toSong() *models.Song {
-	return &models.Song{
+	song := &models.Song{
 		Id:          models.Id(c.Id),
 		Name:        c.Title,
 		Duration:    c.Duration,
@@ -212,8 +224,16 @@ func (c *child) toSong() *models.Song {
 		DiscNumber:  c.DiscNumber,
 		Artists:     nil,
 		AlbumArtist: models.Id(c.ArtistId),
-		Favorite:    false,
+		Favorite:    c.Starred != "",
+		PlayCount:   c.PlayCount,
+	}
+	if c.ReplayGain != nil {
+		song.TrackGain = c.ReplayGain.TrackGain
+		song.AlbumGain = c.ReplayGain.AlbumGain
+		song.TrackPeak = c.ReplayGain.TrackPeak
+		song.AlbumPeak = c.ReplayGain.AlbumPeak
 	}
+	return song
 }
 
 type searchResp struct {
@@ -248,6 +268,7 @@ func (p *playlist) toPlaylist() *models.Playlist {
 }
 
 type playlistSongs struct {
+	playlist
 	Songs []child `json:"entry"`
 }
 
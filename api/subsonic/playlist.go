@@ -0,0 +1,139 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package subsonic
+
+import (
+	"fmt"
+	"strconv"
+	"tryffel.net/go/jellycli/models"
+)
+
+// CreatePlaylist creates new playlist.
+func (s *Subsonic) CreatePlaylist(name string, songs []models.Id) (*models.Playlist, error) {
+	params := &params{"name": name}
+	resp, err := s.getList("/createPlaylist", params, listParams{"songId": idsToStrings(songs)})
+	if err != nil {
+		return nil, fmt.Errorf("create playlist: %v", err)
+	}
+	if resp.Playlist != nil && resp.Playlist.Id != "" {
+		return resp.Playlist.toPlaylist(), nil
+	}
+
+	// servers implementing api version < 1.14 do not return created playlist
+	playlists, err := s.GetPlaylists()
+	if err != nil {
+		return nil, fmt.Errorf("get created playlist: %v", err)
+	}
+	for i := len(playlists) - 1; i >= 0; i-- {
+		if playlists[i].Name == name {
+			return playlists[i], nil
+		}
+	}
+	return nil, fmt.Errorf("created playlist '%s' not found", name)
+}
+
+// RenamePlaylist renames playlist.
+func (s *Subsonic) RenamePlaylist(playlist models.Id, name string) error {
+	params := &params{"playlistId": playlist.String(), "name": name}
+	_, err := s.get("/updatePlaylist", params)
+	if err != nil {
+		return fmt.Errorf("rename playlist: %v", err)
+	}
+	return nil
+}
+
+// DeletePlaylist deletes playlist.
+func (s *Subsonic) DeletePlaylist(playlist models.Id) error {
+	params := &params{}
+	params.setId(playlist.String())
+	_, err := s.get("/deletePlaylist", params)
+	if err != nil {
+		return fmt.Errorf("delete playlist: %v", err)
+	}
+	return nil
+}
+
+// AddPlaylistSongs appends songs to playlist.
+func (s *Subsonic) AddPlaylistSongs(playlist models.Id, songs []models.Id) error {
+	params := &params{"playlistId": playlist.String()}
+	_, err := s.getList("/updatePlaylist", params, listParams{"songIdToAdd": idsToStrings(songs)})
+	if err != nil {
+		return fmt.Errorf("add songs to playlist: %v", err)
+	}
+	return nil
+}
+
+// RemovePlaylistSongs removes songs in given indices from playlist.
+func (s *Subsonic) RemovePlaylistSongs(playlist models.Id, indices []int) error {
+	params := &params{"playlistId": playlist.String()}
+	remove := make([]string, len(indices))
+	for i, v := range indices {
+		remove[i] = strconv.Itoa(v)
+	}
+	_, err := s.getList("/updatePlaylist", params, listParams{"songIndexToRemove": remove})
+	if err != nil {
+		return fmt.Errorf("remove songs from playlist: %v", err)
+	}
+	return nil
+}
+
+// MovePlaylistSong moves song in index to newIndex. Subsonic has no method for reordering playlist,
+// so whole playlist is replaced with reordered songs.
+func (s *Subsonic) MovePlaylistSong(playlist models.Id, index, newIndex int) error {
+	songs, err := s.GetPlaylistSongs(playlist)
+	if err != nil {
+		return fmt.Errorf("get playlist songs: %v", err)
+	}
+	if index < 0 || index >= len(songs) || newIndex < 0 || newIndex >= len(songs) {
+		return fmt.Errorf("move playlist song: invalid index %d -> %d, playlist has %d songs",
+			index, newIndex, len(songs))
+	}
+
+	ids := make([]models.Id, 0, len(songs))
+	for i, v := range songs {
+		if i != index {
+			ids = append(ids, v.Id)
+		}
+	}
+	ids = append(ids[:newIndex], append([]models.Id{songs[index].Id}, ids[newIndex:]...)...)
+
+	err = s.SetPlaylistSongs(playlist, ids)
+	if err != nil {
+		return fmt.Errorf("move playlist song: %v", err)
+	}
+	return nil
+}
+
+// SetPlaylistSongs replaces all songs in playlist.
+func (s *Subsonic) SetPlaylistSongs(playlist models.Id, songs []models.Id) error {
+	params := &params{"playlistId": playlist.String()}
+	_, err := s.getList("/createPlaylist", params, listParams{"songId": idsToStrings(songs)})
+	if err != nil {
+		return fmt.Errorf("set playlist songs: %v", err)
+	}
+	return nil
+}
+
+func idsToStrings(ids []models.Id) []string {
+	out := make([]string, len(ids))
+	for i, v := range ids {
+		out[i] = v.String()
+	}
+	return out
+}
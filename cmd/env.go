@@ -51,6 +51,23 @@ JELLYCLI_PLAYER_AUDIO_BUFFERING_MS
 JELLYCLI_PLAYER_ENABLE_REMOTE_CONTROL
 JELLYCLI_PLAYER_ENABLE_LOCAL_CACHE
 JELLYCLI_PLAYER_ENABLE_LOCAL_CACHE_DIR
+JELLYCLI_PLAYER_CROSSFADE_S
+JELLYCLI_PLAYER_CROSSFADE_ALBUM_AWARE
+JELLYCLI_PLAYER_PREFETCH_S
+JELLYCLI_PLAYER_PREFETCH_DEPTH
+JELLYCLI_PLAYER_REPLAY_GAIN
+JELLYCLI_PLAYER_REPLAY_GAIN_PREAMP_DB
+JELLYCLI_PLAYER_REPLAY_GAIN_CLIP_PROTECTION
+JELLYCLI_PLAYER_EQUALIZER_ENABLED
+JELLYCLI_PLAYER_EQUALIZER_PRESET
+JELLYCLI_PLAYER_EQUALIZER_BANDS
+JELLYCLI_PLAYER_AUDIO_OUTPUT
+JELLYCLI_PLAYER_AUDIO_OUTPUT_PATH
+JELLYCLI_PLAYER_AUDIO_OUTPUT_FORMAT
+JELLYCLI_PLAYER_SLEEP_FADE_S
+JELLYCLI_PLAYER_RETRY_COUNT
+JELLYCLI_PLAYER_RETRY_BACKOFF_MS
+JELLYCLI_PLAYER_QUALITY_PROFILE
 
 JELLYCLI_GUI_PAGESIZE
 JELLYCLI_GUI_DEBUG_MODE
@@ -27,10 +27,12 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 	"tryffel.net/go/jellycli/api"
 	"tryffel.net/go/jellycli/api/jellyfin"
 	"tryffel.net/go/jellycli/api/subsonic"
 	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/interfaces"
 	"tryffel.net/go/jellycli/mpris"
 	"tryffel.net/go/jellycli/player"
 	"tryffel.net/go/jellycli/task"
@@ -44,10 +46,16 @@ type app struct {
 	mpris       *mpris.MediaController
 	mprisPlayer *mpris.Player
 	logfile     *os.File
+
+	sleepMode     interfaces.SleepMode
+	sleepDuration time.Duration
 }
 
 var disableGui = false
 
+// sleepTimer is sleep timer set with flag, see interfaces.ParseSleepTimer
+var sleepTimer = ""
+
 func initApplication() (*app, error) {
 
 	if viper.GetBool("player_nogui") {
@@ -63,10 +71,18 @@ func initApplication() (*app, error) {
 	a.logfile = logFile
 	// write to both log file and stdout for startup, in case there are any errors that prevent gui
 	writer := io.MultiWriter(a.logfile, os.Stdout)
+	if audioToStdout() {
+		writer = a.logfile
+	}
 	logrus.SetOutput(writer)
 
 	logrus.Infof("############# %s v%s ############", config.AppName, config.Version)
 
+	a.sleepMode, a.sleepDuration, err = interfaces.ParseSleepTimer(sleepTimer)
+	if err != nil {
+		logrus.Fatalf("sleep timer: %v", err)
+	}
+
 	err = a.initServerConnection()
 	if err != nil {
 		logrus.Fatalf("connect to server: %v", err)
@@ -175,6 +191,18 @@ func (a *app) run() {
 			logrus.Fatalf("start task: %v", err)
 		}
 	}
+	if a.sleepMode != interfaces.SleepModeOff {
+		a.player.SetSleepTimer(a.sleepMode, a.sleepDuration)
+	}
+	if _, ok := a.player.SavedSession(); ok && disableGui {
+		// there is no one to ask, resume paused so that playback can be continued remotely
+		go func() {
+			err := a.player.ResumeSession()
+			if err != nil {
+				logrus.Errorf("resume session: %v", err)
+			}
+		}()
+	}
 
 	if !disableGui {
 		logrus.SetOutput(a.logfile)
@@ -199,9 +227,14 @@ func (a *app) stopOnSignal() {
 
 func (a *app) stop() error {
 	logrus.Info("Stopping application")
-	tasks := []task.Tasker{a.player, a.server}
-	var err error
 	var hasError bool
+	err := a.player.SaveSession()
+	if err != nil {
+		logrus.Error(err)
+		hasError = true
+	}
+
+	tasks := []task.Tasker{a.player, a.server}
 	for _, v := range tasks {
 		err = v.Stop()
 		if err != nil {
@@ -219,7 +252,9 @@ func (a *app) stop() error {
 	}
 
 	if a.logfile != nil {
-		logrus.SetOutput(os.Stdout)
+		if !audioToStdout() {
+			logrus.SetOutput(os.Stdout)
+		}
 		err = a.logfile.Close()
 		if err != nil {
 			err = fmt.Errorf("close log file: %v", err)
@@ -228,6 +263,12 @@ func (a *app) stop() error {
 	return err
 }
 
+// audioToStdout returns true if audio is written to stdout, in which case nothing else can be written there
+func audioToStdout() bool {
+	output, path := config.AppConfig.Player.Output()
+	return output == config.AudioOutputPipe && path == config.AudioOutputStdout
+}
+
 func runApplication() {
 	_, err := initApplication()
 	if err != nil {
@@ -56,6 +56,10 @@ func Execute() {
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file")
 	rootCmd.Flags().BoolVar(&disableGui, "no-gui", false, "disable gui")
+	rootCmd.Flags().StringVar(&config.AudioOutputOverride, "output", "",
+		"audio output: speaker, null, file:<path> or pipe:<path>. Use 'pipe:-' for stdout")
+	rootCmd.Flags().StringVar(&sleepTimer, "sleep", "",
+		"sleep timer: stop playback after minutes or duration (e.g. 30 or 1h30m), or at the end of 'track' or 'album'")
 }
 
 func initConfig() {
@@ -28,6 +28,7 @@ const (
 	colorModalBackground = tcell.Color236
 	colorText            = tcell.Color252
 	colorShortcut        = tcell.Color214
+	colorError           = tcell.Color167
 	TextSecondary        = tcell.Color179
 	TextDisabled         = tcell.Color241
 	TextDisabled2        = tcell.Color247
@@ -48,8 +49,10 @@ type AppColor struct {
 	TextDisabled             tcell.Color
 	TextDisabled2            tcell.Color
 	BackgroundSelected       tcell.Color
+	BackgroundMarked         tcell.Color
 	TextSelected             tcell.Color
 	TextSongPlaying          tcell.Color
+	TextSongFailed           tcell.Color
 	NavBar                   ColorNavBar
 	Status                   ColorStatus
 	Modal                    ColorModal
@@ -69,8 +72,10 @@ func defaultColors() AppColor {
 		TextDisabled:             TextDisabled,
 		TextDisabled2:            TextDisabled2,
 		BackgroundSelected:       tcell.Color23,
+		BackgroundMarked:         tcell.Color238,
 		TextSelected:             tcell.Color252,
 		TextSongPlaying:          colorShortcut,
+		TextSongFailed:           colorError,
 		NavBar:                   defaultColorNavBar(),
 		Status:                   defaultColorStatus(),
 		Modal:                    defaultColorModal(),
@@ -131,6 +136,11 @@ type ColorStatus struct {
 	TextPrimary      tcell.Color
 	TextSecondary    tcell.Color
 	VolumeMuted      tcell.Color
+	// Spectrum is color of spectrum bars, and SpectrumPeak color of bars near full scale
+	Spectrum     tcell.Color
+	SpectrumPeak tcell.Color
+	// Error is color of playback error
+	Error tcell.Color
 }
 
 func defaultColorStatus() ColorStatus {
@@ -145,5 +155,8 @@ func defaultColorStatus() ColorStatus {
 		TextPrimary:      colorText,
 		TextSecondary:    tcell.Color26,
 		VolumeMuted:      tcell.Color238,
+		Spectrum:         tcell.Color30,
+		SpectrumPeak:     colorShortcut,
+		Error:            colorError,
 	}
 }
@@ -29,6 +29,7 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -73,12 +74,98 @@ type Player struct {
 	LogLevel         string `yaml:"log_level"`
 	AudioBufferingMs int    `yaml:"audio_buffering_ms"`
 	HttpBufferingS   int    `yaml:"http_buffering_s"`
-	// memory limit in MiB
+	// memory limit in MiB, stream data exceeding this is stored in temporary file
 	HttpBufferingLimitMem int  `yaml:"http_buffering_limit_mem"`
 	EnableRemoteControl   bool `yaml:"enable_remote_control"`
 
 	EnableLocalCache bool   `yaml:"enable_local_cache"`
 	LocalCacheDir    string `yaml:"local_cache_dir"`
+
+	// crossfade duration in seconds, 0 disables crossfade
+	CrossfadeS int `yaml:"crossfade_s"`
+	// disable crossfade between consecutive songs from same album
+	CrossfadeAlbumAware bool `yaml:"crossfade_album_aware"`
+
+	// PrefetchS is how many seconds before current song ends upcoming songs are downloaded. Crossfade is added to it.
+	PrefetchS int `yaml:"prefetch_s"`
+	// PrefetchDepth is how many upcoming songs are downloaded ahead
+	PrefetchDepth int `yaml:"prefetch_depth"`
+
+	// ReplayGain is loudness normalization mode: off, track or album
+	ReplayGain string `yaml:"replay_gain"`
+	// ReplayGainPreampDb is added to normalization gain, in dB
+	ReplayGainPreampDb float64 `yaml:"replay_gain_preamp_db"`
+	// ReplayGainClipProtection limits gain so that song does not clip
+	ReplayGainClipProtection bool `yaml:"replay_gain_clip_protection"`
+
+	EqualizerEnabled bool `yaml:"equalizer_enabled"`
+	// EqualizerPreset is one of EqualizerPresetNames
+	EqualizerPreset string `yaml:"equalizer_preset"`
+	// EqualizerBands are custom gains in dB for each of EqualizerFrequencies
+	EqualizerBands []float64 `yaml:"equalizer_bands"`
+
+	// AudioOutput is one of: speaker, null, file, pipe
+	AudioOutput string `yaml:"audio_output"`
+	// AudioOutputPath is file to write to with file output, or fifo with pipe output. Pipe defaults to stdout.
+	AudioOutputPath string `yaml:"audio_output_path"`
+	// AudioOutputFormat is wav or raw. File output defaults to wav and pipe to raw.
+	AudioOutputFormat string `yaml:"audio_output_format"`
+
+	// SleepFadeS is duration in seconds to fade audio out before sleep timer stops playback, 0 disables fade
+	SleepFadeS int `yaml:"sleep_fade_s"`
+
+	// RetryCount is how many times song that fails to download or decode is retried before skipping it
+	RetryCount int `yaml:"retry_count"`
+	// RetryBackoffMs is delay before first retry, in milliseconds. Delay is doubled for each retry.
+	RetryBackoffMs int `yaml:"retry_backoff_ms"`
+
+	// QualityProfiles are available transcoding profiles
+	QualityProfiles []QualityProfile `yaml:"quality_profiles"`
+	// QualityProfile is name of active profile
+	QualityProfile string `yaml:"quality_profile"`
+}
+
+// Audio outputs
+const (
+	// AudioOutputSpeaker plays audio with sound card
+	AudioOutputSpeaker = "speaker"
+	// AudioOutputNull discards audio
+	AudioOutputNull = "null"
+	// AudioOutputFile writes audio to file
+	AudioOutputFile = "file"
+	// AudioOutputPipe writes audio to fifo or stdout
+	AudioOutputPipe = "pipe"
+
+	// AudioOutputStdout is pipe output path for stdout
+	AudioOutputStdout = "-"
+
+	AudioOutputFormatWav = "wav"
+	// AudioOutputFormatRaw is signed 16-bit little-endian stereo pcm
+	AudioOutputFormatRaw = "raw"
+)
+
+// ReplayGain modes
+const (
+	ReplayGainOff   = "off"
+	ReplayGainTrack = "track"
+	ReplayGainAlbum = "album"
+)
+
+// Output returns audio output and its path. Command line flag overrides config.
+// Pipe output without a path writes to stdout.
+func (p *Player) Output() (output, path string) {
+	output, path = p.AudioOutput, p.AudioOutputPath
+	if AudioOutputOverride != "" {
+		output, path = AudioOutputOverride, ""
+		if i := strings.Index(output, ":"); i >= 0 {
+			output, path = output[:i], output[i+1:]
+		}
+	}
+	output = strings.ToLower(output)
+	if output == AudioOutputPipe && path == "" {
+		path = AudioOutputStdout
+	}
+	return output, path
 }
 
 func (g *Gui) sanitize() {
@@ -119,6 +206,59 @@ func (p *Player) sanitize() {
 	if p.HttpBufferingLimitMem == 0 {
 		p.HttpBufferingLimitMem = 20
 	}
+	if p.CrossfadeS < 0 {
+		p.CrossfadeS = 0
+	}
+	if p.PrefetchS <= 0 {
+		p.PrefetchS = 5
+	}
+	if p.PrefetchDepth <= 0 {
+		p.PrefetchDepth = 1
+	} else if p.PrefetchDepth > PrefetchMaxDepth {
+		p.PrefetchDepth = PrefetchMaxDepth
+	}
+	if p.SleepFadeS < 0 {
+		p.SleepFadeS = 0
+	}
+	if p.RetryCount < 0 {
+		p.RetryCount = 0
+	}
+	if p.RetryBackoffMs <= 0 {
+		p.RetryBackoffMs = 1000
+	}
+	p.ReplayGain = strings.ToLower(p.ReplayGain)
+	if p.ReplayGain != ReplayGainTrack && p.ReplayGain != ReplayGainAlbum {
+		p.ReplayGain = ReplayGainOff
+	}
+
+	p.EqualizerPreset = strings.ToLower(p.EqualizerPreset)
+	if _, ok := EqualizerPresets[p.EqualizerPreset]; !ok && p.EqualizerPreset != EqualizerPresetCustom {
+		p.EqualizerPreset = EqualizerPresetFlat
+	}
+	p.AudioOutput = strings.ToLower(p.AudioOutput)
+	if p.AudioOutput == "" {
+		p.AudioOutput = AudioOutputSpeaker
+	}
+	p.AudioOutputFormat = strings.ToLower(p.AudioOutputFormat)
+
+	if len(p.QualityProfiles) == 0 {
+		p.QualityProfiles = DefaultQualityProfiles()
+	}
+	for i := range p.QualityProfiles {
+		p.QualityProfiles[i].sanitize()
+	}
+	p.QualityProfile = p.GetQualityProfile(p.QualityProfile).Name
+
+	if len(p.EqualizerBands) != len(EqualizerFrequencies) {
+		p.EqualizerBands = make([]float64, len(EqualizerFrequencies))
+	}
+	for i, v := range p.EqualizerBands {
+		if v > EqualizerMaxGaindB {
+			p.EqualizerBands[i] = EqualizerMaxGaindB
+		} else if v < -EqualizerMaxGaindB {
+			p.EqualizerBands[i] = -EqualizerMaxGaindB
+		}
+	}
 
 	if p.LocalCacheDir == "" {
 		baseCacheDir, err := os.UserCacheDir()
@@ -149,6 +289,9 @@ func (c *Config) initNewConfig() {
 
 	c.Gui.EnableResultsFiltering = true
 	c.Player.EnableLocalCache = false
+	c.Player.ReplayGainClipProtection = true
+	c.Player.SleepFadeS = 10
+	c.Player.RetryCount = 2
 }
 
 // can config file be considered empty / not configured
@@ -210,6 +353,30 @@ func ConfigFromViper() error {
 			EnableRemoteControl:   viper.GetBool("player.enable_remote_control"),
 			LocalCacheDir:         viper.GetString("player.local_cache_dir"),
 			EnableLocalCache:      viper.GetBool("player.enable_local_cache"),
+			CrossfadeS:            viper.GetInt("player.crossfade_s"),
+			CrossfadeAlbumAware:   viper.GetBool("player.crossfade_album_aware"),
+			PrefetchS:             viper.GetInt("player.prefetch_s"),
+			PrefetchDepth:         viper.GetInt("player.prefetch_depth"),
+
+			ReplayGain:               viper.GetString("player.replay_gain"),
+			ReplayGainPreampDb:       viper.GetFloat64("player.replay_gain_preamp_db"),
+			ReplayGainClipProtection: viper.GetBool("player.replay_gain_clip_protection"),
+
+			EqualizerEnabled: viper.GetBool("player.equalizer_enabled"),
+			EqualizerPreset:  viper.GetString("player.equalizer_preset"),
+			EqualizerBands:   getFloatSlice("player.equalizer_bands"),
+
+			AudioOutput:       viper.GetString("player.audio_output"),
+			AudioOutputPath:   viper.GetString("player.audio_output_path"),
+			AudioOutputFormat: viper.GetString("player.audio_output_format"),
+
+			SleepFadeS: viper.GetInt("player.sleep_fade_s"),
+
+			RetryCount:     viper.GetInt("player.retry_count"),
+			RetryBackoffMs: viper.GetInt("player.retry_backoff_ms"),
+
+			QualityProfiles: getQualityProfiles("player.quality_profiles"),
+			QualityProfile:  viper.GetString("player.quality_profile"),
 		},
 		Gui: Gui{
 			PageSize:            viper.GetInt("gui.pagesize"),
@@ -269,6 +436,41 @@ func setDefaults() {
 	}
 }
 
+// getFloatSlice reads list of numbers. Environment variables may contain comma-separated list.
+func getFloatSlice(key string) []float64 {
+	var values []interface{}
+	switch v := viper.Get(key).(type) {
+	case []float64:
+		return v
+	case []interface{}:
+		values = v
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			values = append(values, strings.TrimSpace(s))
+		}
+	default:
+		return nil
+	}
+
+	floats := make([]float64, 0, len(values))
+	for _, v := range values {
+		switch value := v.(type) {
+		case float64:
+			floats = append(floats, value)
+		case int:
+			floats = append(floats, float64(value))
+		case string:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				logrus.Errorf("invalid number in %s: %v", key, err)
+				return nil
+			}
+			floats = append(floats, f)
+		}
+	}
+	return floats
+}
+
 // set AppConfig. This is needed for testing.
 func configFrom(conf *Config) {
 	AppConfig = conf
@@ -296,6 +498,24 @@ func UpdateViper() {
 	viper.Set("player.audio_buffering_ms", AppConfig.Player.AudioBufferingMs)
 	viper.Set("player.local_cache_dir", AppConfig.Player.LocalCacheDir)
 	viper.Set("player.enable_local_cache", AppConfig.Player.EnableLocalCache)
+	viper.Set("player.crossfade_s", AppConfig.Player.CrossfadeS)
+	viper.Set("player.crossfade_album_aware", AppConfig.Player.CrossfadeAlbumAware)
+	viper.Set("player.prefetch_s", AppConfig.Player.PrefetchS)
+	viper.Set("player.prefetch_depth", AppConfig.Player.PrefetchDepth)
+	viper.Set("player.replay_gain", AppConfig.Player.ReplayGain)
+	viper.Set("player.replay_gain_preamp_db", AppConfig.Player.ReplayGainPreampDb)
+	viper.Set("player.replay_gain_clip_protection", AppConfig.Player.ReplayGainClipProtection)
+	viper.Set("player.equalizer_enabled", AppConfig.Player.EqualizerEnabled)
+	viper.Set("player.equalizer_preset", AppConfig.Player.EqualizerPreset)
+	viper.Set("player.equalizer_bands", AppConfig.Player.EqualizerBands)
+	viper.Set("player.audio_output", AppConfig.Player.AudioOutput)
+	viper.Set("player.audio_output_path", AppConfig.Player.AudioOutputPath)
+	viper.Set("player.audio_output_format", AppConfig.Player.AudioOutputFormat)
+	viper.Set("player.sleep_fade_s", AppConfig.Player.SleepFadeS)
+	viper.Set("player.retry_count", AppConfig.Player.RetryCount)
+	viper.Set("player.retry_backoff_ms", AppConfig.Player.RetryBackoffMs)
+	viper.Set("player.quality_profiles", AppConfig.Player.QualityProfiles)
+	viper.Set("player.quality_profile", AppConfig.Player.QualityProfile)
 
 	viper.Set("gui.search_results_limit", AppConfig.Gui.SearchResultsLimit)
 	viper.Set("gui.debug_mode", AppConfig.Gui.DebugMode)
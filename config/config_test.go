@@ -55,6 +55,33 @@ func TestConfigToFromViper(t *testing.T) {
 			EnableRemoteControl:   true,
 			LocalCacheDir:         "/tmp/jellycli",
 			EnableLocalCache:      true,
+			CrossfadeS:            3,
+			CrossfadeAlbumAware:   true,
+			PrefetchS:             30,
+			PrefetchDepth:         3,
+
+			ReplayGain:               "album",
+			ReplayGainPreampDb:       -2.5,
+			ReplayGainClipProtection: true,
+
+			EqualizerEnabled: true,
+			EqualizerPreset:  "custom",
+			EqualizerBands:   []float64{3, 2.5, 1, 0, 0, -1, 0, 1, 2, -1.5},
+
+			AudioOutput:       "file",
+			AudioOutputPath:   "/tmp/jellycli.wav",
+			AudioOutputFormat: "wav",
+
+			SleepFadeS: 15,
+
+			RetryCount:     3,
+			RetryBackoffMs: 500,
+
+			QualityProfiles: []QualityProfile{
+				{Name: "lossless"},
+				{Name: "mobile", Codec: "ogg", MaxBitrateKbps: 96},
+			},
+			QualityProfile: "mobile",
 		},
 		Gui: Gui{
 			PageSize:               100,
@@ -118,6 +145,24 @@ func TestInitEmptyConfig(t *testing.T) {
 			EnableRemoteControl:   true,
 			LocalCacheDir:         path.Join(cachedir, AppNameLower),
 			EnableLocalCache:      false,
+			PrefetchS:             5,
+			PrefetchDepth:         1,
+
+			ReplayGain:               "off",
+			ReplayGainClipProtection: true,
+
+			EqualizerPreset: "flat",
+			EqualizerBands:  []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+
+			AudioOutput: "speaker",
+
+			SleepFadeS: 10,
+
+			RetryCount:     2,
+			RetryBackoffMs: 1000,
+
+			QualityProfiles: DefaultQualityProfiles(),
+			QualityProfile:  "lossless",
 		},
 		Gui: Gui{
 			PageSize:            100,
@@ -211,6 +256,15 @@ func TestSanitizeConfig(t *testing.T) {
 	invalidConf.Player.HttpBufferingS = 5
 	invalidConf.Player.HttpBufferingLimitMem = 20
 	invalidConf.Player.LocalCacheDir = path.Join(cachedir, AppNameLower)
+	invalidConf.Player.ReplayGain = "off"
+	invalidConf.Player.EqualizerPreset = "flat"
+	invalidConf.Player.EqualizerBands = []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	invalidConf.Player.AudioOutput = "speaker"
+	invalidConf.Player.RetryBackoffMs = 1000
+	invalidConf.Player.PrefetchS = 5
+	invalidConf.Player.PrefetchDepth = 1
+	invalidConf.Player.QualityProfiles = DefaultQualityProfiles()
+	invalidConf.Player.QualityProfile = "lossless"
 
 	invalidConf.Gui.PageSize = 100
 	invalidConf.Gui.DoubleClickMs = 220
@@ -231,3 +285,62 @@ func TestSanitizeConfig(t *testing.T) {
 		t.Errorf("sanitized config invalid: %s", diff)
 	}
 }
+
+func TestPlayer_Output(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		path       string
+		override   string
+		wantOutput string
+		wantPath   string
+	}{
+		{name: "config", output: "file", path: "/tmp/out.wav", wantOutput: "file", wantPath: "/tmp/out.wav"},
+		{name: "pipe to stdout", output: "pipe", wantOutput: "pipe", wantPath: "-"},
+		{name: "override", output: "speaker", override: "null", wantOutput: "null"},
+		{name: "override with path", output: "file", path: "/tmp/out.wav", override: "pipe:/tmp/snapfifo",
+			wantOutput: "pipe", wantPath: "/tmp/snapfifo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AudioOutputOverride = tt.override
+			defer func() { AudioOutputOverride = "" }()
+			p := &Player{AudioOutput: tt.output, AudioOutputPath: tt.path}
+
+			output, path := p.Output()
+			if output != tt.wantOutput {
+				t.Errorf("output, got: %s, want: %s", output, tt.wantOutput)
+			}
+			if path != tt.wantPath {
+				t.Errorf("path, got: %s, want: %s", path, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestGetQualityProfiles(t *testing.T) {
+	viper.Reset()
+	// yaml file is read as list of maps
+	viper.Set("player.quality_profiles", []interface{}{
+		map[interface{}]interface{}{"name": "lossless"},
+		map[interface{}]interface{}{"name": "mobile", "codec": "OGG", "max_bitrate_kbps": 96},
+		map[interface{}]interface{}{"name": "unknown", "codec": "wma", "max_bitrate_kbps": -1},
+	})
+	p := &Player{QualityProfiles: getQualityProfiles("player.quality_profiles"), QualityProfile: "missing"}
+	p.sanitize()
+
+	want := []QualityProfile{
+		{Name: "lossless"},
+		{Name: "mobile", Codec: "ogg", MaxBitrateKbps: 96},
+		{Name: "unknown"},
+	}
+	if diff := cmp.Diff(want, p.QualityProfiles); diff != "" {
+		t.Errorf("quality profiles differ: %s", diff)
+	}
+	if p.QualityProfile != "lossless" {
+		t.Errorf("missing profile, got: %s, want: lossless", p.QualityProfile)
+	}
+	if got := p.GetQualityProfile("mobile").String(); got != "ogg 96 kbps" {
+		t.Errorf("profile description, got: %s, want: ogg 96 kbps", got)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package config
+
+// equalizer configuration
+const (
+	// EqualizerMaxGaindB is max gain (and min attenuation) of single equalizer band
+	EqualizerMaxGaindB = 12
+
+	// EqualizerQ is quality factor of each band, about one octave wide
+	EqualizerQ = 1.41
+
+	// EqualizerPresetFlat does not modify audio
+	EqualizerPresetFlat = "flat"
+	// EqualizerPresetCustom uses band gains from config
+	EqualizerPresetCustom = "custom"
+)
+
+// EqualizerFrequencies are center frequencies of equalizer bands in Hz
+var EqualizerFrequencies = []float64{31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000}
+
+// EqualizerPresetNames are equalizer presets in display order
+var EqualizerPresetNames = []string{
+	EqualizerPresetFlat, "bass", "treble", "vocal", "loudness", "headphones", "speakers", EqualizerPresetCustom,
+}
+
+// EqualizerPresets contains band gains in dB for each preset, except custom
+var EqualizerPresets = map[string][]float64{
+	EqualizerPresetFlat: {0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	"bass":              {6, 5, 4, 2, 0, 0, 0, 0, 0, 0},
+	"treble":            {0, 0, 0, 0, 0, 0, 2, 4, 5, 6},
+	"vocal":             {-2, -2, -1, 1, 3, 3, 2, 1, 0, -1},
+	"loudness":          {5, 4, 2, 0, -1, 0, 0, 2, 4, 5},
+	"headphones":        {3, 2, 1, 0, -1, -1, 0, 1, 2, 3},
+	"speakers":          {4, 3, 1, 0, 0, 0, 0, 1, 2, 1},
+}
+
+// EqualizerGains returns band gains in dB for preset. Custom preset returns custom gains.
+func EqualizerGains(preset string, custom []float64) []float64 {
+	if preset == EqualizerPresetCustom {
+		return custom
+	}
+	gains, ok := EqualizerPresets[preset]
+	if !ok {
+		return EqualizerPresets[EqualizerPresetFlat]
+	}
+	return gains
+}
@@ -26,28 +26,36 @@ var (
 
 // GlobalBindings can have only one action since they override all others
 type GlobalBindings struct {
-	PlayPause  tcell.Key
-	Stop       tcell.Key
-	Next       tcell.Key
-	Previous   tcell.Key
-	Forward    tcell.Key
-	Backward   tcell.Key
-	VolumeUp   tcell.Key
-	VolumeDown tcell.Key
-	MuteUnmute tcell.Key
-	Shuffle    tcell.Key
+	PlayPause   tcell.Key
+	Stop        tcell.Key
+	Next        tcell.Key
+	Previous    tcell.Key
+	Forward     tcell.Key
+	Backward    tcell.Key
+	VolumeUp    tcell.Key
+	VolumeDown  tcell.Key
+	MuteUnmute  tcell.Key
+	Shuffle     tcell.Key
+	ShuffleMode tcell.Key
+	Repeat      tcell.Key
+	SpeedUp     tcell.Key
+	SpeedDown   tcell.Key
+	SleepTimer  tcell.Key
+	Spectrum    tcell.Key
+	Quality     tcell.Key
 }
 
 // NavigationBarBindings also override every other key
 type NavigationBarBindings struct {
-	Quit     tcell.Key
-	Help     tcell.Key
-	View     tcell.Key
-	Search   tcell.Key
-	Queue    tcell.Key
-	History  tcell.Key
-	Settings tcell.Key
-	Dump     tcell.Key
+	Quit      tcell.Key
+	Help      tcell.Key
+	View      tcell.Key
+	Search    tcell.Key
+	Queue     tcell.Key
+	History   tcell.Key
+	Settings  tcell.Key
+	Equalizer tcell.Key
+	Dump      tcell.Key
 }
 
 // MovingBindings control moving cursor inside panel
@@ -77,23 +85,31 @@ type KeyBindings struct {
 func DefaultKeyBindings() KeyBindings {
 	k := KeyBindings{
 		Global: GlobalBindings{
-			PlayPause:  tcell.KeyF6,
-			Stop:       tcell.KeyF5,
-			Next:       tcell.KeyF7,
-			Previous:   tcell.KeyF4,
-			Forward:    0,
-			Backward:   0,
-			VolumeUp:   tcell.KeyF10,
-			VolumeDown: tcell.KeyF9,
-			MuteUnmute: tcell.KeyCtrlU,
-			Shuffle:    tcell.KeyCtrlD,
+			PlayPause:   tcell.KeyF6,
+			Stop:        tcell.KeyF5,
+			Next:        tcell.KeyF7,
+			Previous:    tcell.KeyF4,
+			Forward:     0,
+			Backward:    0,
+			VolumeUp:    tcell.KeyF10,
+			VolumeDown:  tcell.KeyF9,
+			MuteUnmute:  tcell.KeyCtrlU,
+			Shuffle:     tcell.KeyCtrlD,
+			ShuffleMode: tcell.KeyCtrlG,
+			Repeat:      tcell.KeyCtrlR,
+			SpeedUp:     tcell.KeyF12,
+			SpeedDown:   tcell.KeyF11,
+			SleepTimer:  tcell.KeyCtrlT,
+			Spectrum:    tcell.KeyF8,
+			Quality:     tcell.KeyCtrlB,
 		},
 		NavigationBar: NavigationBarBindings{
-			Help:    tcell.KeyF1,
-			Search:  tcell.KeyCtrlF,
-			Queue:   tcell.KeyF2,
-			History: tcell.KeyF3,
-			Dump:    tcell.KeyCtrlW,
+			Help:      tcell.KeyF1,
+			Search:    tcell.KeyCtrlF,
+			Queue:     tcell.KeyF2,
+			History:   tcell.KeyF3,
+			Equalizer: tcell.KeyCtrlE,
+			Dump:      tcell.KeyCtrlW,
 		},
 		Moving: MovingBindings{
 			Up:    tcell.KeyUp,
@@ -0,0 +1,136 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"strconv"
+	"strings"
+)
+
+// QualityProfile describes how server transcodes songs before streaming them.
+type QualityProfile struct {
+	Name string `yaml:"name"`
+	// Codec is one of QualityCodecs. Empty codec streams original file, if server allows it.
+	Codec string `yaml:"codec"`
+	// MaxBitrateKbps limits stream bitrate in kbit/s, 0 for no limit
+	MaxBitrateKbps int `yaml:"max_bitrate_kbps"`
+}
+
+// QualityCodecs are codecs that songs can be transcoded to, and that player can decode.
+var QualityCodecs = []string{"mp3", "ogg", "flac"}
+
+// DefaultQualityProfiles are used if there are no profiles in config.
+func DefaultQualityProfiles() []QualityProfile {
+	return []QualityProfile{
+		{Name: "lossless"},
+		{Name: "high 320k", Codec: "mp3", MaxBitrateKbps: 320},
+		{Name: "mobile 128k", Codec: "mp3", MaxBitrateKbps: 128},
+	}
+}
+
+// String returns short description of profile, e.g. 'mp3 320 kbps'.
+func (q QualityProfile) String() string {
+	codec := q.Codec
+	if codec == "" {
+		codec = "original"
+	}
+	if q.MaxBitrateKbps > 0 {
+		return fmt.Sprintf("%s %d kbps", codec, q.MaxBitrateKbps)
+	}
+	return codec
+}
+
+func (q *QualityProfile) sanitize() {
+	q.Name = strings.TrimSpace(q.Name)
+	q.Codec = strings.ToLower(q.Codec)
+	valid := q.Codec == ""
+	for _, v := range QualityCodecs {
+		if q.Codec == v {
+			valid = true
+		}
+	}
+	if !valid {
+		logrus.Warningf("quality profile %s: unsupported codec %s, stream original file", q.Name, q.Codec)
+		q.Codec = ""
+	}
+	if q.MaxBitrateKbps < 0 {
+		q.MaxBitrateKbps = 0
+	}
+}
+
+// GetQualityProfile returns quality profile with given name. If there is no such profile, return first profile.
+func (p *Player) GetQualityProfile(name string) QualityProfile {
+	for _, v := range p.QualityProfiles {
+		if v.Name == name {
+			return v
+		}
+	}
+	if len(p.QualityProfiles) == 0 {
+		return QualityProfile{}
+	}
+	return p.QualityProfiles[0]
+}
+
+// getQualityProfiles reads list of quality profiles.
+func getQualityProfiles(key string) []QualityProfile {
+	var values []interface{}
+	switch v := viper.Get(key).(type) {
+	case []QualityProfile:
+		return v
+	case []interface{}:
+		values = v
+	default:
+		return nil
+	}
+
+	profiles := make([]QualityProfile, 0, len(values))
+	for _, v := range values {
+		fields := map[string]interface{}{}
+		switch value := v.(type) {
+		case map[string]interface{}:
+			fields = value
+		case map[interface{}]interface{}:
+			for k, field := range value {
+				fields[fmt.Sprint(k)] = field
+			}
+		default:
+			logrus.Errorf("invalid quality profile in %s: %v", key, v)
+			continue
+		}
+		profile := QualityProfile{}
+		if name, ok := fields["name"]; ok {
+			profile.Name = fmt.Sprint(name)
+		}
+		if codec, ok := fields["codec"]; ok {
+			profile.Codec = fmt.Sprint(codec)
+		}
+		if bitrate, ok := fields["max_bitrate_kbps"]; ok {
+			kbps, err := strconv.Atoi(fmt.Sprint(bitrate))
+			if err != nil {
+				logrus.Errorf("invalid bitrate in quality profile %s: %v", profile.Name, err)
+			}
+			profile.MaxBitrateKbps = kbps
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
@@ -36,15 +36,29 @@ var (
 	// how many recent song to show, if limited
 	LimitedRecentlyPlayedCount = 24
 	AudioBufferPeriod          = time.Millisecond * 100
+	// AudioDecodeBuffer is how much audio is decoded ahead of playback
+	AudioDecodeBuffer = time.Second * 2
+	// PrefetchMaxDepth is max number of upcoming songs to download ahead
+	PrefetchMaxDepth = 10
+	// Streams are downloaded as fast as server sends them, without throttling. Player.HttpBufferingLimitMem
+	// only limits how much of a stream is kept in memory, rest of it is stored in temporary file.
+
+	// StreamReconnectCount is how many times interrupted download is resumed before giving up
+	StreamReconnectCount = 5
+	// StreamReconnectDelay is delay before first reconnect. Delay is doubled for each attempt.
+	StreamReconnectDelay = time.Second
 
 	VolumeStepSize = 5
 )
 
 // audio configuration
 const (
-	// AudioSamplingRate is default sampling rate. This may vary depending on song being played.
+	// AudioSamplingRate is output sampling rate. Songs with different sampling rate are resampled.
 	AudioSamplingRate = 44100
 
+	// AudioResampleQuality is quality for resampling songs, see beep.Resample
+	AudioResampleQuality = 4
+
 	// Volume range in decibels
 	AudioMinVolumedB = -6
 	AudioMaxVolumedB = 0
@@ -68,3 +82,7 @@ var LogFile string
 
 // ConfigFile is absolute location for configuration file
 var ConfigFile string
+
+// AudioOutputOverride is audio output set with command line flag, as '<output>[:<path>]'. It overrides config
+// and is not persisted.
+var AudioOutputOverride string
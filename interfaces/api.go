@@ -58,12 +58,15 @@ type ApiPlaybackState struct {
 	IsMuted  bool
 	// Total length of current playlist in seconds
 	PlaylistLength int
-	// Position in seconds
+	// Position in seconds, in track time
 	Position int
 	// Volume in 0-100
 	Volume int
 
 	Shuffle bool
+	Repeat  RepeatMode
+	// Speed is playback speed, 1 being normal
+	Speed float64
 
 	Queue []models.Id
 }
@@ -49,6 +49,11 @@ type QueueController interface {
 	// Reorder shifts item in current index to left or right (earlier / later) by one depending on left.
 	// If down, play it earlier, else play it later. Returns true if reorder was made.
 	Reorder(currentIndex int, down bool) bool
+
+	// ReorderRange shifts songs in indices start...end (inclusive) by one like Reorder, keeping their order.
+	// Returns true if reorder was made.
+	ReorderRange(start, end int, down bool) bool
+
 	//GetHistory get's n past songs that has been played.
 	GetHistory(n int) []*models.Song
 	//AddQueueChangedCallback sets function that is called every time queue changes.
@@ -57,8 +62,21 @@ type QueueController interface {
 	// RemoveSongs remove song in given index. First index is 0.
 	RemoveSong(index int)
 
+	// RemoveSongs removes songs in given indices. First song cannot be removed.
+	RemoveSongs(indices []int)
+
 	// SetHistoryChangedCallback sets a function that gets called every time history items update
 	SetHistoryChangedCallback(func(songs []*models.Song))
+
+	// SongError returns reason why song could not be played, or nil if song has not failed.
+	SongError(id models.Id) error
+
+	// UndoQueue reverts latest edit to queue: adding, removing or reordering songs, clearing queue or
+	// toggling shuffle. Song that is playing is not interrupted. Returns false if there is nothing to undo.
+	UndoQueue() bool
+
+	// RedoQueue applies latest edit that was undone. Returns false if there is nothing to redo.
+	RedoQueue() bool
 }
 
 //MediaManager manages media: artists, albums, songs
@@ -114,6 +132,32 @@ type ItemController interface {
 	// GetLink returns a link to item that can be opened with browser.
 	// If there is no link or item is invalid, empty link is returned.
 	GetLink(item models.Item) string
+
+	// CreatePlaylist creates new playlist with given songs. If server does not support editing playlists,
+	// it is saved locally.
+	CreatePlaylist(name string, songs []*models.Song) (*models.Playlist, error)
+
+	// RenamePlaylist renames playlist.
+	RenamePlaylist(playlist *models.Playlist, name string) error
+
+	// DeletePlaylist deletes playlist.
+	DeletePlaylist(playlist *models.Playlist) error
+
+	// AddPlaylistSongs appends songs to playlist. Playlist songs are updated.
+	AddPlaylistSongs(playlist *models.Playlist, songs []*models.Song) error
+
+	// RemovePlaylistSongs removes songs in given indices from playlist. Playlist songs are updated.
+	RemovePlaylistSongs(playlist *models.Playlist, indices []int) error
+
+	// MovePlaylistSong moves song in index to newIndex. Playlist songs are updated.
+	MovePlaylistSong(playlist *models.Playlist, index, newIndex int) error
+
+	// SetPlaylistSongs replaces playlist songs and returns playlist that songs were saved to.
+	// If server does not support editing playlists, songs are saved to new local playlist.
+	SetPlaylistSongs(playlist *models.Playlist, songs []*models.Song) (*models.Playlist, error)
+
+	// SetFavorite adds songs to favorites, or removes them from favorites.
+	SetFavorite(songs []*models.Song, favorite bool) error
 }
 
 // Paging. First page is 0
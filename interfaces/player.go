@@ -18,18 +18,59 @@
 
 package interfaces
 
-import "tryffel.net/go/jellycli/models"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"tryffel.net/go/jellycli/models"
+)
 
-// AudioState is audio player state, playing song, stopped
+// AudioState is audio player state. Song moves from Idle to Loading when it is being downloaded,
+// to Buffering when it is being decoded, and to Playing once there is audio to play. Playing and Paused
+// toggle with each other, and playing song returns to Buffering if it runs out of decoded audio.
+// Stopping moves any state to Idle, and failure to download or play song moves it to Error.
 type AudioState int
 
 const (
-	// AudioStateStopped, no audio to play
-	AudioStateStopped AudioState = iota
+	// AudioStateIdle, no audio to play
+	AudioStateIdle AudioState = iota
+	// AudioStateLoading, song is being downloaded
+	AudioStateLoading
+	// AudioStateBuffering, song is being decoded and there is no audio to play yet
+	AudioStateBuffering
 	// AudioStatePlaying, playing song
 	AudioStatePlaying
+	// AudioStatePaused, song is paused
+	AudioStatePaused
+	// AudioStateError, song could not be played. Error is set in AudioStatus.Error.
+	AudioStateError
 )
 
+func (a AudioState) String() string {
+	switch a {
+	case AudioStateIdle:
+		return "Idle"
+	case AudioStateLoading:
+		return "Loading"
+	case AudioStateBuffering:
+		return "Buffering"
+	case AudioStatePlaying:
+		return "Playing"
+	case AudioStatePaused:
+		return "Paused"
+	case AudioStateError:
+		return "Error"
+	default:
+		return "Unknown"
+	}
+}
+
+// HasSong returns true if song is loaded to audio: it is buffering, playing or paused.
+func (a AudioState) HasSong() bool {
+	return a == AudioStateBuffering || a == AudioStatePlaying || a == AudioStatePaused
+}
+
 // AudioAction is an action for audio player, set volume, go to next
 type AudioAction int
 
@@ -52,8 +93,167 @@ const (
 	AudioActionSetVolume
 
 	AudioActionShuffleChanged
+	// AudioActionRepeatChanged changes repeat mode
+	AudioActionRepeatChanged
+	// AudioActionSpeedChanged changes playback speed
+	AudioActionSpeedChanged
+	// AudioActionSleepTimerChanged sets or clears sleep timer
+	AudioActionSleepTimerChanged
+	// AudioActionQualityChanged changes quality profile
+	AudioActionQualityChanged
 )
 
+// RepeatMode controls what to play after current song
+type RepeatMode int
+
+const (
+	// RepeatModeOff plays queue once
+	RepeatModeOff RepeatMode = iota
+	// RepeatModeOne repeats current song
+	RepeatModeOne
+	// RepeatModeAll repeats whole queue
+	RepeatModeAll
+)
+
+func (r RepeatMode) String() string {
+	switch r {
+	case RepeatModeOff:
+		return "Off"
+	case RepeatModeOne:
+		return "One"
+	case RepeatModeAll:
+		return "All"
+	default:
+		return "Unknown"
+	}
+}
+
+// Next returns next repeat mode, cycling off -> all -> one -> off.
+func (r RepeatMode) Next() RepeatMode {
+	switch r {
+	case RepeatModeOff:
+		return RepeatModeAll
+	case RepeatModeAll:
+		return RepeatModeOne
+	default:
+		return RepeatModeOff
+	}
+}
+
+// ShuffleMode controls how queue is ordered when shuffle is enabled
+type ShuffleMode int
+
+const (
+	// ShuffleModeRandom orders songs randomly
+	ShuffleModeRandom ShuffleMode = iota
+	// ShuffleModeAlbum orders albums randomly and keeps songs of each album in track order
+	ShuffleModeAlbum
+	// ShuffleModeArtist orders songs randomly so that songs of the same artist are spread apart
+	ShuffleModeArtist
+	// ShuffleModeWeighted orders songs randomly, favoring favorite and rarely played songs
+	ShuffleModeWeighted
+)
+
+var shuffleModes = []ShuffleMode{ShuffleModeRandom, ShuffleModeAlbum, ShuffleModeArtist, ShuffleModeWeighted}
+
+func (s ShuffleMode) String() string {
+	switch s {
+	case ShuffleModeRandom:
+		return "Random"
+	case ShuffleModeAlbum:
+		return "Album"
+	case ShuffleModeArtist:
+		return "Artist"
+	case ShuffleModeWeighted:
+		return "Weighted"
+	default:
+		return "Unknown"
+	}
+}
+
+// Next returns next shuffle mode, cycling random -> album -> artist -> weighted -> random.
+func (s ShuffleMode) Next() ShuffleMode {
+	return shuffleModes[(int(s)+1)%len(shuffleModes)]
+}
+
+// ParseShuffleMode parses shuffle mode: 'random', 'album', 'artist' or 'weighted'.
+func ParseShuffleMode(value string) (ShuffleMode, error) {
+	value = strings.TrimSpace(value)
+	for _, v := range shuffleModes {
+		if strings.EqualFold(value, v.String()) {
+			return v, nil
+		}
+	}
+	return ShuffleModeRandom, fmt.Errorf("invalid shuffle mode '%s', expected 'random', 'album', 'artist' "+
+		"or 'weighted'", value)
+}
+
+// SleepMode controls when sleep timer stops playback
+type SleepMode int
+
+const (
+	// SleepModeOff disables sleep timer
+	SleepModeOff SleepMode = iota
+	// SleepModeTimer stops playback after given duration
+	SleepModeTimer
+	// SleepModeEndOfTrack stops playback when current song completes
+	SleepModeEndOfTrack
+	// SleepModeEndOfAlbum stops playback when last song of current album in queue completes
+	SleepModeEndOfAlbum
+)
+
+func (s SleepMode) String() string {
+	switch s {
+	case SleepModeOff:
+		return "Off"
+	case SleepModeTimer:
+		return "Timer"
+	case SleepModeEndOfTrack:
+		return "End of track"
+	case SleepModeEndOfAlbum:
+		return "End of album"
+	default:
+		return "Unknown"
+	}
+}
+
+// SleepTimer stops playback after given time, or at the end of current track or album.
+type SleepTimer struct {
+	Mode SleepMode
+	// Duration is total duration of timer, if mode is SleepModeTimer
+	Duration time.Duration
+	// At is time when timer stops playback, if mode is SleepModeTimer
+	At time.Time
+}
+
+// ParseSleepTimer parses sleep timer: 'off', 'track', 'album', minutes (e.g. '30') or duration (e.g. '1h30m').
+func ParseSleepTimer(value string) (SleepMode, time.Duration, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "off", "":
+		return SleepModeOff, 0, nil
+	case "track":
+		return SleepModeEndOfTrack, 0, nil
+	case "album":
+		return SleepModeEndOfAlbum, 0, nil
+	}
+
+	var duration time.Duration
+	if minutes, err := strconv.Atoi(value); err == nil {
+		duration = time.Duration(minutes) * time.Minute
+	} else {
+		duration, err = time.ParseDuration(value)
+		if err != nil {
+			return SleepModeOff, 0, fmt.Errorf("invalid sleep timer '%s', "+
+				"expected 'off', 'track', 'album', minutes or duration", value)
+		}
+	}
+	if duration <= 0 {
+		return SleepModeOff, 0, fmt.Errorf("sleep timer duration must be positive")
+	}
+	return SleepModeTimer, duration, nil
+}
+
 // AudioTick is alias for millisecond
 type AudioTick int
 
@@ -94,6 +294,34 @@ func (a AudioVolume) Add(vol int) AudioVolume {
 	return result
 }
 
+// AudioSpeed is playback speed, where 1 is normal speed. Pitch is preserved.
+type AudioSpeed float64
+
+const (
+	AudioSpeedMin    = 0.5
+	AudioSpeedMax    = 2.0
+	AudioSpeedNormal = 1.0
+	// AudioSpeedStep is step size when changing speed
+	AudioSpeedStep = 0.25
+)
+
+// InRange returns true if speed is in allowed range
+func (a AudioSpeed) InRange() bool {
+	return a >= AudioSpeedMin && a <= AudioSpeedMax
+}
+
+// Add adds value to speed. Negative values are allowed. Always returns speed that's in allowed range.
+func (a AudioSpeed) Add(speed float64) AudioSpeed {
+	result := a + AudioSpeed(speed)
+	if result < AudioSpeedMin {
+		return AudioSpeedMin
+	}
+	if result > AudioSpeedMax {
+		return AudioSpeedMax
+	}
+	return result
+}
+
 // AudioStatus contains audio player status
 type AudioStatus struct {
 	State  AudioState
@@ -107,8 +335,21 @@ type AudioStatus struct {
 	SongPast AudioTick
 	Volume   AudioVolume
 	Muted    bool
-	Paused   bool
 	Shuffle  bool
+	Repeat   RepeatMode
+	Speed    AudioSpeed
+	Sleep    SleepTimer
+	// Error is set when State is AudioStateError
+	Error error
+	// ShuffleMode is used when Shuffle is enabled
+	ShuffleMode ShuffleMode
+
+	// Quality is name of active quality profile
+	Quality string
+	// Format is format of the stream that is playing
+	Format AudioFormat
+	// Bitrate of the stream that is playing in kbit/s, 0 if not known
+	Bitrate int
 }
 
 func (a *AudioStatus) Clear() {
@@ -135,9 +376,10 @@ type Player interface {
 	Next()
 	//Previous plays last played song (first in history) if there is one.
 	Previous()
-	//Seek seeks forward given seconds
+	//Seek seeks forward given ticks. Negative ticks seeks backwards.
 	Seek(ticks AudioTick)
-	//SeekBackwards seeks backwards given seconds
+	//SetPosition seeks to given position in current song.
+	SetPosition(position AudioTick)
 	//AddStatusCallback adds callback that get's called every time status has changed,
 	//including playback progress
 	AddStatusCallback(func(status AudioStatus))
@@ -149,6 +391,30 @@ type Player interface {
 	ToggleMute()
 
 	SetShuffle(enabled bool)
+	// SetShuffleMode sets how queue is shuffled. If shuffle is enabled, queue is shuffled again.
+	SetShuffleMode(mode ShuffleMode)
+	// SetRepeat sets repeat mode
+	SetRepeat(mode RepeatMode)
+	// SetEqualizer enables or disables equalizer and sets gain in dB for each band
+	SetEqualizer(enabled bool, gains []float64)
+	// SetSpeed sets playback speed in range of [AudioSpeedMin, AudioSpeedMax]
+	SetSpeed(speed AudioSpeed)
+	// SetSleepTimer sets sleep timer. Duration is used only with SleepModeTimer.
+	SetSleepTimer(mode SleepMode, duration time.Duration)
+	// SetQuality sets active quality profile by name. Profile applies to songs that are downloaded after this.
+	SetQuality(profile string)
+	// EnableSpectrum enables or disables collecting audio for Spectrum.
+	EnableSpectrum(enabled bool)
+	// Spectrum fills levels with current audio level in each frequency band, in range [0,1].
+	// Bands are spaced logarithmically.
+	Spectrum(levels []float64)
+
+	// SavedSession returns session saved on previous run, if it has not been resumed or discarded yet.
+	SavedSession() (*models.Session, bool)
+	// ResumeSession restores saved session and loads first song paused at saved position.
+	ResumeSession() error
+	// DiscardSession discards saved session.
+	DiscardSession()
 }
 
 // Queuer contains read-only methods for song queue.
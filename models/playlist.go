@@ -18,6 +18,12 @@
 
 package models
 
+import "strings"
+
+// LocalPlaylistPrefix is prefix for ids of playlists that are saved only locally, because server
+// could not save them.
+const LocalPlaylistPrefix = "local-"
+
 // Playlist is a list of songs. It has no artists itself, but songs do have albums and artists.
 type Playlist struct {
 	Id       Id     `db:"id"`
@@ -55,3 +61,47 @@ func (p Playlist) GetParent() Id {
 func (p Playlist) GetType() ItemType {
 	return TypePlaylist
 }
+
+// IsLocal returns true if playlist is saved only locally.
+func (p Playlist) IsLocal() bool {
+	return strings.HasPrefix(p.Id.String(), LocalPlaylistPrefix)
+}
+
+// SetSongs sets playlist songs and updates song count and duration.
+func (p *Playlist) SetSongs(songs []*Song) {
+	p.Songs = songs
+	p.SongCount = len(songs)
+	p.Duration = 0
+	for _, v := range songs {
+		p.Duration += v.Duration
+	}
+}
+
+// RemoveSongs removes songs in given indices. Invalid indices are ignored.
+func (p *Playlist) RemoveSongs(indices []int) {
+	remove := make(map[int]bool, len(indices))
+	for _, v := range indices {
+		remove[v] = true
+	}
+	songs := make([]*Song, 0, len(p.Songs))
+	for i, v := range p.Songs {
+		if !remove[i] {
+			songs = append(songs, v)
+		}
+	}
+	p.SetSongs(songs)
+}
+
+// MoveSong moves song in index to newIndex. Other songs are shifted to fill the gap.
+func (p *Playlist) MoveSong(index, newIndex int) {
+	if index < 0 || index >= len(p.Songs) || newIndex < 0 || newIndex >= len(p.Songs) {
+		return
+	}
+	song := p.Songs[index]
+	if index < newIndex {
+		copy(p.Songs[index:newIndex], p.Songs[index+1:newIndex+1])
+	} else {
+		copy(p.Songs[newIndex+1:index+1], p.Songs[newIndex:index])
+	}
+	p.Songs[newIndex] = song
+}
@@ -0,0 +1,92 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func playlistSongIds(p *Playlist) []Id {
+	ids := make([]Id, len(p.Songs))
+	for i, v := range p.Songs {
+		ids[i] = v.Id
+	}
+	return ids
+}
+
+func testPlaylist() *Playlist {
+	p := &Playlist{}
+	p.SetSongs([]*Song{{Id: "a", Duration: 10}, {Id: "b", Duration: 20}, {Id: "c", Duration: 30},
+		{Id: "d", Duration: 40}})
+	return p
+}
+
+func TestPlaylist_RemoveSongs(t *testing.T) {
+	tests := []struct {
+		name    string
+		indices []int
+		want    []Id
+	}{
+		{name: "first", indices: []int{0}, want: []Id{"b", "c", "d"}},
+		{name: "multiple", indices: []int{3, 1}, want: []Id{"a", "c"}},
+		{name: "invalid", indices: []int{-1, 4}, want: []Id{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPlaylist()
+			p.RemoveSongs(tt.indices)
+			if got := playlistSongIds(p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RemoveSongs() got = %v, want %v", got, tt.want)
+			}
+			if p.SongCount != len(tt.want) {
+				t.Errorf("RemoveSongs() song count = %d, want %d", p.SongCount, len(tt.want))
+			}
+		})
+	}
+
+	p := testPlaylist()
+	p.RemoveSongs([]int{1})
+	if p.Duration != 80 {
+		t.Errorf("RemoveSongs() duration = %d, want 80", p.Duration)
+	}
+}
+
+func TestPlaylist_MoveSong(t *testing.T) {
+	tests := []struct {
+		name     string
+		index    int
+		newIndex int
+		want     []Id
+	}{
+		{name: "down", index: 0, newIndex: 2, want: []Id{"b", "c", "a", "d"}},
+		{name: "up", index: 3, newIndex: 1, want: []Id{"a", "d", "b", "c"}},
+		{name: "same", index: 1, newIndex: 1, want: []Id{"a", "b", "c", "d"}},
+		{name: "invalid", index: 1, newIndex: 4, want: []Id{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPlaylist()
+			p.MoveSong(tt.index, tt.newIndex)
+			if got := playlistSongIds(p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MoveSong() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
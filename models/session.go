@@ -0,0 +1,63 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import "time"
+
+// Session is player state that is saved on shutdown and can be resumed on next start.
+type Session struct {
+	// Queue contains queued songs, first one being the song that was playing.
+	Queue []SessionSong
+	// History contains played songs, latest first.
+	History []SessionSong
+	// Position is playback position in first song of queue.
+	Position time.Duration
+	Volume   int
+	Shuffle  bool
+	Saved    time.Time
+}
+
+// SessionSong identifies saved song. Besides id, it contains enough info to find the song again in its album,
+// in case server has changed the id, e.g. after rescanning the library.
+type SessionSong struct {
+	Id         Id     `db:"id"`
+	Album      Id     `db:"album"`
+	Name       string `db:"name"`
+	Index      int    `db:"song_index"`
+	DiscNumber int    `db:"disc_number"`
+}
+
+// NewSessionSong returns session song for song.
+func NewSessionSong(song *Song) SessionSong {
+	return SessionSong{
+		Id:         song.Id,
+		Album:      song.Album,
+		Name:       song.Name,
+		Index:      song.Index,
+		DiscNumber: song.DiscNumber,
+	}
+}
+
+// Matches returns true if song is the same song, either by id or by its position and name in album.
+func (s *SessionSong) Matches(song *Song) bool {
+	if s.Id == song.Id {
+		return true
+	}
+	return s.Album == song.Album && s.DiscNumber == song.DiscNumber && s.Index == song.Index && s.Name == song.Name
+}
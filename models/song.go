@@ -35,6 +35,15 @@ type Song struct {
 	AlbumArtist Id `db:"artist"`
 
 	Favorite bool `db:"favorite"`
+	// PlayCount is how many times user has played song, if server reports it
+	PlayCount int `db:"play_count"`
+
+	// TrackGain and AlbumGain are ReplayGain values in dB. Zero if unknown.
+	TrackGain float64 `db:"track_gain"`
+	AlbumGain float64 `db:"album_gain"`
+	// TrackPeak and AlbumPeak are ReplayGain peak amplitudes, where 1.0 is full scale. Zero if unknown.
+	TrackPeak float64 `db:"track_peak"`
+	AlbumPeak float64 `db:"album_peak"`
 }
 
 func (s *Song) GetId() Id {
@@ -38,6 +38,8 @@ type Stats struct {
 	ServerInfo *ServerInfo
 
 	StorageInfo StorageInfo
+
+	Playback PlaybackStats
 }
 
 // HeapString returns heap usage in human-readable format
@@ -66,6 +68,28 @@ type ServerInfo struct {
 	Misc map[string]string
 }
 
+// PlaybackStats describes health of audio decode buffer.
+type PlaybackStats struct {
+	// BufferSize is how much audio is decoded ahead of playback at most
+	BufferSize time.Duration
+	// Buffered is how much audio of current song is decoded and ready to play
+	Buffered time.Duration
+	// NextBuffered is how much audio of next song is decoded, if next song is already queued
+	NextBuffered time.Duration
+	// Underruns is how many times playback has run out of decoded audio
+	Underruns int
+	// UnderrunTime is total duration of silence played due to underruns
+	UnderrunTime time.Duration
+}
+
+// BufferHealth returns how full buffer of current song is, in percents.
+func (p PlaybackStats) BufferHealth() int {
+	if p.BufferSize <= 0 {
+		return 0
+	}
+	return int(p.Buffered * 100 / p.BufferSize)
+}
+
 type StorageInfo struct {
 	DbSize      int
 	DbFile      string
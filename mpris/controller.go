@@ -71,6 +71,7 @@ func NewController(controller interfaces.Player) (c *MediaController, err error)
 
 	player := &Player{MediaController: c}
 	c.dbus.Export(player, basePath, objectName("Player"))
+	c.dbus.Export(&Jellycli{MediaController: c}, basePath, jellycliObject)
 
 	c.dbus.Export(introspect.NewIntrospectable(c.IntrospectNode()), basePath,
 		"org.freedesktop.DBus.Introspectable")
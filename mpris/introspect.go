@@ -202,6 +202,31 @@ func (m *MediaController) IntrospectNode() *introspect.Node {
 					},
 				},
 			},
+			introspect.Interface{
+				Name: jellycliObject,
+				Methods: []introspect.Method{
+					introspect.Method{
+						Name: "SetSleepTimer",
+						Args: []introspect.Arg{
+							introspect.Arg{
+								Name:      "Timer",
+								Type:      "s",
+								Direction: "in",
+							},
+						},
+					},
+					introspect.Method{
+						Name: "SetShuffleMode",
+						Args: []introspect.Arg{
+							introspect.Arg{
+								Name:      "Mode",
+								Type:      "s",
+								Direction: "in",
+							},
+						},
+					},
+				},
+			},
 			// TODO: This interface is not fully implemented.
 			// introspect.Interface{
 			// 	Name: "org.mpris.MediaPlayer2.TrackList",
@@ -0,0 +1,59 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package mpris
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/interfaces"
+)
+
+// jellycliObject is the interface for features that are not part of mpris specification.
+// It is exported in the same path as mpris interfaces.
+const jellycliObject = "net.tryffel.Jellycli"
+
+// Jellycli is a DBus object for jellycli-specific features, e.g.:
+// dbus-send --session --dest=<name> --type=method_call /org/mpris/MediaPlayer2 \
+// net.tryffel.Jellycli.SetSleepTimer string:30m
+type Jellycli struct {
+	*MediaController
+}
+
+// SetShuffleMode sets shuffle mode: 'random', 'album', 'artist' or 'weighted'. Shuffle itself is enabled
+// with mpris Shuffle property.
+func (j *Jellycli) SetShuffleMode(mode string) *dbus.Error {
+	shuffleMode, err := interfaces.ParseShuffleMode(mode)
+	if err != nil {
+		logrus.Warningf("dbus set shuffle mode: %v", err)
+		return dbus.MakeFailedError(err)
+	}
+	j.controller.SetShuffleMode(shuffleMode)
+	return nil
+}
+
+// SetSleepTimer sets sleep timer: 'off', 'track', 'album', minutes (e.g. '30') or duration (e.g. '1h30m').
+func (j *Jellycli) SetSleepTimer(timer string) *dbus.Error {
+	mode, duration, err := interfaces.ParseSleepTimer(timer)
+	if err != nil {
+		logrus.Warningf("dbus set sleep timer: %v", err)
+		return dbus.MakeFailedError(err)
+	}
+	j.controller.SetSleepTimer(mode, duration)
+	return nil
+}
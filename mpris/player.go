@@ -22,6 +22,7 @@ package mpris
 
 import (
 	"errors"
+	"fmt"
 	"github.com/godbus/dbus"
 	"github.com/godbus/dbus/prop"
 	"github.com/sirupsen/logrus"
@@ -81,20 +82,23 @@ const (
 	LoopStatusPlaylist LoopStatus = "Playlist"
 )
 
+// playbackStatusFromState maps player state to mpris status. Loading and buffering song is reported as playing,
+// since playback starts without user action once song is ready.
+func playbackStatusFromState(state interfaces.AudioState) PlaybackStatus {
+	switch state {
+	case interfaces.AudioStateLoading, interfaces.AudioStateBuffering, interfaces.AudioStatePlaying:
+		return PlaybackStatusPlaying
+	case interfaces.AudioStatePaused:
+		return PlaybackStatusPaused
+	default:
+		return PlaybackStatusStopped
+	}
+}
+
 //UpdateStatus updates status to dbus
 func (p *Player) UpdateStatus(state interfaces.AudioStatus) {
 	p.lastState = state
-	var playStatus PlaybackStatus
-	switch state.State {
-	case interfaces.AudioStatePlaying:
-		playStatus = PlaybackStatusPlaying
-	case interfaces.AudioStateStopped:
-		playStatus = PlaybackStatusStopped
-	}
-
-	if state.State == interfaces.AudioStatePlaying && state.Paused {
-		playStatus = PlaybackStatusPaused
-	}
+	playStatus := playbackStatusFromState(state.State)
 	object := objectName("Player")
 
 	var pos int64 = 0
@@ -117,6 +121,18 @@ func (p *Player) UpdateStatus(state interfaces.AudioStatus) {
 		logrus.Error(err)
 		return
 	}
+
+	// LoopStatus and Rate have callbacks, which must not be called here
+	p.props.SetMust(object, "LoopStatus", loopStatusFromRepeat(state.Repeat))
+	if state.Speed != 0 {
+		p.props.SetMust(object, "Rate", float64(state.Speed))
+	}
+
+	if state.Action == interfaces.AudioActionSeek {
+		if err := p.dbus.Emit(basePath, object+".Seeked", pos); err != nil {
+			logrus.Error(err)
+		}
+	}
 }
 
 func notImplemented(c *prop.Change) *dbus.Error {
@@ -129,6 +145,40 @@ func (p *Player) OnLoopStatus(c *prop.Change) *dbus.Error {
 	loop := LoopStatus(c.Value.(string))
 	logrus.Debugf("LoopStatus changed to %v\n", loop)
 
+	switch loop {
+	case LoopStatusNone:
+		p.controller.SetRepeat(interfaces.RepeatModeOff)
+	case LoopStatusTrack:
+		p.controller.SetRepeat(interfaces.RepeatModeOne)
+	case LoopStatusPlaylist:
+		p.controller.SetRepeat(interfaces.RepeatModeAll)
+	default:
+		return dbus.MakeFailedError(fmt.Errorf("invalid loop status: %s", loop))
+	}
+	return nil
+}
+
+func loopStatusFromRepeat(mode interfaces.RepeatMode) LoopStatus {
+	switch mode {
+	case interfaces.RepeatModeOne:
+		return LoopStatusTrack
+	case interfaces.RepeatModeAll:
+		return LoopStatusPlaylist
+	default:
+		return LoopStatusNone
+	}
+}
+
+// OnRate handles Rate change. Rate 0 is not supported, since it would pause playback.
+// https://specifications.freedesktop.org/mpris-spec/latest/Player_Interface.html#Property:Rate
+func (p *Player) OnRate(c *prop.Change) *dbus.Error {
+	rate := interfaces.AudioSpeed(c.Value.(float64))
+	logrus.Debugf("Rate changed to %v\n", rate)
+	if !rate.InRange() {
+		return dbus.MakeFailedError(fmt.Errorf("rate must be in range [%.1f, %.1f]",
+			interfaces.AudioSpeedMin, interfaces.AudioSpeedMax))
+	}
+	p.controller.SetSpeed(rate)
 	return nil
 }
 
@@ -156,8 +206,8 @@ func (p *Player) OnShuffle(c *prop.Change) *dbus.Error {
 func (p *Player) properties() map[string]*prop.Prop {
 	return map[string]*prop.Prop{
 		"PlaybackStatus": newProp(PlaybackStatusPlaying, true, true, nil),
-		"LoopStatus":     newProp(LoopStatusTrack, true, true, p.OnLoopStatus),
-		"Rate":           newProp(1.0, true, true, notImplemented),
+		"LoopStatus":     newProp(LoopStatusNone, true, true, p.OnLoopStatus),
+		"Rate":           newProp(1.0, true, true, p.OnRate),
 		"Shuffle":        newProp(false, true, true, p.OnShuffle),
 		"Metadata":       newProp(mapFromStatus(p.lastState), true, true, nil),
 		"Volume":         newProp(math.Max(0, float64(80)/100.0), true, true, p.OnVolume),
@@ -167,13 +217,13 @@ func (p *Player) properties() map[string]*prop.Prop {
 			Emit:     prop.EmitTrue,
 			Callback: nil,
 		},
-		"MinimumRate":   newProp(1.0, false, true, nil),
-		"MaximumRate":   newProp(1.0, false, true, nil),
+		"MinimumRate":   newProp(float64(interfaces.AudioSpeedMin), false, true, nil),
+		"MaximumRate":   newProp(float64(interfaces.AudioSpeedMax), false, true, nil),
 		"CanGoNext":     newProp(true, false, true, nil),
 		"CanGoPrevious": newProp(true, false, true, nil),
 		"CanPlay":       newProp(true, false, true, nil),
 		"CanPause":      newProp(true, false, true, nil),
-		"CanSeek":       newProp(false, true, true, nil),
+		"CanSeek":       newProp(true, true, true, nil),
 		"CanControl":    newProp(true, false, true, nil),
 	}
 }
@@ -225,11 +275,24 @@ func (p *Player) PlayPause() *dbus.Error {
 // Seek seeks forward in the current track by the specified number of microseconds.
 // https://specifications.freedesktop.org/mpris-spec/latest/Player_Interface.html#Method:Seek
 func (p *Player) Seek(x TimeInUs) *dbus.Error {
+	p.controller.Seek(interfaces.AudioTick(x.Duration().Milliseconds()))
 	return nil
 }
 
 // SetPosition sets the current track position in microseconds.
 // https://specifications.freedesktop.org/mpris-spec/latest/Player_Interface.html#Method:SetPosition
 func (p *Player) SetPosition(o TrackID, x TimeInUs) *dbus.Error {
+	if p.lastState.Song == nil {
+		return nil
+	}
+	// ignore stale requests for other tracks, as spec requires
+	if string(o) != fmt.Sprintf(TrackIDFormat, p.lastState.Song.Id) {
+		logrus.Debugf("Ignore set position for track %s", o)
+		return nil
+	}
+	if x < 0 || x.Duration() > time.Duration(p.lastState.Song.Duration)*time.Second {
+		return nil
+	}
+	p.controller.SetPosition(interfaces.AudioTick(x.Duration().Milliseconds()))
 	return nil
 }
@@ -22,16 +22,14 @@ import (
 	"fmt"
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/effects"
-	"github.com/faiface/beep/flac"
-	"github.com/faiface/beep/mp3"
-	"github.com/faiface/beep/speaker"
-	"github.com/faiface/beep/vorbis"
-	"github.com/faiface/beep/wav"
 	"github.com/sirupsen/logrus"
 	"io"
+	"math"
+	"sync/atomic"
 	"time"
 	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
 )
 
 type audioFormat string
@@ -40,26 +38,148 @@ type audioFormat string
 type Audio struct {
 	status interfaces.AudioStatus
 
-	// todo: we need multiple streamers to allow seamlessly running next song
-	streamer beep.StreamSeekCloser
+	// current is song that is playing
+	current *audioTrack
+	// next is song that is decoded ahead and played right after current one completes
+	next *audioTrack
+	// fading is previous song that is fading out while current one fades in
+	fading       *audioTrack
+	fadePosition int
+	fadeLength   int
+	fadeBuffer   [][2]float64
+
+	// crossfade duration in samples, 0 disables crossfade
+	crossfade int
+	// crossfadeAlbumAware disables crossfade between consecutive songs from same album
+	crossfadeAlbumAware bool
+
+	// replayGain is loudness normalization mode, see config.ReplayGainOff
+	replayGain string
+	// replayGainPreamp is added to normalization gain, in dB
+	replayGainPreamp float64
+	// replayGainClipProtection limits gain to song peak
+	replayGainClipProtection bool
 
 	// ctrl allows pause
 	ctrl *beep.Ctrl
+	// buffering is true when current song has run out of decoded audio
+	buffering bool
+	// fader fades audio out before sleep timer stops playback
+	fader *fader
+	// spectrum copies audio to spectrum analyzer
+	spectrum *spectrumTap
+	// stretch changes playback speed
+	stretch *timeStretch
+	// equalizer filters mixed audio before volume
+	equalizer *equalizer
 	// volume
 	volume *effects.Volume
 	// mixer allows adding multiple streams sequentially
 	mixer *beep.Mixer
+	// output plays audio
+	output output
+	// latency is duration of audio buffered in output, which is not yet heard
+	latency time.Duration
+	// decodeMetrics are shared by all tracks
+	decodeMetrics *decodeMetrics
 
-	songCompleteFunc func()
+	// songCompleteFunc is called every time song completes. NextStarted flags whether next song was
+	// already queued and is now playing.
+	songCompleteFunc func(nextStarted bool)
 
 	statusCallbacks []func(status interfaces.AudioStatus)
+}
+
+// audioTrack is a decoded song
+type audioTrack struct {
+	// stream is decoded on its own goroutine to buffer ahead of playback
+	stream *decodeBuffer
+	format beep.Format
+	// streamer is stream resampled to output sample rate, if needed, and normalized
+	streamer beep.Streamer
+	metadata songMetadata
+}
+
+// newAudioTrack decodes song. Gain is normalization gain in dB, applied before user volume.
+// This waits until decoder has buffered some audio, so that playback does not start with an underrun.
+func newAudioTrack(metadata songMetadata, gain float64, metrics *decodeMetrics) (*audioTrack, error) {
+	source, err := newSongStream(metadata.reader, metadata.format)
+	if err != nil {
+		metadata.reader.Close()
+		return nil, fmt.Errorf("decode audio stream: %v", err)
+	}
+
+	sampleRate := source.format.SampleRate
+	stream := newDecodeBuffer(source, sampleRate.N(config.AudioDecodeBuffer), metrics)
+	stream.waitBuffered(sampleRate.N(config.AudioDecodeBuffer / 2))
+	if err := stream.Err(); err != nil && stream.buffered() == 0 {
+		stream.Close()
+		return nil, fmt.Errorf("decode audio: %v", err)
+	}
+	track := &audioTrack{
+		stream:   stream,
+		format:   source.format,
+		streamer: stream,
+		metadata: metadata,
+	}
+
+	logrus.Debugf("Song %s samplerate: %d Hz", metadata.song.Name, sampleRate.N(time.Second))
+	if sampleRate != config.AudioSamplingRate {
+		logrus.Debugf("Resample song from %d Hz to %d Hz", sampleRate.N(time.Second), config.AudioSamplingRate)
+		track.streamer = beep.Resample(config.AudioResampleQuality, sampleRate, config.AudioSamplingRate, stream)
+	}
+	if gain != 0 {
+		logrus.Debugf("Song %s normalization gain: %.2f dB", metadata.song.Name, gain)
+		track.streamer = &effects.Volume{
+			Streamer: track.streamer,
+			Base:     10,
+			Volume:   gain / 20,
+			Silent:   false,
+		}
+	}
+	return track, nil
+}
 
-	currentSampleRate int
+// position returns how much of song has been streamed, in song's own sample rate.
+func (t *audioTrack) position() time.Duration {
+	return t.format.SampleRate.D(t.stream.Position())
 }
 
-// initialize new player. This also initializes faiface.Speaker, which should be initialized only once.
-func newAudio() *Audio {
+// remaining returns number of samples left in song, in output sample rate.
+// If decoder does not know song length, song duration is used instead.
+func (t *audioTrack) remaining() int {
+	length := t.stream.Len()
+	if length <= 0 {
+		length = t.format.SampleRate.N(time.Duration(t.metadata.song.Duration) * time.Second)
+	}
+	left := t.format.SampleRate.D(length - t.stream.Position())
+	return beep.SampleRate(config.AudioSamplingRate).N(left)
+}
+
+func (t *audioTrack) Close() error {
+	streamErr := t.stream.Err()
+	if streamErr != nil {
+		if streamErr != io.EOF {
+			logrus.Errorf("streamer error: %v", streamErr)
+		} else {
+			logrus.Warning("got streamer error EOF")
+		}
+	}
+	err := t.stream.Close()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return fmt.Errorf("close streamer: %v", err)
+	}
+	logrus.Debug("closed old streamer")
+	return nil
+}
+
+// initialize new player with given output. Output must be initialized with initOutput before playing.
+func newAudio(out output) *Audio {
 	a := &Audio{
+		output: out,
 		ctrl: &beep.Ctrl{
 			Streamer: nil,
 			Paused:   false,
@@ -70,28 +190,192 @@ func newAudio() *Audio {
 			Volume:   (config.AudioMinVolumedB + config.AudioMaxVolumedB) / 2,
 			Silent:   false,
 		},
+		stretch:         newTimeStretch(),
+		equalizer:       newEqualizer(config.AudioSamplingRate, config.EqualizerFrequencies),
+		fader:           &fader{},
+		spectrum:        newSpectrumTap(),
+		decodeMetrics:   &decodeMetrics{},
 		mixer:           &beep.Mixer{},
 		statusCallbacks: make([]func(status interfaces.AudioStatus), 0),
 	}
-	a.ctrl.Streamer = a.mixer
+	a.mixer.Add(beep.StreamerFunc(a.streamTracks))
+	a.stretch.Streamer = a.mixer
+	a.equalizer.Streamer = a.stretch
+	a.ctrl.Streamer = a.equalizer
 	a.ctrl.Paused = false
-	a.volume.Streamer = a.ctrl
+	a.fader.Streamer = a.ctrl
+	a.spectrum.Streamer = a.fader
+	a.volume.Streamer = a.spectrum
 	a.volume.Silent = false
 	a.status.Volume = 50
-
-	a.currentSampleRate = config.AudioSamplingRate
+	a.status.Speed = interfaces.AudioSpeedNormal
 	return a
 }
 
-func initAudio() error {
-	err := speaker.Init(config.AudioSamplingRate, config.AudioSamplingRate/1000*
+// initOutput initializes audio output, which should be initialized only once.
+func (a *Audio) initOutput() error {
+	err := a.output.Init(config.AudioSamplingRate, config.AudioSamplingRate/1000*
 		int(config.AudioBufferPeriod.Milliseconds()))
 	if err != nil {
-		return fmt.Errorf("init speaker: %v", err)
+		return fmt.Errorf("init audio output: %v", err)
 	}
+	a.output.Lock()
+	a.latency = a.output.Latency()
+	a.output.Unlock()
 	return nil
 }
 
+// closeOutput closes audio output.
+func (a *Audio) closeOutput() error {
+	return a.output.Close()
+}
+
+// setCrossfade sets crossfade duration. Zero duration disables crossfade.
+func (a *Audio) setCrossfade(duration time.Duration, albumAware bool) {
+	if duration > 0 {
+		logrus.Infof("Crossfade songs: %.1f s, album aware: %t", duration.Seconds(), albumAware)
+	}
+	a.output.Lock()
+	defer a.output.Unlock()
+	a.crossfade = beep.SampleRate(config.AudioSamplingRate).N(duration)
+	a.crossfadeAlbumAware = albumAware
+}
+
+// setReplayGain sets loudness normalization mode (see config.ReplayGainOff), preamp in dB and whether to
+// prevent clipping.
+func (a *Audio) setReplayGain(mode string, preamp float64, clipProtection bool) {
+	if mode != config.ReplayGainOff {
+		logrus.Infof("ReplayGain: %s, preamp %.1f dB, clip protection: %t", mode, preamp, clipProtection)
+	}
+	a.output.Lock()
+	defer a.output.Unlock()
+	a.replayGain = mode
+	a.replayGainPreamp = preamp
+	a.replayGainClipProtection = clipProtection
+}
+
+// songGain returns normalization gain in dB for song. If song has no gain, return 0.
+// Album mode falls back to track gain, if album gain is missing. With clip protection song is not amplified
+// over its peak, and if peak is unknown, song is not amplified at all.
+func (a *Audio) songGain(song *models.Song) float64 {
+	a.output.Lock()
+	mode := a.replayGain
+	preamp := a.replayGainPreamp
+	clipProtection := a.replayGainClipProtection
+	a.output.Unlock()
+
+	var gain, peak float64
+	switch mode {
+	case config.ReplayGainTrack:
+		gain, peak = song.TrackGain, song.TrackPeak
+	case config.ReplayGainAlbum:
+		gain, peak = song.AlbumGain, song.AlbumPeak
+		if gain == 0 {
+			gain, peak = song.TrackGain, song.TrackPeak
+		}
+	default:
+		return 0
+	}
+	if gain == 0 {
+		return 0
+	}
+
+	gain += preamp
+	if clipProtection {
+		maxGain := 0.0
+		if peak > 0 {
+			maxGain = -20 * math.Log10(peak)
+		}
+		if gain > maxGain {
+			gain = maxGain
+		}
+	}
+	return gain
+}
+
+// SetEqualizer enables or disables equalizer and sets gain in dB for each band in config.EqualizerFrequencies.
+func (a *Audio) SetEqualizer(enabled bool, gains []float64) {
+	logrus.Debugf("Set equalizer enabled: %t, gains: %v", enabled, gains)
+	a.output.Lock()
+	defer a.output.Unlock()
+	a.equalizer.enabled = enabled
+	a.equalizer.setGains(gains)
+}
+
+// SetSpeed sets playback speed. Pitch is preserved.
+func (a *Audio) SetSpeed(speed interfaces.AudioSpeed) {
+	speed = speed.Add(0)
+	logrus.Infof("Set playback speed: %.2f", speed)
+	a.output.Lock()
+	defer a.output.Unlock()
+	a.stretch.setRate(float64(speed))
+	a.status.Speed = speed
+	a.status.Action = interfaces.AudioActionSpeedChanged
+	go a.flushStatus()
+}
+
+// setSleepTimer sets sleep timer to status. Player manages the timer itself.
+func (a *Audio) setSleepTimer(timer interfaces.SleepTimer) {
+	a.output.Lock()
+	defer a.output.Unlock()
+	a.status.Sleep = timer
+	a.status.Action = interfaces.AudioActionSleepTimerChanged
+	go a.flushStatus()
+}
+
+// setQuality sets name of quality profile to status. Player applies the profile itself.
+func (a *Audio) setQuality(profile string) {
+	a.output.Lock()
+	defer a.output.Unlock()
+	a.status.Quality = profile
+	a.status.Action = interfaces.AudioActionQualityChanged
+	go a.flushStatus()
+}
+
+// fadeOut fades audio out over given duration and keeps it silent until resetFade is called.
+func (a *Audio) fadeOut(duration time.Duration) {
+	logrus.Infof("Fade out audio in %.1f s", duration.Seconds())
+	a.output.Lock()
+	defer a.output.Unlock()
+	a.fader.fadeOut(beep.SampleRate(config.AudioSamplingRate).N(duration))
+}
+
+// resetFade restores audio after fadeOut.
+func (a *Audio) resetFade() {
+	a.output.Lock()
+	defer a.output.Unlock()
+	a.fader.reset()
+}
+
+// playbackStats returns decode buffer health and underruns.
+func (a *Audio) playbackStats() models.PlaybackStats {
+	stats := models.PlaybackStats{
+		BufferSize:   config.AudioDecodeBuffer,
+		Underruns:    int(atomic.LoadInt64(&a.decodeMetrics.underruns)),
+		UnderrunTime: time.Duration(atomic.LoadInt64(&a.decodeMetrics.underrunTime)),
+	}
+	a.output.Lock()
+	defer a.output.Unlock()
+	if a.current != nil {
+		stats.Buffered = a.current.stream.buffered()
+	}
+	if a.next != nil {
+		stats.NextBuffered = a.next.stream.buffered()
+	}
+	return stats
+}
+
+// EnableSpectrum enables or disables collecting audio for spectrum.
+func (a *Audio) EnableSpectrum(enabled bool) {
+	a.spectrum.setEnabled(enabled)
+}
+
+// Spectrum fills levels with current audio level in each frequency band, in range [0, 1].
+// This does not block audio output.
+func (a *Audio) Spectrum(levels []float64) {
+	a.spectrum.spectrum(levels, config.AudioSamplingRate)
+}
+
 func (a *Audio) SetShuffle(shuffle bool) {
 	if shuffle {
 		logrus.Info("Enable shuffle")
@@ -99,22 +383,40 @@ func (a *Audio) SetShuffle(shuffle bool) {
 		logrus.Info("Disable shuffle")
 	}
 
-	speaker.Lock()
-	defer speaker.Unlock()
+	a.output.Lock()
+	defer a.output.Unlock()
 	a.status.Shuffle = shuffle
 	a.status.Action = interfaces.AudioActionShuffleChanged
 	go a.flushStatus()
 }
 
+func (a *Audio) SetShuffleMode(mode interfaces.ShuffleMode) {
+	logrus.Infof("Set shuffle mode: %s", mode)
+	a.output.Lock()
+	defer a.output.Unlock()
+	a.status.ShuffleMode = mode
+	a.status.Action = interfaces.AudioActionShuffleChanged
+	go a.flushStatus()
+}
+
+func (a *Audio) SetRepeat(mode interfaces.RepeatMode) {
+	logrus.Infof("Set repeat mode: %s", mode)
+	a.output.Lock()
+	defer a.output.Unlock()
+	a.status.Repeat = mode
+	a.status.Action = interfaces.AudioActionRepeatChanged
+	go a.flushStatus()
+}
+
 func (a *Audio) getStatus() interfaces.AudioStatus {
-	speaker.Lock()
-	defer speaker.Unlock()
+	a.output.Lock()
+	defer a.output.Unlock()
 	return a.status
 }
 
 // PlayPause toggles pause.
 func (a *Audio) PlayPause() {
-	speaker.Lock()
+	a.output.Lock()
 	if a.ctrl == nil {
 		return
 	}
@@ -125,80 +427,123 @@ func (a *Audio) PlayPause() {
 		logrus.Info("Continue")
 	}
 	a.ctrl.Paused = state
-	a.status.Paused = state
+	a.updateSongState()
 	a.status.Action = interfaces.AudioActionPlayPause
-	speaker.Unlock()
+	a.output.Unlock()
 	go a.flushStatus()
 }
 
 // Pause pauses audio. If audio is already paused, do nothing.
 func (a *Audio) Pause() {
 	logrus.Info("Pause audio")
-	speaker.Lock()
+	a.output.Lock()
 	if a.ctrl == nil {
 		return
 	}
 	a.ctrl.Paused = true
-	a.status.Paused = true
+	a.updateSongState()
 	a.status.Action = interfaces.AudioActionPlayPause
-	speaker.Unlock()
+	a.output.Unlock()
 	go a.flushStatus()
 }
 
 // Continue continues paused audio. If audio is already playing, do nothing.
 func (a *Audio) Continue() {
 	logrus.Info("Continue audio")
-	speaker.Lock()
+	a.output.Lock()
 	if a.ctrl == nil {
 		return
 	}
 	a.ctrl.Paused = false
-	a.status.Paused = false
+	a.updateSongState()
 	a.status.Action = interfaces.AudioActionPlayPause
-	speaker.Unlock()
+	a.output.Unlock()
 	go a.flushStatus()
 }
 
 // StopMedia stops music. If there is no audio to play, do nothing.
 func (a *Audio) StopMedia() {
 	logrus.Infof("Stop audio")
-	speaker.Lock()
-	a.status.State = interfaces.AudioStateStopped
+	a.output.Lock()
+	a.changeState(interfaces.AudioStateIdle, nil)
 	a.status.Action = interfaces.AudioActionStop
 	a.ctrl.Paused = false
-	a.status.Paused = false
-	speaker.Unlock()
-	speaker.Clear()
+	a.buffering = false
+	a.output.Unlock()
+	a.output.Clear()
 
-	speaker.Lock()
+	a.output.Lock()
 	err := a.closeOldStream()
-	speaker.Unlock()
+	others := []*audioTrack{a.next, a.fading}
+	a.next = nil
+	a.fading = nil
+	a.output.Unlock()
 	if err != nil {
 		logrus.Errorf("stop: %v", err)
 	}
+	for _, v := range others {
+		if v != nil {
+			err = v.Close()
+			if err != nil {
+				logrus.Errorf("stop: %v", err)
+			}
+		}
+	}
 	go a.flushStatus()
 }
 
 // Next plays next track. If there's no next song to play, do nothing.
 func (a *Audio) Next() {
 	logrus.Info("Next song")
-	speaker.Lock()
+	a.output.Lock()
 	a.status.Action = interfaces.AudioActionNext
-	speaker.Unlock()
+	a.output.Unlock()
 	go a.flushStatus()
 }
 
 // Previous plays previous track. If previous track does not exist, do nothing.
 func (a *Audio) Previous() {
 	logrus.Info("Previous song")
-	speaker.Lock()
+	a.output.Lock()
 	a.status.Action = interfaces.AudioActionPrevious
-	speaker.Unlock()
+	a.output.Unlock()
 	go a.flushStatus()
 }
 
-// Seek seeks given ticks. If there is no audio, do nothing.
+// Seek seeks given ticks. Negative ticks seeks backwards. If there is no audio, do nothing.
 func (a *Audio) Seek(ticks interfaces.AudioTick) {
+	a.output.Lock()
+	if a.current == nil {
+		a.output.Unlock()
+		return
+	}
+	position := a.pastTicks()
+	a.output.Unlock()
+	a.SetPosition(position + ticks)
+}
+
+// SetPosition seeks to given position in current song. If there is no audio, do nothing.
+func (a *Audio) SetPosition(position interfaces.AudioTick) {
+	if position < 0 {
+		position = 0
+	}
+	logrus.Infof("Seek to %d ms", position.MilliSeconds())
+	a.output.Lock()
+	if a.current == nil {
+		a.output.Unlock()
+		return
+	}
+	sample := a.current.format.SampleRate.N(time.Duration(position) * time.Millisecond)
+	if length := a.current.stream.Len(); length > 0 && sample > length {
+		sample = length
+	}
+	err := a.current.stream.Seek(sample)
+	if err != nil {
+		logrus.Errorf("seek: %v", err)
+	}
+	a.status.Action = interfaces.AudioActionSeek
+	a.output.Unlock()
+	go a.flushStatus()
 }
 
 // AddStatusCallback adds a callback that gets called every time audio status is changed, or after certain time.
@@ -210,7 +555,7 @@ func (a *Audio) AddStatusCallback(cb func(status interfaces.AudioStatus)) {
 func (a *Audio) SetVolume(volume interfaces.AudioVolume) {
 	decibels := float64(volumeTodB(int(volume)))
 	logrus.Debugf("Set volume to %d %s -> %.2f Db", volume, "%", decibels)
-	speaker.Lock()
+	a.output.Lock()
 
 	// settings volume to 0 does not mute audio, set silent to true
 	if decibels <= config.AudioMinVolumedB {
@@ -227,7 +572,7 @@ func (a *Audio) SetVolume(volume interfaces.AudioVolume) {
 		a.status.Volume = volume
 	}
 	a.status.Action = interfaces.AudioActionSetVolume
-	speaker.Unlock()
+	a.output.Unlock()
 	go a.flushStatus()
 }
 
@@ -239,150 +584,289 @@ func (a *Audio) SetMute(muted bool) {
 	} else {
 		logrus.Info("Unmute audio")
 	}
-	speaker.Lock()
+	a.output.Lock()
 	if a.ctrl == nil {
 		return
 	}
 	a.ctrl.Paused = false
 	a.volume.Silent = muted
 	a.status.Muted = muted
-	speaker.Unlock()
+	a.output.Unlock()
 	go a.flushStatus()
 }
 
 func (a *Audio) ToggleMute() {
 	logrus.Info("Toggle mute")
-	speaker.Lock()
+	a.output.Lock()
 	muted := a.status.Muted
-	speaker.Unlock()
+	a.output.Unlock()
 	a.SetMute(!muted)
 }
 
-func (a *Audio) streamCompleted() {
+// streamTracks streams current song and continues to next song without gaps, when current one completes.
+// If crossfade is enabled, next song is started before current one completes, and they are mixed together.
+// When there is nothing to play, stream silence. This is called from output, which holds output lock.
+func (a *Audio) streamTracks(samples [][2]float64) (n int, ok bool) {
+	if a.shouldCrossfade() {
+		a.startCrossfade()
+	}
+	for n < len(samples) && a.current != nil {
+		trackN, trackOk := a.current.streamer.Stream(samples[n:])
+		n += trackN
+		if !trackOk {
+			a.trackCompleted()
+		} else if trackN == 0 {
+			break
+		}
+	}
+	if a.current != nil {
+		a.setBuffering(a.current.stream.waiting())
+	}
+	for i := n; i < len(samples); i++ {
+		samples[i] = [2]float64{}
+	}
+	if a.fading != nil {
+		a.streamFading(samples)
+	}
+	return len(samples), true
+}
+
+func (a *Audio) shouldCrossfade() bool {
+	if a.crossfade <= 0 || a.current == nil || a.next == nil || a.fading != nil {
+		return false
+	}
+	if a.crossfadeAlbumAware && a.current.metadata.song.Album == a.next.metadata.song.Album {
+		return false
+	}
+	return a.current.remaining() <= a.crossfade
+}
+
+// startCrossfade starts next song and sets current one to fade out.
+func (a *Audio) startCrossfade() {
+	logrus.Debug("Crossfade to next song")
+	a.fading = a.current
+	a.fadePosition = 0
+	a.fadeLength = a.fading.remaining()
+	a.current = a.next
+	a.next = nil
+	a.setTrackStatus(a.current.metadata)
+	go a.flushStatus()
+	if a.songCompleteFunc != nil {
+		a.songCompleteFunc(true)
+	}
+}
+
+// streamFading mixes song fading out to samples and fades samples in.
+func (a *Audio) streamFading(samples [][2]float64) {
+	if len(a.fadeBuffer) < len(samples) {
+		a.fadeBuffer = make([][2]float64, len(samples))
+	}
+	buf := a.fadeBuffer[:len(samples)]
+	fadeN, fadeOk := a.fading.streamer.Stream(buf)
+	for i := range samples {
+		progress := 1.0
+		if a.fadePosition < a.fadeLength {
+			progress = float64(a.fadePosition) / float64(a.fadeLength)
+		}
+		// equal power crossfade
+		gainIn := math.Sin(progress * math.Pi / 2)
+		gainOut := math.Cos(progress * math.Pi / 2)
+		samples[i][0] *= gainIn
+		samples[i][1] *= gainIn
+		if i < fadeN {
+			samples[i][0] += buf[i][0] * gainOut
+			samples[i][1] += buf[i][1] * gainOut
+		}
+		a.fadePosition++
+	}
+
+	if !fadeOk || a.fadePosition >= a.fadeLength {
+		logrus.Debug("Crossfade complete")
+		err := a.fading.Close()
+		if err != nil {
+			logrus.Errorf("close faded song: %v", err)
+		}
+		a.fading = nil
+	}
+}
+
+// trackCompleted closes current song and starts next one, if there is one queued. Otherwise audio waits for
+// next song to be loaded.
+func (a *Audio) trackCompleted() {
 	logrus.Debug("audio stream complete")
 	err := a.closeOldStream()
 	if err != nil {
 		logrus.Errorf("complete stream: %v", err)
 	}
+
+	nextStarted := false
+	if a.next != nil {
+		logrus.Debug("Continue to next song")
+		a.current = a.next
+		a.next = nil
+		a.setTrackStatus(a.current.metadata)
+		nextStarted = true
+		go a.flushStatus()
+	} else if a.changeState(interfaces.AudioStateLoading, nil) {
+		go a.flushStatus()
+	}
 	if a.songCompleteFunc != nil {
-		a.songCompleteFunc()
+		a.songCompleteFunc(nextStarted)
 	}
 }
 
 func (a *Audio) closeOldStream() error {
-	// don't use locking here, since speaker calls streamCompleted, which calls this to close reader
-	var err error
-	var streamErr error
-	if a.streamer != nil {
-		streamErr = a.streamer.Err()
-		if streamErr != nil {
-			if streamErr != io.EOF {
-				logrus.Errorf("streamer error: %v", streamErr)
-			} else {
-				logrus.Warning("got streamer error EOF")
-				err = nil
-			}
-		}
-		err = a.streamer.Close()
+	// don't use locking here, since output calls trackCompleted, which calls this to close reader
+	if a.current == nil {
+		return fmt.Errorf("audio stream completed but streamer is nil")
+	}
+	err := a.current.Close()
+	a.current = nil
+	return err
+}
+
+// dropNextSong closes next song, if one is queued.
+func (a *Audio) dropNextSong() {
+	a.output.Lock()
+	next := a.next
+	a.next = nil
+	a.output.Unlock()
+	if next != nil {
+		logrus.Debug("Drop next song")
+		err := next.Close()
 		if err != nil {
-			if err == io.EOF {
-				// pass
-			} else {
-				err = fmt.Errorf("close streamer: %v", err)
-			}
-		} else {
-			logrus.Debug("closed old streamer")
+			logrus.Errorf("close next song: %v", err)
 		}
-		a.streamer = nil
-	} else {
-		err = fmt.Errorf("audio stream completed but streamer is nil")
 	}
-	return err
+}
+
+// playNextTrack plays track that is queued to play next immediately, if it is given song. Returns false if
+// there is no next track or it is some other song.
+func (a *Audio) playNextTrack(song *models.Song) (bool, error) {
+	a.output.Lock()
+	next := a.next
+	if next == nil || next.metadata.song != song {
+		a.output.Unlock()
+		return false, nil
+	}
+	a.next = nil
+	a.ctrl.Paused = false
+	a.output.Unlock()
+	logrus.Debugf("Play queued song %s", song.Name)
+	return true, a.playTrack(next)
+}
+
+// hasNextSong returns true if next song is already queued
+func (a *Audio) hasNextSong() bool {
+	a.output.Lock()
+	defer a.output.Unlock()
+	return a.next != nil
+}
+
+// hasCurrentSong returns true if there is a track playing or paused.
+func (a *Audio) hasCurrentSong() bool {
+	a.output.Lock()
+	defer a.output.Unlock()
+	return a.current != nil
+}
+
+// nextSong returns song that is queued to play next, or nil.
+func (a *Audio) nextSong() *models.Song {
+	a.output.Lock()
+	defer a.output.Unlock()
+	if a.next == nil {
+		return nil
+	}
+	return a.next.metadata.song
 }
 
 // gather latest status and flush it to callbacks
 func (a *Audio) updateStatus() {
-	past := a.getPastTicks()
-	speaker.Lock()
-	a.status.SongPast = past
+	a.output.Lock()
 	a.status.Action = interfaces.AudioActionTimeUpdate
-	speaker.Unlock()
+	a.output.Unlock()
 	a.flushStatus()
 }
 
+// flushStatus sends status to callbacks, with current position in song.
 func (a *Audio) flushStatus() {
-	speaker.Lock()
+	a.output.Lock()
+	a.status.SongPast = a.pastTicks()
 	status := a.status
-	speaker.Unlock()
+	a.output.Unlock()
 	for _, v := range a.statusCallbacks {
 		v(status)
 	}
 }
 
-// play song from io reader. Only song/album/artist/imageurl are used from status.
-func (a *Audio) playSongFromReader(metadata songMetadata) error {
-	// decode
-	var songFormat beep.Format
-	var streamer beep.StreamSeekCloser
-	var err error
-	switch metadata.format {
-	case interfaces.AudioFormatMp3:
-		streamer, songFormat, err = mp3.Decode(metadata.reader)
-	case interfaces.AudioFormatFlac:
-		streamer, songFormat, err = flac.Decode(metadata.reader)
-	case interfaces.AudioFormatWav:
-		streamer, songFormat, err = wav.Decode(metadata.reader)
-	case interfaces.AudioFormatOgg:
-		streamer, songFormat, err = vorbis.Decode(metadata.reader)
-	default:
-		return fmt.Errorf("unknown audio format: %s", metadata.format)
-	}
-	if err != nil {
-		return fmt.Errorf("decode audio stream: %v", err)
-	}
+// newTrack decodes song from reader with normalization gain.
+func (a *Audio) newTrack(metadata songMetadata) (*audioTrack, error) {
+	return newAudioTrack(metadata, a.songGain(metadata.song), a.decodeMetrics)
+}
 
-	logrus.Debugf("Song %s samplerate: %d Hz", metadata.song.Name, songFormat.SampleRate.N(time.Second))
-	sampleRate := songFormat.SampleRate.N(time.Second)
-	if a.currentSampleRate != sampleRate {
-		logrus.Debugf("Set samplerate to %d kHz", sampleRate/1000)
-		err = speaker.Init(songFormat.SampleRate, sampleRate/1000*
-			int(config.AudioBufferPeriod.Seconds()*1000))
-		if err != nil {
-			logrus.Errorf("Update sample rate (%d -> %d): %v", a.currentSampleRate, sampleRate, err)
-		} else {
-			a.currentSampleRate = sampleRate
-		}
+// queueTrack queues track to play right after current song, without a gap.
+// If there is no song playing, track is not queued and false is returned.
+func (a *Audio) queueTrack(track *audioTrack) (bool, error) {
+	var err error
+	a.output.Lock()
+	if a.current == nil {
+		a.output.Unlock()
+		return false, nil
 	}
-	logrus.Debug("Setting new streamer from ", metadata.format.String())
-	if streamer == nil {
-		return fmt.Errorf("empty streamer")
-	}
-	stream := beep.Seq(streamer, beep.Callback(a.streamCompleted))
-	speaker.Clear()
-	speaker.Lock()
-	old := a.streamer
-	a.mixer.Clear()
-	a.streamer = streamer
-	a.mixer.Add(stream)
-	speaker.Unlock()
+	logrus.Debugf("Queue song %s to play next", track.metadata.song.Name)
+	old := a.next
+	a.next = track
+	a.output.Unlock()
 	if old != nil {
-		err := old.Close()
+		err = old.Close()
 		if err != nil {
 			err = fmt.Errorf("failed to close old stream: %v", err)
 		}
 	}
-	speaker.Play(a.volume)
-	speaker.Lock()
+	return true, err
+}
 
+// playTrack plays track immediately.
+func (a *Audio) playTrack(track *audioTrack) error {
+	var err error
+	logrus.Debug("Setting new streamer from ", track.metadata.format.String())
+	a.output.Clear()
+	a.output.Lock()
+	old := []*audioTrack{a.current, a.next, a.fading}
+	a.current = track
+	a.next = nil
+	a.fading = nil
+	a.setTrackStatus(track.metadata)
+	a.output.Unlock()
+	for _, v := range old {
+		if v != nil {
+			closeErr := v.Close()
+			if closeErr != nil {
+				err = fmt.Errorf("failed to close old stream: %v", closeErr)
+			}
+		}
+	}
+	a.output.Play(a.volume)
+	a.flushStatus()
+	return err
+}
+
+// set status for new song. Caller must hold output lock.
+func (a *Audio) setTrackStatus(metadata songMetadata) {
 	a.status.Song = metadata.song
 	a.status.Album = metadata.album
 	a.status.Artist = metadata.artist
 	a.status.AlbumImageUrl = metadata.albumImageUrl
-	a.status.State = interfaces.AudioStatePlaying
+	a.status.Format = metadata.format
+	a.status.Bitrate = metadata.bitrate
+	a.status.SongPast = 0
+	a.buffering = false
+	if !a.status.State.HasSong() {
+		// new song is always decoded before it plays
+		a.changeState(interfaces.AudioStateBuffering, nil)
+	}
+	a.changeState(a.songState(), nil)
 	a.status.Action = interfaces.AudioActionPlay
-	speaker.Unlock()
-	a.flushStatus()
-	return err
 }
 
 // linear scaling with a & b coefficients
@@ -395,13 +879,29 @@ func volumeTodB(volume int) float32 {
 	return volumeTodBA*float32(volume) + volumeTodBB
 }
 
-// how many ticks current track has played
-func (a *Audio) getPastTicks() interfaces.AudioTick {
-	speaker.Lock()
-	defer speaker.Unlock()
-	if a.streamer == nil {
+// pastTicks returns how long current track has been heard, in track time. Audio that is buffered in
+// output or time stretch is not counted. Caller must hold output lock.
+func (a *Audio) pastTicks() interfaces.AudioTick {
+	if a.current == nil {
+		return 0
+	}
+	buffered := beep.SampleRate(config.AudioSamplingRate).D(a.stretch.lookahead()) +
+		time.Duration(float64(a.latency)*a.stretch.rate)
+	past := a.current.position() - buffered
+	if past < 0 {
+		past = 0
+	}
+	return interfaces.AudioTick(past.Milliseconds())
+}
+
+// timeLeft returns how long current track has left to play in wall time, taking playback speed and
+// audio buffered in output into account. If there is no track, return 0.
+func (a *Audio) timeLeft() time.Duration {
+	a.output.Lock()
+	defer a.output.Unlock()
+	if a.current == nil {
 		return 0
 	}
-	left := a.streamer.Position() / config.AudioSamplingRate
-	return interfaces.AudioTick((time.Second * time.Duration(left)).Milliseconds())
+	left := beep.SampleRate(config.AudioSamplingRate).D(a.current.remaining() + a.stretch.lookahead())
+	return time.Duration(float64(left)/a.stretch.rate) + a.latency
 }
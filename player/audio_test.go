@@ -19,21 +19,28 @@
 package player
 
 import (
+	"bytes"
+	"github.com/faiface/beep"
 	"github.com/sirupsen/logrus"
+	"math"
 	"testing"
+	"time"
+	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
 )
 
 func TestAudio_PlayPause(t *testing.T) {
 	logrus.SetLevel(logrus.WarnLevel)
-	audio := newAudio()
+	audio := newAudio(newStreamOutput(nil, false))
+	audio.status.State = interfaces.AudioStatePlaying
 
 	wantPaused := func() {
 		if !audio.ctrl.Paused {
 			t.Errorf("expect audio.ctrl paused")
 		}
-		if !audio.status.Paused {
-			t.Errorf("expect audio.status paused")
+		if audio.status.State != interfaces.AudioStatePaused {
+			t.Errorf("expect audio.status paused, got: %s", audio.status.State)
 		}
 	}
 
@@ -41,8 +48,8 @@ func TestAudio_PlayPause(t *testing.T) {
 		if audio.ctrl.Paused {
 			t.Errorf("expect audio.ctrl non-paused")
 		}
-		if audio.status.Paused {
-			t.Errorf("expect audio.status non-paused")
+		if audio.status.State != interfaces.AudioStatePlaying {
+			t.Errorf("expect audio.status playing, got: %s", audio.status.State)
 		}
 	}
 
@@ -103,7 +110,7 @@ func TestAudio_SetVolume(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			a := newAudio()
+			a := newAudio(newStreamOutput(nil, false))
 			a.SetVolume(tt.volume)
 
 			if a.status.Volume != tt.volume {
@@ -123,7 +130,7 @@ func TestAudio_SetVolume(t *testing.T) {
 
 func TestAudio_SetMute(t *testing.T) {
 	logrus.SetLevel(logrus.WarnLevel)
-	audio := newAudio()
+	audio := newAudio(newStreamOutput(nil, false))
 
 	if audio.status.Muted {
 		t.Errorf("audio is muted on init")
@@ -146,3 +153,129 @@ func TestAudio_SetMute(t *testing.T) {
 		t.Errorf("want audio.volume not muted")
 	}
 }
+
+func TestAudio_shouldCrossfade(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	newTrack := func(album models.Id) *audioTrack {
+		metadata := songMetadata{
+			song:   &models.Song{Album: album, Duration: 1},
+			reader: &testReader{bytes.NewReader(testWav(20000))},
+			format: interfaces.AudioFormatWav,
+		}
+		track, err := newAudioTrack(metadata, 0, &decodeMetrics{})
+		if err != nil {
+			t.Fatalf("init track: %v", err)
+		}
+		return track
+	}
+
+	tests := []struct {
+		name       string
+		crossfade  time.Duration
+		albumAware bool
+		nextAlbum  models.Id
+		want       bool
+	}{
+		{name: "disabled", crossfade: 0, nextAlbum: "album-2", want: false},
+		{name: "different album", crossfade: time.Second, nextAlbum: "album-2", want: true},
+		{name: "same album", crossfade: time.Second, nextAlbum: "album-1", want: true},
+		{name: "album aware, same album", crossfade: time.Second, albumAware: true, nextAlbum: "album-1", want: false},
+		{name: "album aware, different album", crossfade: time.Second, albumAware: true, nextAlbum: "album-2",
+			want: true},
+		{name: "not yet", crossfade: time.Millisecond * 100, nextAlbum: "album-2", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAudio(newStreamOutput(nil, false))
+			a.setCrossfade(tt.crossfade, tt.albumAware)
+			a.current = newTrack("album-1")
+			a.next = newTrack(tt.nextAlbum)
+
+			if got := a.shouldCrossfade(); got != tt.want {
+				t.Errorf("shouldCrossfade, got: %t, want: %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAudio_songGain(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	song := &models.Song{TrackGain: -6, AlbumGain: -8, TrackPeak: 0.5, AlbumPeak: 0.9}
+	trackOnly := &models.Song{TrackGain: 3, TrackPeak: 0.5}
+	noPeak := &models.Song{TrackGain: 3}
+
+	tests := []struct {
+		name           string
+		song           *models.Song
+		mode           string
+		preamp         float64
+		clipProtection bool
+		want           float64
+	}{
+		{name: "off", song: song, mode: config.ReplayGainOff, want: 0},
+		{name: "track", song: song, mode: config.ReplayGainTrack, want: -6},
+		{name: "album", song: song, mode: config.ReplayGainAlbum, want: -8},
+		{name: "album fallback to track", song: trackOnly, mode: config.ReplayGainAlbum, want: 3},
+		{name: "preamp", song: song, mode: config.ReplayGainTrack, preamp: 2, want: -4},
+		{name: "no gain", song: &models.Song{}, mode: config.ReplayGainTrack, preamp: 2, want: 0},
+		{name: "clip protection", song: song, mode: config.ReplayGainTrack, preamp: 14, clipProtection: true,
+			want: -20 * math.Log10(0.5)},
+		{name: "clip protection, no peak", song: noPeak, mode: config.ReplayGainTrack, clipProtection: true,
+			want: 0},
+		{name: "clip protection, no clipping", song: song, mode: config.ReplayGainTrack, clipProtection: true,
+			want: -6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAudio(newStreamOutput(nil, false))
+			a.setReplayGain(tt.mode, tt.preamp, tt.clipProtection)
+			if got := a.songGain(tt.song); got != tt.want {
+				t.Errorf("songGain, got: %f, want: %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAudio_pastTicks(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	tests := []struct {
+		name       string
+		sampleRate int
+		latency    time.Duration
+		streamed   time.Duration
+		want       interfaces.AudioTick
+	}{
+		{name: "44.1 kHz", sampleRate: 44100, streamed: time.Millisecond * 500, want: 500},
+		{name: "48 kHz", sampleRate: 48000, streamed: time.Millisecond * 500, want: 500},
+		{name: "96 kHz", sampleRate: 96000, streamed: time.Millisecond * 750, want: 750},
+		{name: "latency", sampleRate: 48000, latency: time.Millisecond * 100, streamed: time.Millisecond * 500,
+			want: 400},
+		{name: "latency at start", sampleRate: 48000, latency: time.Millisecond * 100,
+			streamed: time.Millisecond * 50, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAudio(newStreamOutput(nil, false))
+			a.latency = tt.latency
+			metadata := songMetadata{
+				song:   &models.Song{Duration: 1},
+				reader: &testReader{bytes.NewReader(testWavRate(tt.sampleRate, tt.sampleRate))},
+				format: interfaces.AudioFormatWav,
+			}
+			track, err := newAudioTrack(metadata, 0, &decodeMetrics{})
+			if err != nil {
+				t.Fatalf("init track: %v", err)
+			}
+			a.current = track
+
+			samples := make([][2]float64, beep.SampleRate(config.AudioSamplingRate).N(tt.streamed))
+			a.streamTracks(samples)
+
+			// resampler reads few samples ahead
+			got := a.pastTicks()
+			if diff := got - tt.want; diff < 0 || diff > 2 {
+				t.Errorf("pastTicks, got: %d ms, want: %d ms", got, tt.want)
+			}
+		})
+	}
+}
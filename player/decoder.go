@@ -0,0 +1,265 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/faiface/beep"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// decodeChunkSize is the number of samples decoder decodes at once.
+const decodeChunkSize = 2048
+
+// decodeMetrics are decode buffer metrics, shared by all tracks. Access with atomic operations.
+type decodeMetrics struct {
+	// underruns is how many times playback has run out of decoded audio
+	underruns int64
+	// underrunTime is total duration of silence played due to underruns, in nanoseconds
+	underrunTime int64
+}
+
+// decodeBuffer decodes stream on its own goroutine to a fixed-size ring buffer ahead of playback, so that slow
+// network reads or heavy frames do not block audio output. If buffer runs empty before stream is complete,
+// silence is played and an underrun is recorded. Seeking is done by decoder goroutine, and buffer is refilled
+// from new position.
+// Audio is streamed from buffer, but its Stream only holds the lock while copying samples.
+type decodeBuffer struct {
+	source     *songStream
+	sampleRate beep.SampleRate
+	metrics    *decodeMetrics
+
+	lock *sync.Mutex
+	// cond is signaled when samples are written to or read from ring, or decoder state changes
+	cond *sync.Cond
+	ring [][2]float64
+	// start is index of first unread sample in ring, and count number of unread samples
+	start int
+	count int
+	// position is the number of samples streamed from buffer
+	position int
+	length   int
+	// seek is position to seek to, or -1 if there is no pending seek
+	seek int
+	// generation is increased on every seek, so decoded samples from previous position are discarded
+	generation int
+	// refilling is true after seek until samples from new position are available
+	refilling bool
+	// starving is true while playback is running out of audio
+	starving bool
+	done     bool
+	closed   bool
+	err      error
+	stopped  chan bool
+}
+
+// newDecodeBuffer starts decoding source to buffer of given size in samples.
+func newDecodeBuffer(source *songStream, size int, metrics *decodeMetrics) *decodeBuffer {
+	if size < decodeChunkSize*2 {
+		size = decodeChunkSize * 2
+	}
+	d := &decodeBuffer{
+		source:     source,
+		sampleRate: source.format.SampleRate,
+		metrics:    metrics,
+		lock:       &sync.Mutex{},
+		ring:       make([][2]float64, size),
+		length:     source.Len(),
+		seek:       -1,
+		stopped:    make(chan bool),
+	}
+	d.cond = sync.NewCond(d.lock)
+	go d.decode()
+	return d
+}
+
+func (d *decodeBuffer) decode() {
+	defer close(d.stopped)
+	chunk := make([][2]float64, decodeChunkSize)
+	for {
+		d.lock.Lock()
+		for !d.closed && d.seek < 0 && (d.done || len(d.ring)-d.count < len(chunk)) {
+			d.cond.Wait()
+		}
+		if d.closed {
+			d.lock.Unlock()
+			return
+		}
+		seek := d.seek
+		d.seek = -1
+		generation := d.generation
+		d.lock.Unlock()
+
+		if seek >= 0 {
+			err := d.source.Seek(seek)
+			if err != nil {
+				logrus.Errorf("seek: %v", err)
+			}
+			length := d.source.Len()
+			position := d.source.Position()
+			d.lock.Lock()
+			d.length = length
+			if generation == d.generation {
+				d.position = position
+			}
+			d.lock.Unlock()
+			continue
+		}
+
+		n, ok := d.source.Stream(chunk)
+		err := d.source.Err()
+		d.lock.Lock()
+		if generation == d.generation {
+			d.write(chunk[:n])
+			d.refilling = false
+			if !ok || n == 0 {
+				d.done = true
+				d.err = err
+			}
+		}
+		d.lock.Unlock()
+		d.cond.Broadcast()
+	}
+}
+
+// write copies samples to ring. Caller must hold lock and ensure there is space for samples.
+func (d *decodeBuffer) write(samples [][2]float64) {
+	end := (d.start + d.count) % len(d.ring)
+	copied := copy(d.ring[end:], samples)
+	copy(d.ring, samples[copied:])
+	d.count += len(samples)
+}
+
+// read copies samples from ring and returns number of samples copied. Caller must hold lock.
+func (d *decodeBuffer) read(samples [][2]float64) int {
+	n := len(samples)
+	if n > d.count {
+		n = d.count
+	}
+	copied := copy(samples[:n], d.ring[d.start:])
+	copy(samples[copied:n], d.ring)
+	d.start = (d.start + n) % len(d.ring)
+	d.count -= n
+	return n
+}
+
+func (d *decodeBuffer) Stream(samples [][2]float64) (n int, ok bool) {
+	d.lock.Lock()
+	n = d.read(samples)
+	d.position += n
+	done := d.done
+	underrun := n < len(samples) && !done && !d.refilling
+	if underrun && !d.starving {
+		atomic.AddInt64(&d.metrics.underruns, 1)
+	}
+	d.starving = underrun
+	d.lock.Unlock()
+	d.cond.Broadcast()
+
+	if n < len(samples) && !done {
+		if underrun {
+			atomic.AddInt64(&d.metrics.underrunTime, int64(d.sampleRate.D(len(samples)-n)))
+		}
+		for i := range samples[n:] {
+			samples[n+i] = [2]float64{}
+		}
+		return len(samples), true
+	}
+	return n, n > 0
+}
+
+func (d *decodeBuffer) Err() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.err
+}
+
+func (d *decodeBuffer) Len() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.length
+}
+
+// Position returns the number of samples streamed.
+func (d *decodeBuffer) Position() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.position
+}
+
+// Seek drops buffered audio and starts decoding from given sample. Seek does not wait for decoder.
+func (d *decodeBuffer) Seek(p int) error {
+	if p < 0 {
+		p = 0
+	}
+	d.lock.Lock()
+	d.seek = p
+	d.generation++
+	d.start = 0
+	d.count = 0
+	d.position = p
+	d.done = false
+	d.err = nil
+	d.refilling = true
+	d.lock.Unlock()
+	d.cond.Broadcast()
+	return nil
+}
+
+// waiting returns true if playback has run out of audio and is waiting for decoder, either due to underrun or
+// after seeking.
+func (d *decodeBuffer) waiting() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.count == 0 && !d.done && (d.starving || d.refilling)
+}
+
+// buffered returns duration of decoded audio that is ready to play.
+func (d *decodeBuffer) buffered() time.Duration {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.sampleRate.D(d.count)
+}
+
+// waitBuffered waits until buffer has given number of samples, or stream is complete.
+func (d *decodeBuffer) waitBuffered(samples int) {
+	// decoder writes whole chunks, so ring might never be completely full
+	if max := len(d.ring) - decodeChunkSize + 1; samples > max {
+		samples = max
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for d.count < samples && !d.done && !d.closed {
+		d.cond.Wait()
+	}
+}
+
+// Close stops decoder and closes source. Source is closed before waiting for decoder,
+// since decoder might be blocked reading the source.
+func (d *decodeBuffer) Close() error {
+	d.lock.Lock()
+	d.closed = true
+	d.lock.Unlock()
+	d.cond.Broadcast()
+	err := d.source.Close()
+	<-d.stopped
+	return err
+}
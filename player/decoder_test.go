@@ -0,0 +1,165 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"tryffel.net/go/jellycli/interfaces"
+)
+
+// stallingReader reads given number of bytes from reader and then blocks until it is closed.
+type stallingReader struct {
+	reader  *bytes.Reader
+	limit   int
+	closed  chan bool
+	release sync.Once
+}
+
+func (s *stallingReader) Read(p []byte) (int, error) {
+	read := int(s.reader.Size()) - s.reader.Len()
+	if read >= s.limit {
+		<-s.closed
+		return 0, errors.New("closed")
+	}
+	if len(p) > s.limit-read {
+		p = p[:s.limit-read]
+	}
+	return s.reader.Read(p)
+}
+
+func (s *stallingReader) Close() error {
+	s.release.Do(func() { close(s.closed) })
+	return nil
+}
+
+func newTestDecodeBuffer(t *testing.T, source *testReader, size int, metrics *decodeMetrics) *decodeBuffer {
+	stream, err := newSongStream(source, interfaces.AudioFormatWav)
+	if err != nil {
+		t.Fatalf("decode stream: %v", err)
+	}
+	return newDecodeBuffer(stream, size, metrics)
+}
+
+func sampleValue(sample [2]float64) int {
+	return int(sample[0]*(1<<15-1) + 0.5)
+}
+
+func TestDecodeBuffer_Stream(t *testing.T) {
+	metrics := &decodeMetrics{}
+	buffer := newTestDecodeBuffer(t, &testReader{bytes.NewReader(testWav(20000))}, 5000, metrics)
+	defer buffer.Close()
+	buffer.waitBuffered(5000)
+
+	samples := make([][2]float64, 3000)
+	total := 0
+	for {
+		n, ok := buffer.Stream(samples)
+		if !ok {
+			break
+		}
+		for i := range samples[:n] {
+			if got := sampleValue(samples[i]); got != total+i {
+				t.Fatalf("sample %d, got: %d", total+i, got)
+			}
+		}
+		total += n
+		// let decoder keep ahead of playback
+		buffer.waitBuffered(len(samples))
+	}
+	if total != 20000 {
+		t.Errorf("streamed samples, got: %d, want: 20000", total)
+	}
+	if got := buffer.Position(); got != 20000 {
+		t.Errorf("position, got: %d, want: 20000", got)
+	}
+	if got := atomic.LoadInt64(&metrics.underruns); got != 0 {
+		t.Errorf("underruns, got: %d, want: 0", got)
+	}
+}
+
+func TestDecodeBuffer_Seek(t *testing.T) {
+	metrics := &decodeMetrics{}
+	buffer := newTestDecodeBuffer(t, &testReader{bytes.NewReader(testWav(20000))}, 4096, metrics)
+	defer buffer.Close()
+	buffer.waitBuffered(4096)
+
+	samples := make([][2]float64, 1)
+	for _, seek := range []int{15000, 1000, 0} {
+		buffer.Seek(seek)
+		if got := buffer.Position(); got != seek {
+			t.Errorf("position after seek, got: %d, want: %d", got, seek)
+		}
+		buffer.waitBuffered(1)
+		buffer.Stream(samples)
+		if got := sampleValue(samples[0]); got != seek {
+			t.Errorf("sample after seek, got: %d, want: %d", got, seek)
+		}
+	}
+	if got := atomic.LoadInt64(&metrics.underruns); got != 0 {
+		t.Errorf("underruns, got: %d, want: 0", got)
+	}
+}
+
+func TestDecodeBuffer_Underrun(t *testing.T) {
+	wav := testWav(20000)
+	// header and 1000 samples
+	source := &stallingReader{reader: bytes.NewReader(wav), limit: 44 + 1000*4, closed: make(chan bool)}
+	stream, err := newSongStream(source, interfaces.AudioFormatWav)
+	if err != nil {
+		t.Fatalf("decode stream: %v", err)
+	}
+	metrics := &decodeMetrics{}
+	buffer := newDecodeBuffer(stream, 4096, metrics)
+	buffer.waitBuffered(1000)
+
+	samples := make([][2]float64, 1500)
+	for i := 0; i < 3; i++ {
+		n, ok := buffer.Stream(samples)
+		if n != len(samples) || !ok {
+			t.Errorf("underrun continues with silence, got: %d, %t", n, ok)
+		}
+	}
+	if got := sampleValue(samples[0]); got != 0 {
+		t.Errorf("underrun plays silence, got sample: %d", got)
+	}
+	if got := atomic.LoadInt64(&metrics.underruns); got != 1 {
+		t.Errorf("underruns, got: %d, want: 1", got)
+	}
+	wantTime := int64(buffer.sampleRate.D(500) + buffer.sampleRate.D(1500)*2)
+	if got := atomic.LoadInt64(&metrics.underrunTime); got != wantTime {
+		t.Errorf("underrun time, got: %s, want: %s", time.Duration(got), time.Duration(wantTime))
+	}
+
+	// decoder is blocked reading source, close must not hang
+	closed := make(chan bool)
+	go func() {
+		buffer.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second * 5):
+		t.Fatalf("close blocks")
+	}
+}
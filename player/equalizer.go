@@ -0,0 +1,128 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/faiface/beep"
+	"math"
+	"tryffel.net/go/jellycli/config"
+)
+
+// biquad is a second order iir filter for stereo audio.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+
+	// previous input and output samples
+	x1, x2, y1, y2 [2]float64
+}
+
+// setPeaking sets filter to boost or attenuate gain (dB) around frequency (Hz). Filter state is kept,
+// so filter can be changed while streaming. See Robert Bristow-Johnson's Audio EQ Cookbook.
+func (b *biquad) setPeaking(frequency, gain, q float64, sampleRate beep.SampleRate) {
+	amplitude := math.Pow(10, gain/40)
+	w0 := 2 * math.Pi * frequency / float64(sampleRate)
+	alpha := math.Sin(w0) / (2 * q)
+	cosW0 := math.Cos(w0)
+
+	a0 := 1 + alpha/amplitude
+	b.b0 = (1 + alpha*amplitude) / a0
+	b.b1 = -2 * cosW0 / a0
+	b.b2 = (1 - alpha*amplitude) / a0
+	b.a1 = -2 * cosW0 / a0
+	b.a2 = (1 - alpha/amplitude) / a0
+}
+
+func (b *biquad) process(samples [][2]float64) {
+	for i := range samples {
+		for c := 0; c < 2; c++ {
+			x := samples[i][c]
+			y := b.b0*x + b.b1*b.x1[c] + b.b2*b.x2[c] - b.a1*b.y1[c] - b.a2*b.y2[c]
+			b.x2[c] = b.x1[c]
+			b.x1[c] = x
+			b.y2[c] = b.y1[c]
+			b.y1[c] = y
+			samples[i][c] = y
+		}
+	}
+}
+
+// equalizer is a graphic equalizer that filters streamer with one peaking filter per band.
+// Equalizer is not safe for concurrent use, caller must hold output lock.
+type equalizer struct {
+	Streamer beep.Streamer
+
+	enabled bool
+	gains   []float64
+	filters []biquad
+	// preamp prevents boosted bands from clipping
+	preamp float64
+
+	sampleRate  beep.SampleRate
+	frequencies []float64
+}
+
+func newEqualizer(sampleRate beep.SampleRate, frequencies []float64) *equalizer {
+	e := &equalizer{
+		sampleRate:  sampleRate,
+		frequencies: frequencies,
+		filters:     make([]biquad, len(frequencies)),
+		gains:       make([]float64, len(frequencies)),
+		preamp:      1,
+	}
+	e.setGains(e.gains)
+	return e
+}
+
+// setGains sets gain in dB for each band. Missing bands are set to 0 dB.
+func (e *equalizer) setGains(gains []float64) {
+	maxGain := 0.0
+	for i, frequency := range e.frequencies {
+		gain := 0.0
+		if i < len(gains) {
+			gain = gains[i]
+		}
+		e.gains[i] = gain
+		if gain > maxGain {
+			maxGain = gain
+		}
+		e.filters[i].setPeaking(frequency, gain, config.EqualizerQ, e.sampleRate)
+	}
+	e.preamp = math.Pow(10, -maxGain/20)
+}
+
+func (e *equalizer) Stream(samples [][2]float64) (n int, ok bool) {
+	n, ok = e.Streamer.Stream(samples)
+	if !e.enabled {
+		return n, ok
+	}
+	for i := range e.filters {
+		e.filters[i].process(samples[:n])
+	}
+	if e.preamp != 1 {
+		for i := range samples[:n] {
+			samples[i][0] *= e.preamp
+			samples[i][1] *= e.preamp
+		}
+	}
+	return n, ok
+}
+
+func (e *equalizer) Err() error {
+	return e.Streamer.Err()
+}
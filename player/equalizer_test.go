@@ -0,0 +1,81 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/faiface/beep"
+	"math"
+	"testing"
+	"tryffel.net/go/jellycli/config"
+)
+
+// equalize sine wave of given frequency and return its amplitude after equalizer has settled
+func equalize(e *equalizer, frequency float64) float64 {
+	position := 0
+	e.Streamer = beep.StreamerFunc(func(samples [][2]float64) (n int, ok bool) {
+		for i := range samples {
+			value := math.Sin(2 * math.Pi * frequency * float64(position) / config.AudioSamplingRate)
+			samples[i] = [2]float64{value, value}
+			position++
+		}
+		return len(samples), true
+	})
+
+	samples := make([][2]float64, config.AudioSamplingRate)
+	e.Stream(samples)
+	amplitude := 0.0
+	for _, v := range samples[len(samples)/2:] {
+		amplitude = math.Max(amplitude, math.Abs(v[0]))
+	}
+	return amplitude
+}
+
+func TestEqualizer_Stream(t *testing.T) {
+	boost1k := make([]float64, len(config.EqualizerFrequencies))
+	boost1k[5] = 6
+
+	tests := []struct {
+		name      string
+		enabled   bool
+		gains     []float64
+		frequency float64
+		// wantdB is wanted amplitude in dB
+		wantdB float64
+	}{
+		{name: "disabled", enabled: false, gains: boost1k, frequency: 1000, wantdB: 0},
+		{name: "flat", enabled: true, gains: config.EqualizerPresets[config.EqualizerPresetFlat],
+			frequency: 1000, wantdB: 0},
+		{name: "boosted band", enabled: true, gains: boost1k, frequency: 1000, wantdB: 0},
+		// boosted bands are compensated by lowering other frequencies
+		{name: "other band", enabled: true, gains: boost1k, frequency: 16000, wantdB: -6},
+		{name: "cut", enabled: true, gains: []float64{0, 0, 0, 0, 0, -6}, frequency: 1000, wantdB: -6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEqualizer(config.AudioSamplingRate, config.EqualizerFrequencies)
+			e.enabled = tt.enabled
+			e.setGains(tt.gains)
+
+			got := 20 * math.Log10(equalize(e, tt.frequency))
+			if math.Abs(got-tt.wantdB) > 0.3 {
+				t.Errorf("amplitude, got: %.2f dB, want: %.2f dB", got, tt.wantdB)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import "github.com/faiface/beep"
+
+// fader fades audio out linearly and stays silent after that, until it is reset. Fader does not modify
+// volume, so audio continues with the same volume after reset.
+// Fader is not safe for concurrent use, caller must hold output lock.
+type fader struct {
+	Streamer beep.Streamer
+	// length of fade in samples, 0 if not fading
+	length   int
+	position int
+}
+
+// fadeOut starts fading out over given number of samples.
+func (f *fader) fadeOut(length int) {
+	if length < 1 {
+		length = 1
+	}
+	f.length = length
+	f.position = 0
+}
+
+func (f *fader) reset() {
+	f.length = 0
+	f.position = 0
+}
+
+func (f *fader) Stream(samples [][2]float64) (n int, ok bool) {
+	n, ok = f.Streamer.Stream(samples)
+	if f.length == 0 {
+		return n, ok
+	}
+	for i := range samples[:n] {
+		gain := 0.0
+		if f.position < f.length {
+			gain = 1 - float64(f.position)/float64(f.length)
+			f.position++
+		}
+		samples[i][0] *= gain
+		samples[i][1] *= gain
+	}
+	return n, ok
+}
+
+func (f *fader) Err() error {
+	return f.Streamer.Err()
+}
@@ -0,0 +1,66 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/faiface/beep"
+	"testing"
+)
+
+func TestFader_Stream(t *testing.T) {
+	ones := beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		for i := range samples {
+			samples[i] = [2]float64{1, 1}
+		}
+		return len(samples), true
+	})
+	f := &fader{Streamer: ones}
+	samples := make([][2]float64, 4)
+
+	f.Stream(samples)
+	for i, v := range samples {
+		if v[0] != 1 || v[1] != 1 {
+			t.Errorf("not fading, sample %d, got: %v, want: 1", i, v)
+		}
+	}
+
+	f.fadeOut(4)
+	f.Stream(samples)
+	want := []float64{1, 0.75, 0.5, 0.25}
+	for i, v := range samples {
+		if v[0] != want[i] || v[1] != want[i] {
+			t.Errorf("fading, sample %d, got: %v, want: %f", i, v, want[i])
+		}
+	}
+
+	f.Stream(samples)
+	for i, v := range samples {
+		if v[0] != 0 || v[1] != 0 {
+			t.Errorf("faded, sample %d, got: %v, want: 0", i, v)
+		}
+	}
+
+	f.reset()
+	f.Stream(samples)
+	for i, v := range samples {
+		if v[0] != 1 || v[1] != 1 {
+			t.Errorf("reset, sample %d, got: %v, want: 1", i, v)
+		}
+	}
+}
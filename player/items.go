@@ -19,9 +19,11 @@
 package player
 
 import (
+	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"runtime"
+	"strings"
 	"tryffel.net/go/jellycli/api"
 	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
@@ -34,6 +36,8 @@ type Items struct {
 	browser api.MediaServer
 
 	db *storage.Db
+	// local has playlists that server could not save
+	local *localPlaylists
 }
 
 func newItems(api api.MediaServer) (*Items, error) {
@@ -49,6 +53,10 @@ func newItems(api api.MediaServer) (*Items, error) {
 			return items, fmt.Errorf("init local database: %v", err)
 		}
 	}
+	items.local, err = newLocalPlaylists(serverId)
+	if err != nil {
+		return items, fmt.Errorf("init local playlists: %v", err)
+	}
 	return items, err
 }
 
@@ -94,16 +102,27 @@ func (i *Items) GetAlbumSongs(album models.Id) ([]*models.Song, error) {
 }
 
 func (i *Items) GetPlaylists() ([]*models.Playlist, error) {
+	var playlists []*models.Playlist
+	var err error
 	if config.AppConfig.Player.EnableLocalCache {
-		return i.db.GetPlaylists()
+		playlists, err = i.db.GetPlaylists()
 	} else {
-		return i.browser.GetPlaylists()
+		playlists, err = i.browser.GetPlaylists()
 	}
-
+	if err != nil {
+		return playlists, err
+	}
+	return append(playlists, i.local.list()...), nil
 }
 
 func (i *Items) GetPlaylistSongs(playlist *models.Playlist) error {
-	songs, err := i.browser.GetPlaylistSongs(playlist.Id)
+	var songs []*models.Song
+	var err error
+	if playlist.IsLocal() {
+		songs, err = i.local.songs(playlist.Id)
+	} else {
+		songs, err = i.browser.GetPlaylistSongs(playlist.Id)
+	}
 	if err != nil {
 		return err
 	}
@@ -220,3 +239,48 @@ func (i *Items) GetLink(item models.Item) string {
 	return i.browser.GetLink(item)
 
 }
+
+// SetFavorite adds songs to favorites, or removes them from favorites. If some songs fail, rest of them are
+// still updated and returned error names failed songs.
+func (i *Items) SetFavorite(songs []*models.Song, favorite bool) error {
+	favoriter, ok := i.browser.(api.Favoriter)
+	if !ok {
+		return errors.New("server does not support favorites")
+	}
+	err := favoriter.SetFavorite(songIds(songs), favorite)
+	updated := songs
+	var failedNames []string
+	if err != nil {
+		favoriteErr, ok := err.(*api.FavoriteError)
+		if !ok {
+			return err
+		}
+		failed := make(map[models.Id]bool, len(favoriteErr.Failed))
+		for _, id := range favoriteErr.Failed {
+			failed[id] = true
+		}
+		updated = make([]*models.Song, 0, len(songs))
+		for _, v := range songs {
+			if failed[v.Id] {
+				failedNames = append(failedNames, v.Name)
+			} else {
+				updated = append(updated, v)
+			}
+		}
+		err = favoriteErr.Err
+	}
+
+	for _, v := range updated {
+		v.Favorite = favorite
+	}
+	if i.db != nil && len(updated) > 0 {
+		dbErr := i.db.UpdateSongs(updated)
+		if dbErr != nil {
+			logrus.Errorf("update favorite songs in local cache: %v", dbErr)
+		}
+	}
+	if len(failedNames) > 0 {
+		return fmt.Errorf("%s: %v", strings.Join(failedNames, ", "), err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/util"
+)
+
+// localPlaylists saves playlists that server could not save as json file. Songs are saved with complete info,
+// so that playlists can be played without querying server.
+type localPlaylists struct {
+	lock      sync.Mutex
+	file      string
+	playlists []*models.Playlist
+}
+
+func newLocalPlaylists(serverId string) (*localPlaylists, error) {
+	err := os.MkdirAll(config.AppConfig.Player.LocalCacheDir, 0760)
+	if err != nil {
+		return nil, fmt.Errorf("create cache dir: %v", err)
+	}
+	l := &localPlaylists{
+		file: path.Join(config.AppConfig.Player.LocalCacheDir, serverId+"-playlists.json"),
+	}
+	data, err := ioutil.ReadFile(l.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return l, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(data, &l.playlists)
+	if err != nil {
+		return nil, fmt.Errorf("parse playlists file: %v", err)
+	}
+	return l, nil
+}
+
+func newLocalPlaylistId() models.Id {
+	return models.Id(models.LocalPlaylistPrefix + util.RandomKey(16))
+}
+
+// list returns all playlists. Songs are not filled.
+func (l *localPlaylists) list() []*models.Playlist {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	playlists := make([]*models.Playlist, len(l.playlists))
+	for i, v := range l.playlists {
+		playlist := *v
+		playlist.Songs = nil
+		playlists[i] = &playlist
+	}
+	return playlists
+}
+
+// songs returns playlist songs.
+func (l *localPlaylists) songs(id models.Id) ([]*models.Song, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	index := l.index(id)
+	if index < 0 {
+		return nil, fmt.Errorf("local playlist %s not found", id)
+	}
+	songs := make([]*models.Song, len(l.playlists[index].Songs))
+	copy(songs, l.playlists[index].Songs)
+	return songs, nil
+}
+
+// save adds playlist or replaces existing playlist with same id.
+func (l *localPlaylists) save(playlist *models.Playlist) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	saved := *playlist
+	saved.Songs = make([]*models.Song, len(playlist.Songs))
+	copy(saved.Songs, playlist.Songs)
+
+	index := l.index(playlist.Id)
+	if index < 0 {
+		l.playlists = append(l.playlists, &saved)
+	} else {
+		l.playlists[index] = &saved
+	}
+	return l.write()
+}
+
+func (l *localPlaylists) remove(id models.Id) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	index := l.index(id)
+	if index < 0 {
+		return nil
+	}
+	l.playlists = append(l.playlists[:index], l.playlists[index+1:]...)
+	return l.write()
+}
+
+func (l *localPlaylists) index(id models.Id) int {
+	for i, v := range l.playlists {
+		if v.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (l *localPlaylists) write() error {
+	data, err := json.Marshal(l.playlists)
+	if err != nil {
+		return err
+	}
+	// write whole file at once, so that interrupted write does not corrupt old playlists
+	tmp := l.file + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.file)
+}
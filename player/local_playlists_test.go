@@ -0,0 +1,92 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"reflect"
+	"testing"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+func TestLocalPlaylists(t *testing.T) {
+	config.AppConfig.Player.LocalCacheDir = t.TempDir()
+	store, err := newLocalPlaylists("server-1")
+	if err != nil {
+		t.Fatalf("init local playlists: %v", err)
+	}
+	if got := store.list(); len(got) != 0 {
+		t.Errorf("list empty playlists, got: %v", got)
+	}
+
+	songs := []*models.Song{
+		{Id: "song-1", Album: "album-1", Name: "song 1", Duration: 100,
+			Artists: []models.IdName{{Id: "artist-1", Name: "artist 1"}}},
+		{Id: "song-2", Album: "album-1", Name: "song 2", Duration: 200},
+	}
+	first := &models.Playlist{Id: newLocalPlaylistId(), Name: "first"}
+	first.SetSongs(songs)
+	second := &models.Playlist{Id: newLocalPlaylistId(), Name: "second"}
+	second.SetSongs(songs[:1])
+	if !first.IsLocal() || first.Id == second.Id {
+		t.Fatalf("invalid local playlist ids: %s, %s", first.Id, second.Id)
+	}
+	for _, v := range []*models.Playlist{first, second} {
+		err = store.save(v)
+		if err != nil {
+			t.Fatalf("save playlist: %v", err)
+		}
+	}
+	first.Name = "renamed"
+	err = store.save(first)
+	if err != nil {
+		t.Fatalf("update playlist: %v", err)
+	}
+
+	// playlists are read from file
+	store, err = newLocalPlaylists("server-1")
+	if err != nil {
+		t.Fatalf("load local playlists: %v", err)
+	}
+	want := []*models.Playlist{
+		{Id: first.Id, Name: "renamed", Duration: 300, SongCount: 2},
+		{Id: second.Id, Name: "second", Duration: 100, SongCount: 1},
+	}
+	if got := store.list(); !reflect.DeepEqual(got, want) {
+		t.Errorf("list playlists, got: %v, want: %v", got, want)
+	}
+	gotSongs, err := store.songs(first.Id)
+	if err != nil {
+		t.Fatalf("get playlist songs: %v", err)
+	}
+	if !reflect.DeepEqual(gotSongs, songs) {
+		t.Errorf("get playlist songs, got: %v, want: %v", gotSongs, songs)
+	}
+
+	err = store.remove(first.Id)
+	if err != nil {
+		t.Fatalf("remove playlist: %v", err)
+	}
+	if got := store.list(); !reflect.DeepEqual(got, want[1:]) {
+		t.Errorf("list playlists after remove, got: %v, want: %v", got, want[1:])
+	}
+	if _, err = store.songs(first.Id); err == nil {
+		t.Errorf("get removed playlist songs, want error")
+	}
+}
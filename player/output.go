@@ -0,0 +1,121 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"time"
+	"tryffel.net/go/jellycli/config"
+)
+
+// output is an audio sink that pulls audio from streamers. Api follows faiface/beep/speaker.
+type output interface {
+	// Init starts output with given sample rate and buffer size in samples.
+	Init(sampleRate beep.SampleRate, bufferSize int) error
+	// Lock locks output. While locked, output does not pull data from streamers.
+	Lock()
+	// Unlock unlocks output.
+	Unlock()
+	// Play starts playing streamers.
+	Play(s ...beep.Streamer)
+	// Clear removes all streamers.
+	Clear()
+	// Close stops output and releases any resources.
+	Close() error
+	// Latency returns duration of audio that has been pulled from streamers but not yet played.
+	Latency() time.Duration
+}
+
+// newOutput creates audio output from config.
+func newOutput() (output, error) {
+	name, path := config.AppConfig.Player.Output()
+	format := config.AppConfig.Player.AudioOutputFormat
+
+	logrus.Infof("Audio output: %s %s", name, path)
+	switch name {
+	case config.AudioOutputSpeaker, "":
+		return &speakerOutput{}, nil
+	case config.AudioOutputNull:
+		return newStreamOutput(nil, false), nil
+	case config.AudioOutputFile:
+		if path == "" {
+			return nil, fmt.Errorf("file output requires path")
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("create output file: %v", err)
+		}
+		return newStreamOutput(file, format != config.AudioOutputFormatRaw), nil
+	case config.AudioOutputPipe:
+		var writer io.WriteCloser = os.Stdout
+		if path != config.AudioOutputStdout {
+			// opening fifo blocks until there is a reader
+			logrus.Infof("Waiting for reader on %s", path)
+			file, err := os.OpenFile(path, os.O_WRONLY, 0)
+			if err != nil {
+				return nil, fmt.Errorf("open output pipe: %v", err)
+			}
+			writer = file
+		}
+		return newStreamOutput(writer, format == config.AudioOutputFormatWav), nil
+	default:
+		return nil, fmt.Errorf("unknown audio output: '%s'", name)
+	}
+}
+
+// speakerOutput plays audio with default sound card.
+type speakerOutput struct {
+	latency time.Duration
+}
+
+func (s *speakerOutput) Init(sampleRate beep.SampleRate, bufferSize int) error {
+	// speaker pulls one buffer ahead of sound card
+	s.latency = sampleRate.D(bufferSize)
+	return speaker.Init(sampleRate, bufferSize)
+}
+
+func (s *speakerOutput) Lock() {
+	speaker.Lock()
+}
+
+func (s *speakerOutput) Unlock() {
+	speaker.Unlock()
+}
+
+func (s *speakerOutput) Play(streamers ...beep.Streamer) {
+	speaker.Play(streamers...)
+}
+
+func (s *speakerOutput) Clear() {
+	speaker.Clear()
+}
+
+func (s *speakerOutput) Close() error {
+	speaker.Close()
+	return nil
+}
+
+func (s *speakerOutput) Latency() time.Duration {
+	return s.latency
+}
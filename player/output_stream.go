@@ -0,0 +1,204 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/faiface/beep"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// streamOutput pulls audio in real time and writes it to writer as signed 16-bit little-endian stereo pcm,
+// optionally with wav header. Without writer audio is discarded.
+type streamOutput struct {
+	lock  sync.Mutex
+	mixer beep.Mixer
+
+	writer io.Writer
+	wav    bool
+	// written is number of pcm bytes written
+	written int
+
+	sampleRate beep.SampleRate
+	samples    [][2]float64
+	buf        []byte
+
+	done    chan bool
+	stopped chan bool
+}
+
+func newStreamOutput(writer io.Writer, wav bool) *streamOutput {
+	return &streamOutput{
+		writer: writer,
+		wav:    wav,
+	}
+}
+
+func (s *streamOutput) Init(sampleRate beep.SampleRate, bufferSize int) error {
+	s.sampleRate = sampleRate
+	s.samples = make([][2]float64, bufferSize)
+	s.buf = make([]byte, bufferSize*4)
+	if s.wav && s.writer != nil {
+		err := s.writeWavHeader(-1)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.done = make(chan bool)
+	s.stopped = make(chan bool)
+	go s.loop(sampleRate.D(bufferSize))
+	return nil
+}
+
+func (s *streamOutput) loop(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	defer close(s.stopped)
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.update()
+		}
+	}
+}
+
+// update pulls next buffer from streamers and writes it.
+func (s *streamOutput) update() {
+	s.lock.Lock()
+	s.mixer.Stream(s.samples)
+	s.lock.Unlock()
+
+	if s.writer == nil {
+		return
+	}
+
+	for i := range s.samples {
+		for c := range s.samples[i] {
+			val := s.samples[i][c]
+			if val < -1 {
+				val = -1
+			}
+			if val > 1 {
+				val = 1
+			}
+			binary.LittleEndian.PutUint16(s.buf[i*4+c*2:], uint16(int16(val*(1<<15-1))))
+		}
+	}
+
+	n, err := s.writer.Write(s.buf)
+	s.written += n
+	if err != nil {
+		logrus.Errorf("write audio output, discard audio: %v", err)
+		s.writer = nil
+	}
+}
+
+// writeWavHeader writes wav header for given pcm data length. If length is negative, length is unknown.
+func (s *streamOutput) writeWavHeader(length int) error {
+	size := uint32(0xFFFFFFFF)
+	dataSize := uint32(0xFFFFFFFF)
+	if length >= 0 {
+		size = uint32(36 + length)
+		dataSize = uint32(length)
+	}
+
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], size)
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	// pcm, 2 channels
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], 2)
+	binary.LittleEndian.PutUint32(header[24:], uint32(s.sampleRate))
+	// byte rate, block align, bits per sample
+	binary.LittleEndian.PutUint32(header[28:], uint32(s.sampleRate)*4)
+	binary.LittleEndian.PutUint16(header[32:], 4)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], dataSize)
+
+	_, err := s.writer.Write(header)
+	if err != nil {
+		return fmt.Errorf("write wav header: %v", err)
+	}
+	return nil
+}
+
+func (s *streamOutput) Lock() {
+	s.lock.Lock()
+}
+
+func (s *streamOutput) Unlock() {
+	s.lock.Unlock()
+}
+
+func (s *streamOutput) Play(streamers ...beep.Streamer) {
+	s.lock.Lock()
+	s.mixer.Add(streamers...)
+	s.lock.Unlock()
+}
+
+func (s *streamOutput) Clear() {
+	s.lock.Lock()
+	s.mixer.Clear()
+	s.lock.Unlock()
+}
+
+// Close stops output. If writer is a seekable file, wav header is updated with actual length.
+func (s *streamOutput) Close() error {
+	if s.done != nil {
+		close(s.done)
+		<-s.stopped
+		s.done = nil
+	}
+	if s.writer == nil {
+		return nil
+	}
+
+	var err error
+	if seeker, ok := s.writer.(io.WriteSeeker); ok && s.wav {
+		// pipes cannot seek, and length remains unknown
+		if _, seekErr := seeker.Seek(0, io.SeekStart); seekErr == nil {
+			err = s.writeWavHeader(s.written)
+		}
+	}
+	if closer, ok := s.writer.(io.Closer); ok && s.writer != os.Stdout {
+		closeErr := closer.Close()
+		if closeErr != nil {
+			err = fmt.Errorf("close audio output: %v", closeErr)
+		}
+	}
+	s.writer = nil
+	return err
+}
+
+// Latency returns 0, since audio is written as soon as it is pulled. Reader might buffer audio further.
+func (s *streamOutput) Latency() time.Duration {
+	return 0
+}
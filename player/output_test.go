@@ -0,0 +1,116 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/faiface/beep"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// constant returns streamer that streams given value for n samples
+func constant(value float64, n int) beep.Streamer {
+	return beep.Take(n, beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		for i := range samples {
+			samples[i] = [2]float64{value, -value}
+		}
+		return len(samples), true
+	}))
+}
+
+func TestStreamOutput_Wav(t *testing.T) {
+	file, err := os.Create(path.Join(t.TempDir(), "output.wav"))
+	if err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+
+	output := newStreamOutput(file, true)
+	err = output.Init(44100, 441)
+	if err != nil {
+		t.Fatalf("init output: %v", err)
+	}
+	output.Play(constant(0.5, 441))
+	time.Sleep(time.Millisecond * 100)
+	err = output.Close()
+	if err != nil {
+		t.Fatalf("close output: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+	if len(data) < 44+441*4 {
+		t.Fatalf("output too short: %d bytes", len(data))
+	}
+	header, pcm := data[:44], data[44:]
+	if !bytes.Equal(header[0:4], []byte("RIFF")) || !bytes.Equal(header[8:12], []byte("WAVE")) {
+		t.Errorf("invalid wav header: %v", header[:12])
+	}
+	if got := binary.LittleEndian.Uint32(header[4:]); int(got) != len(data)-8 {
+		t.Errorf("riff size, got: %d, want: %d", got, len(data)-8)
+	}
+	if got := binary.LittleEndian.Uint32(header[40:]); int(got) != len(pcm) {
+		t.Errorf("data size, got: %d, want: %d", got, len(pcm))
+	}
+	if got := binary.LittleEndian.Uint32(header[24:]); got != 44100 {
+		t.Errorf("sample rate, got: %d, want: 44100", got)
+	}
+
+	// 0.5 * (1<<15 - 1), truncated
+	want := int16(16383)
+	left := int16(binary.LittleEndian.Uint16(pcm[0:]))
+	right := int16(binary.LittleEndian.Uint16(pcm[2:]))
+	if left != want || right != -want {
+		t.Errorf("first sample, got: (%d, %d), want: (%d, %d)", left, right, want, -want)
+	}
+	// streamer has ended, rest is silence
+	last := int16(binary.LittleEndian.Uint16(pcm[len(pcm)-4:]))
+	if last != 0 {
+		t.Errorf("last sample, got: %d, want: 0", last)
+	}
+}
+
+func TestStreamOutput_Raw(t *testing.T) {
+	buf := &bytes.Buffer{}
+	output := newStreamOutput(buf, false)
+	err := output.Init(44100, 441)
+	if err != nil {
+		t.Fatalf("init output: %v", err)
+	}
+	output.Play(constant(1, 441))
+	time.Sleep(time.Millisecond * 100)
+	err = output.Close()
+	if err != nil {
+		t.Fatalf("close output: %v", err)
+	}
+
+	if buf.Len() == 0 || buf.Len()%(441*4) != 0 {
+		t.Fatalf("output length, got: %d, want multiple of %d", buf.Len(), 441*4)
+	}
+	first := int16(binary.LittleEndian.Uint16(buf.Bytes()))
+	if first != 1<<15-1 {
+		t.Errorf("first sample, got: %d, want: %d", first, 1<<15-1)
+	}
+}
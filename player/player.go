@@ -24,10 +24,10 @@ import (
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"io"
-	"strings"
 	"sync"
 	"time"
 	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
 	"tryffel.net/go/jellycli/models"
 	"tryffel.net/go/jellycli/task"
@@ -41,6 +41,8 @@ type songMetadata struct {
 	albumImageId  string
 	reader        io.ReadCloser
 	format        interfaces.AudioFormat
+	// bitrate of the stream in kbit/s, 0 if not known
+	bitrate int
 }
 
 // Player wraps all controllers and implements interfaces.QueueController, interfaces.Player and
@@ -54,44 +56,83 @@ type Player struct {
 	lock *sync.RWMutex
 
 	downloadingSong bool
+	// downloadCancel is closed when song being downloaded is no longer at downloadIndex in queue
+	downloadCancel chan struct{}
+	downloadIndex  int
+	downloadTarget *models.Song
 
-	songComplete   chan bool
-	audioUpdated   chan interfaces.AudioStatus
-	songDownloaded chan songMetadata
+	completedLock sync.Mutex
+	// completedSongs holds nextStarted flags of completed songs not yet handled by player loop
+	completedSongs []bool
 
-	nextSong *songMetadata
+	// songComplete signals player loop that there are songs in completedSongs
+	songComplete   chan struct{}
+	audioUpdated   chan interfaces.AudioStatus
+	songDownloaded chan *audioTrack
+	// prefetchUpdated requests player loop to check prefetched songs
+	prefetchUpdated chan bool
+	// prefetched has upcoming songs that are downloaded, excluding song queued to Audio
+	prefetched []*audioTrack
 
 	api              api.MediaServer
 	remoteController api.RemoteController
 
 	lastApiReport time.Time
+
+	sleep interfaces.SleepTimer
+	// sleepFading is true when audio is fading out before sleep timer fires
+	sleepFading bool
+
+	sessionLock     sync.Mutex
+	sessionStore    sessionStore
+	savedSession    *models.Session
+	lastSessionSave time.Time
+	// resumeSong is restored from session and is started paused at resumePosition
+	resumeSong     *models.Song
+	resumePosition time.Duration
 }
 
-// initialize new player. This also initializes faiface.Speaker, which should be initialized only once.
+// initialize new player. This also initializes audio output, which should be initialized only once.
 func NewPlayer(browser api.MediaServer) (*Player, error) {
 	var err error
 	p := &Player{
-		lock:           &sync.RWMutex{},
-		songComplete:   make(chan bool, 3),
-		audioUpdated:   make(chan interfaces.AudioStatus, 3),
-		songDownloaded: make(chan songMetadata, 3),
-		api:            browser,
+		lock:            &sync.RWMutex{},
+		songComplete:    make(chan struct{}, 1),
+		audioUpdated:    make(chan interfaces.AudioStatus, 3),
+		songDownloaded:  make(chan *audioTrack, 3),
+		prefetchUpdated: make(chan bool, 1),
+		api:             browser,
 	}
 	p.Name = "Player"
 	p.Task.SetLoop(p.loop)
 
-	p.Audio = newAudio()
+	out, err := newOutput()
+	if err != nil {
+		return p, fmt.Errorf("init audio output: %v", err)
+	}
+	p.Audio = newAudio(out)
+	p.Audio.setCrossfade(time.Duration(config.AppConfig.Player.CrossfadeS)*time.Second,
+		config.AppConfig.Player.CrossfadeAlbumAware)
+	p.Audio.setReplayGain(config.AppConfig.Player.ReplayGain, config.AppConfig.Player.ReplayGainPreampDb,
+		config.AppConfig.Player.ReplayGainClipProtection)
+	p.Audio.SetEqualizer(config.AppConfig.Player.EqualizerEnabled,
+		config.EqualizerGains(config.AppConfig.Player.EqualizerPreset, config.AppConfig.Player.EqualizerBands))
+	p.SetQuality(config.AppConfig.Player.QualityProfile)
 	p.Queue = newQueue()
 	p.Items, err = newItems(browser)
 	if err != nil {
 		return p, err
 	}
+	err = p.initSession()
+	if err != nil {
+		logrus.Errorf("load saved session: %v", err)
+	}
 	if remoteController, ok := browser.(api.RemoteController); ok {
 		p.remoteController = remoteController
 		p.remoteController.SetPlayer(p)
 	}
 
-	err = initAudio()
+	err = p.Audio.initOutput()
 	if err != nil {
 		return p, fmt.Errorf("init audio backend: %v", err)
 	}
@@ -103,9 +144,44 @@ func NewPlayer(browser api.MediaServer) (*Player, error) {
 	return p, nil
 }
 
-// notify song has completed
-func (p *Player) songCompleted() {
-	p.songComplete <- true
+// notify song has completed. This is called from audio output with its lock held, so it must not block.
+// Completions are recorded and player loop is woken up, possibly once for several completions.
+func (p *Player) songCompleted(nextStarted bool) {
+	p.completedLock.Lock()
+	p.completedSongs = append(p.completedSongs, nextStarted)
+	p.completedLock.Unlock()
+	select {
+	case p.songComplete <- struct{}{}:
+	default:
+		// loop has not handled previous signal yet
+	}
+}
+
+// takeCompletedSongs returns completions recorded with songCompleted and clears them.
+func (p *Player) takeCompletedSongs() []bool {
+	p.completedLock.Lock()
+	defer p.completedLock.Unlock()
+	completed := p.completedSongs
+	p.completedSongs = nil
+	return completed
+}
+
+// handleSongComplete moves queue forward after song has completed and makes sure next song gets played.
+func (p *Player) handleSongComplete(nextStarted bool) {
+	logrus.Debug("song complete")
+	sleep := p.sleepAtSongEnd()
+	p.Queue.songComplete()
+	if sleep {
+		p.sleepTimerFired()
+	} else if nextStarted {
+		// next song was already queued to audio and it's now playing
+		p.updatePrefetch()
+	} else if len(p.Queue.GetQueue()) == 0 {
+		p.Audio.StopMedia()
+	} else if len(p.songDownloaded) == 0 {
+		// next song might be downloaded already, in which case it is played once received
+		go p.downloadSong(0)
+	}
 }
 
 //is download pending / ongoing
@@ -123,129 +199,226 @@ func (p *Player) loop() {
 		select {
 		case <-p.StopChan():
 			// stop application
+			p.closeSession()
+			p.cancelDownload(nil)
 			p.Audio.StopMedia()
+			p.closePrefetched()
+			err := p.Audio.closeOutput()
+			if err != nil {
+				logrus.Errorf("close audio output: %v", err)
+			}
 			p.Items.closeDb()
 			break
 		case <-p.songComplete:
 			// stream / song complete, get next song
-			logrus.Debug("song complete")
-			p.Queue.songComplete()
-			if len(p.Queue.GetQueue()) == 0 {
-				p.Audio.StopMedia()
-			} else {
-				if p.nextSong != nil {
-					err := p.Audio.playSongFromReader(*p.nextSong)
-					if err != nil {
-						logrus.Errorf("play track: %v", err)
-					}
-					p.nextSong = nil
-				} else {
-					p.downloadSong(0)
-				}
+			for _, nextStarted := range p.takeCompletedSongs() {
+				p.handleSongComplete(nextStarted)
 			}
 		case status := <-p.audioUpdated:
 			logrus.Infof("got audio status: %v", status)
 		case <-ticker.C:
 			// periodically update status, this will push status to p.audioUpdated
 			p.Audio.updateStatus()
-			if p.status.Song != nil && p.status.State == interfaces.AudioStatePlaying {
-				if (p.status.Song.Duration-p.status.SongPast.Seconds()) < 5 &&
-					!p.isDownloadingSong() && p.nextSong == nil && len(p.Queue.GetQueue()) >= 2 {
-					p.downloadSong(1)
-				}
-			}
-		case metadata := <-p.songDownloaded:
-			if p.status.State == interfaces.AudioStateStopped {
+			p.checkSleepTimer()
+			p.saveSessionPeriodically()
+			p.prefetch()
+		case <-p.prefetchUpdated:
+			p.updatePrefetch()
+		case track := <-p.songDownloaded:
+			queue := p.Queue.GetQueue()
+			if p.Audio.hasCurrentSong() {
+				// upcoming song is decoded ahead of time to play it without a gap
+				p.addPrefetched(track)
+			} else if len(queue) > 0 && queue[0] == track.metadata.song {
 				// download complete, send to audio
-				err := p.Audio.playSongFromReader(metadata)
+				position, resume := p.takeResumePosition(track.metadata.song)
+				if resume {
+					p.Audio.Pause()
+				}
+				err := p.Audio.playTrack(track)
 				if err != nil {
 					logrus.Errorf("play track: %v", err)
 				}
-				p.nextSong = nil
+				if resume {
+					p.Audio.SetPosition(interfaces.AudioTick(position.Milliseconds()))
+				}
 			} else {
-				p.nextSong = &metadata
+				// queue changed during download and song is not needed anymore
+				closeTrack(track)
+				if len(queue) > 0 && p.Queue.SongError(queue[0].Id) == nil {
+					go p.downloadSong(0)
+				}
 			}
 		}
 	}
 }
 
-// download and play next song asynchronously
+// downloadSong downloads and decodes song in given index in queue and sends it to audio. If song is not
+// playable, it is retried with backoff. If song still fails and it was going to play next, skip it.
 func (p *Player) downloadSong(index int) {
-	if p.isDownloadingSong() || p.Queue.empty() {
+	queue := p.Queue.GetQueue()
+	if index < 0 || index >= len(queue) {
 		return
 	}
-	song := p.Queue.GetQueue()[index]
-
 	p.lock.Lock()
+	if p.downloadingSong {
+		p.lock.Unlock()
+		return
+	}
+	song := queue[index]
+	cancel := make(chan struct{})
 	p.downloadingSong = true
+	p.downloadCancel = cancel
+	p.downloadIndex = index
+	p.downloadTarget = song
+	p.lock.Unlock()
+
+	// song is loaded for playback, instead of being downloaded ahead of time
+	current := index == 0 && !p.Audio.hasCurrentSong()
+
+	var track *audioTrack
+	err := retry(config.AppConfig.Player.RetryCount,
+		time.Duration(config.AppConfig.Player.RetryBackoffMs)*time.Millisecond, cancel, func() error {
+			var err error
+			track, err = p.loadSong(song, current)
+			if err != nil {
+				logrus.Warningf("load song %s: %v", song.Name, err)
+			}
+			return err
+		})
+
+	p.lock.Lock()
+	p.downloadingSong = false
+	p.downloadCancel = nil
+	p.downloadTarget = nil
 	p.lock.Unlock()
-	ok := false
 
+	if err == nil {
+		p.Queue.setSongError(song.Id, nil)
+		p.songDownloaded <- track
+		return
+	}
+
+	select {
+	case <-cancel:
+		// song is not needed anymore, download whatever is needed instead
+		logrus.Debugf("stop retrying song %s, queue changed", song.Name)
+		p.queueChanged(p.Queue.GetQueue())
+	default:
+		p.songFailed(song, current, err)
+	}
+}
+
+// cancelDownload cancels retrying ongoing download, if song is not at its original position in queue anymore.
+// Nil queue always cancels download.
+func (p *Player) cancelDownload(queue []*models.Song) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.downloadCancel == nil {
+		return
+	}
+	if queue != nil && p.downloadIndex < len(queue) && queue[p.downloadIndex] == p.downloadTarget {
+		return
+	}
+	close(p.downloadCancel)
+	p.downloadCancel = nil
+}
+
+// loadSong downloads song, fills its metadata and decodes it.
+func (p *Player) loadSong(song *models.Song, current bool) (*audioTrack, error) {
+	if current {
+		p.Audio.setState(interfaces.AudioStateLoading, nil)
+	}
 	reader, format, err := p.api.Stream(song)
 	if err != nil {
-		if strings.Contains(err.Error(), "A task was canceled") {
-			// server task may fail sometimes, retry
-			logrus.Warningf("Failed to download song, retrying: %v", err)
-			time.Sleep(time.Second)
-			reader, format, err = p.api.Stream(song)
-			if err == nil {
-				ok = true
-			} else {
-				logrus.Errorf("retry downloading song: %v", err)
-			}
-		} else {
-			logrus.Errorf("download song: %v", err)
-		}
+		return nil, fmt.Errorf("download: %v", err)
+	}
+
+	metadata := songMetadata{
+		song:   song,
+		album:  &models.Album{Name: "unknown album"},
+		artist: &models.Artist{Name: "unknown artist"},
+		reader: reader,
+		format: format,
+	}
+	if stream, ok := reader.(interface{ Bitrate() int }); ok {
+		metadata.bitrate = stream.Bitrate()
+	}
+	album, err := p.api.GetAlbum(song.GetParent())
+	if err != nil {
+		logrus.Errorf("Failed to get album by id: %v", err)
 	} else {
-		ok = true
-	}
-	if ok {
-		// fill metadata
-		albumId := song.GetParent()
-		album, err := p.api.GetAlbum(albumId)
-		artist := &models.Artist{Name: "unknown artist"}
-		var imageId string
-		var imageUrl string
-		if err != nil {
-			logrus.Error("Failed to get album by id: ", err.Error())
-			album = &models.Album{Name: "unknown album"}
-		} else {
-			imageId = album.ImageId
-			imageUrl = p.api.GetImageUrl(album.Id, models.TypeAlbum)
-		}
-		a, err := p.api.GetArtist(album.GetParent())
+		metadata.album = album
+		metadata.albumImageId = album.ImageId
+		metadata.albumImageUrl = p.api.GetImageUrl(album.Id, models.TypeAlbum)
+		artist, err := p.api.GetArtist(album.GetParent())
 		if err != nil {
 			logrus.Errorf("Failed to get artist by id: %v", err)
 		} else {
-			artist = a
-			f := func() {
-				metadata := songMetadata{
-					song:          song,
-					album:         album,
-					artist:        artist,
-					albumImageUrl: imageUrl,
-					albumImageId:  imageId,
-					reader:        reader,
-					format:        format,
-				}
-				p.songDownloaded <- metadata
-			}
-			defer f()
+			metadata.artist = artist
 		}
 	}
 
-	p.lock.Lock()
-	p.downloadingSong = false
-	p.lock.Unlock()
+	if current {
+		p.Audio.setState(interfaces.AudioStateBuffering, nil)
+	}
+	track, err := p.Audio.newTrack(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %v", err)
+	}
+	return track, nil
+}
+
+// songFailed marks song as failed. If song was going to play next, it is skipped and next song in queue is
+// started, unless that song has failed before too: then playback stops, so that player does not keep retrying
+// a queue that cannot be played.
+func (p *Player) songFailed(song *models.Song, current bool, err error) {
+	logrus.Errorf("song %s failed: %v", song.Name, err)
+	err = fmt.Errorf("%s: %v", song.Name, err)
+	p.Queue.setSongError(song.Id, err)
+	if !current {
+		// next song is downloaded again once current one completes. Player might have nothing to play,
+		// if queue changed during download.
+		p.queueChanged(p.Queue.GetQueue())
+		return
+	}
 
-	// push song to audio
+	p.Audio.setState(interfaces.AudioStateError, err)
+	queue := p.Queue.GetQueue()
+	if len(queue) == 0 {
+		return
+	}
+	if queue[0] != song {
+		// queue has changed meanwhile, play new song instead
+		if p.Queue.SongError(queue[0].Id) == nil {
+			p.downloadSong(0)
+		}
+		return
+	}
+	p.Queue.skipSong()
+	queue = p.Queue.GetQueue()
+	if len(queue) == 0 || p.Queue.SongError(queue[0].Id) != nil {
+		return
+	}
+	logrus.Infof("Skip song %s, play next song", song.Name)
+	p.downloadSong(0)
 }
 
 // Next plays next song from queue. Override Audio next to ensure there is track to play and download it
 func (p *Player) Next() {
-	if len(p.Queue.GetQueue()) > 1 {
+	queue := p.Queue.GetQueue()
+	if len(queue) > 1 {
+		// next song is usually downloaded already
+		played, err := p.Audio.playNextTrack(queue[1])
+		if err != nil {
+			logrus.Errorf("play next track: %v", err)
+		}
+		if played {
+			p.Queue.skipSong()
+			return
+		}
 		p.StopMedia()
-		p.Queue.songComplete()
+		p.Queue.skipSong()
 		go p.downloadSong(0)
 	}
 }
@@ -271,7 +444,7 @@ func (p *Player) audioCallback(status interfaces.AudioStatus) {
 		return
 	}
 
-	if status.State == interfaces.AudioStateStopped && status.Action == interfaces.AudioActionTimeUpdate {
+	if !status.State.HasSong() && status.Action == interfaces.AudioActionTimeUpdate {
 		// don't report TimeUpdate if player is stopped
 		return
 	}
@@ -289,6 +462,8 @@ func (p *Player) audioCallback(status interfaces.AudioStatus) {
 		Position:       status.SongPast.Seconds(),
 		Volume:         int(status.Volume),
 		Shuffle:        status.Shuffle,
+		Repeat:         status.Repeat,
+		Speed:          float64(status.Speed),
 	}
 
 	switch status.Action {
@@ -304,14 +479,21 @@ func (p *Player) audioCallback(status interfaces.AudioStatus) {
 		apiStatus.Event = interfaces.EventVolumeChange
 	case interfaces.AudioActionTimeUpdate:
 		apiStatus.Event = interfaces.EventTimeUpdate
+	case interfaces.AudioActionSeek:
+		apiStatus.Event = interfaces.EventTimeUpdate
 	case interfaces.AudioActionPlayPause:
-		if status.Paused {
+		if status.State == interfaces.AudioStatePaused {
 			apiStatus.Event = interfaces.EventPause
 		} else {
 			apiStatus.Event = interfaces.EventUnpause
 		}
 	case interfaces.AudioActionShuffleChanged:
 		apiStatus.Event = interfaces.EventShuffleModeChange
+	case interfaces.AudioActionRepeatChanged:
+		apiStatus.Event = interfaces.EventRepeatModeChange
+	case interfaces.AudioActionSpeedChanged, interfaces.AudioActionSleepTimerChanged,
+		interfaces.AudioActionQualityChanged:
+		apiStatus.Event = interfaces.EventTimeUpdate
 	default:
 		apiStatus.Event = interfaces.EventTimeUpdate
 		logrus.Warningf("cannot map audio state to browser event: %v", status.Action)
@@ -323,7 +505,7 @@ func (p *Player) audioCallback(status interfaces.AudioStatus) {
 		queue[i] = v.Id
 	}
 	apiStatus.Queue = queue
-	apiStatus.IsPaused = status.Paused
+	apiStatus.IsPaused = status.State == interfaces.AudioStatePaused
 
 	if status.Song != nil {
 		apiStatus.ItemId = status.Song.Id.String()
@@ -338,17 +520,45 @@ func (p *Player) audioCallback(status interfaces.AudioStatus) {
 	go f()
 }
 
+// GetStatistics returns application statistics, including playback buffer health.
+func (p *Player) GetStatistics() models.Stats {
+	stats := p.Items.GetStatistics()
+	stats.Playback = p.Audio.playbackStats()
+	return stats
+}
+
+// SetQuality sets active quality profile. If there is no such profile, first profile is used.
+// Songs that are already downloaded are played with the quality they were downloaded with.
+func (p *Player) SetQuality(name string) {
+	profile := config.AppConfig.Player.GetQualityProfile(name)
+	logrus.Infof("Set quality profile: %s (%s)", profile.Name, profile)
+	config.AppConfig.Player.QualityProfile = profile.Name
+	if transcoder, ok := p.api.(api.Transcoder); ok {
+		transcoder.SetQuality(profile)
+	} else {
+		logrus.Warning("server does not support transcoding")
+	}
+	p.Audio.setQuality(profile.Name)
+}
+
 func (p *Player) queueChanged(queue []*models.Song) {
+	p.cancelDownload(queue)
+	// upcoming songs might have changed
+	p.refreshPrefetch()
 	// if player has nothing to play, start download
-	state := p.Audio.getStatus()
-	if state.State == interfaces.AudioStateStopped && len(queue) > 0 {
-		go p.downloadSong(0)
+	if !p.Audio.hasCurrentSong() && len(queue) > 0 {
+		go func() {
+			// song that has failed is only retried when user plays it
+			if p.Queue.SongError(queue[0].Id) == nil {
+				p.downloadSong(0)
+			}
+		}()
 	}
 }
 
 func (p *Player) Reorder(index int, left bool) bool {
 	// do not allow ongoing song to be reordered
-	if p.status.State == interfaces.AudioStatePlaying {
+	if p.Audio.getStatus().State.HasSong() {
 		if index == 0 {
 			return false
 		}
@@ -360,7 +570,54 @@ func (p *Player) Reorder(index int, left bool) bool {
 	return p.Queue.Reorder(index, left)
 }
 
+func (p *Player) ReorderRange(start, end int, left bool) bool {
+	// do not allow ongoing song to be reordered
+	if p.Audio.getStatus().State.HasSong() {
+		if start == 0 {
+			return false
+		}
+		if start == 1 && left {
+			return false
+		}
+	}
+
+	return p.Queue.ReorderRange(start, end, left)
+}
+
 func (p *Player) SetShuffle(enabled bool) {
 	p.Queue.SetShuffle(enabled)
 	p.Audio.SetShuffle(enabled)
 }
+
+// UndoQueue reverts latest edit to queue and updates shuffle status, if undo toggled shuffle.
+func (p *Player) UndoQueue() bool {
+	undone := p.Queue.UndoQueue()
+	p.syncShuffle()
+	return undone
+}
+
+// RedoQueue applies latest edit that was undone and updates shuffle status, if redo toggled shuffle.
+func (p *Player) RedoQueue() bool {
+	redone := p.Queue.RedoQueue()
+	p.syncShuffle()
+	return redone
+}
+
+func (p *Player) syncShuffle() {
+	shuffle := p.Queue.shuffleEnabled()
+	if shuffle != p.Audio.getStatus().Shuffle {
+		p.Audio.SetShuffle(shuffle)
+	}
+}
+
+func (p *Player) SetShuffleMode(mode interfaces.ShuffleMode) {
+	p.Queue.SetShuffleMode(mode)
+	p.Audio.SetShuffleMode(mode)
+}
+
+func (p *Player) SetRepeat(mode interfaces.RepeatMode) {
+	p.Queue.SetRepeat(mode)
+	p.Audio.SetRepeat(mode)
+	// upcoming songs depend on repeat mode
+	p.refreshPrefetch()
+}
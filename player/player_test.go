@@ -0,0 +1,154 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"bytes"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"io"
+	"sync"
+	"testing"
+	"time"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+// testServer streams test audio. Songs in failing cannot be downloaded.
+type testServer struct {
+	*api.MockServer
+	failing map[models.Id]bool
+}
+
+func (t *testServer) Stream(song *models.Song) (io.ReadCloser, interfaces.AudioFormat, error) {
+	if t.failing[song.Id] {
+		return nil, interfaces.AudioFormatNil, errors.New("download failed")
+	}
+	return &testReader{bytes.NewReader(testWav(20000))}, interfaces.AudioFormatWav, nil
+}
+
+func (t *testServer) Download(song *models.Song) (io.ReadCloser, interfaces.AudioFormat, error) {
+	return t.Stream(song)
+}
+
+func (t *testServer) GetAlbum(id models.Id) (*models.Album, error) {
+	return nil, errors.New("not found")
+}
+
+func (t *testServer) GetArtist(id models.Id) (*models.Artist, error) {
+	return nil, errors.New("not found")
+}
+
+func (t *testServer) GetId() string {
+	return "test"
+}
+
+func (t *testServer) GetImageUrl(item models.Id, itemType models.ItemType) string {
+	return ""
+}
+
+func TestPlayer_downloadSong_afterSongEnded(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	songs := testSongs()[:3]
+	server := &testServer{MockServer: api.NewMockServer(), failing: map[models.Id]bool{songs[1].Id: true}}
+	p := &Player{
+		lock:            &sync.RWMutex{},
+		Audio:           newAudio(newStreamOutput(nil, false)),
+		Queue:           newQueue(),
+		songDownloaded:  make(chan *audioTrack, 3),
+		prefetchUpdated: make(chan bool, 1),
+		api:             server,
+	}
+	p.Queue.AddSongs(songs)
+
+	track, err := p.loadSong(songs[0], true)
+	if err != nil {
+		t.Fatalf("load song: %v", err)
+	}
+	err = p.Audio.playTrack(track)
+	if err != nil {
+		t.Fatalf("play song: %v", err)
+	}
+
+	// song ends without next song being downloaded
+	p.Audio.output.Lock()
+	p.Audio.trackCompleted()
+	p.Audio.output.Unlock()
+	p.Queue.songComplete()
+
+	p.downloadSong(0)
+	if p.Queue.SongError(songs[1].Id) == nil {
+		t.Errorf("expect failed song to have error")
+	}
+	queue := p.Queue.GetQueue()
+	if len(queue) != 1 || queue[0] != songs[2] {
+		t.Fatalf("expect failed song to be skipped, got queue: %v", queue)
+	}
+	select {
+	case track := <-p.songDownloaded:
+		if track.metadata.song != songs[2] {
+			t.Errorf("downloaded song, got: %s, want: %s", track.metadata.song.Name, songs[2].Name)
+		}
+		closeTrack(track)
+	default:
+		t.Errorf("expect next song to be downloaded")
+	}
+}
+
+func TestPlayer_songCompleted(t *testing.T) {
+	p := &Player{
+		lock:         &sync.RWMutex{},
+		songComplete: make(chan struct{}, 1),
+	}
+	want := []bool{true, true, false, true, false}
+
+	// player loop is not running, songCompleted must not block audio output
+	done := make(chan struct{})
+	go func() {
+		for _, v := range want {
+			p.songCompleted(v)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("songCompleted blocked")
+	}
+
+	select {
+	case <-p.songComplete:
+	default:
+		t.Fatalf("expect player loop to be signalled")
+	}
+	got := p.takeCompletedSongs()
+	if len(got) != len(want) {
+		t.Fatalf("completed songs, got: %v, want: %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("completed songs, got: %v, want: %v", got, want)
+			break
+		}
+	}
+	if got := p.takeCompletedSongs(); len(got) != 0 {
+		t.Errorf("expect completed songs to be cleared, got: %v", got)
+	}
+}
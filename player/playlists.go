@@ -0,0 +1,233 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/models"
+)
+
+var errPlaylistsNotEditable = errors.New("server does not support editing playlists")
+
+// CreatePlaylist creates playlist on server. If server does not support editing playlists, it is saved locally.
+func (i *Items) CreatePlaylist(name string, songs []*models.Song) (*models.Playlist, error) {
+	editor, ok := i.browser.(api.PlaylistEditor)
+	if !ok {
+		logrus.Infof("%v, save playlist %s locally", errPlaylistsNotEditable, name)
+		playlist := &models.Playlist{Id: newLocalPlaylistId(), Name: name}
+		playlist.SetSongs(songs)
+		err := i.local.save(playlist)
+		if err != nil {
+			return nil, fmt.Errorf("save local playlist: %v", err)
+		}
+		return playlist, nil
+	}
+
+	playlist, err := editor.CreatePlaylist(name, songIds(songs))
+	if err != nil {
+		return nil, err
+	}
+	playlist.SetSongs(songs)
+	i.savePlaylist(playlist)
+	return playlist, nil
+}
+
+func (i *Items) RenamePlaylist(playlist *models.Playlist, name string) error {
+	editor, err := i.playlistEditor(playlist)
+	if err != nil {
+		return err
+	}
+	err = i.loadPlaylistSongs(playlist)
+	if err != nil {
+		return err
+	}
+	if editor != nil {
+		err = editor.RenamePlaylist(playlist.Id, name)
+		if err != nil {
+			return err
+		}
+	}
+	playlist.Name = name
+	return i.savePlaylist(playlist)
+}
+
+func (i *Items) DeletePlaylist(playlist *models.Playlist) error {
+	if playlist.IsLocal() {
+		return i.local.remove(playlist.Id)
+	}
+	editor, err := i.playlistEditor(playlist)
+	if err != nil {
+		return err
+	}
+	err = editor.DeletePlaylist(playlist.Id)
+	if err != nil {
+		return err
+	}
+	if i.db != nil {
+		err = i.db.DeletePlaylist(playlist.Id)
+		if err != nil {
+			logrus.Errorf("delete local playlist: %v", err)
+		}
+	}
+	return nil
+}
+
+func (i *Items) AddPlaylistSongs(playlist *models.Playlist, songs []*models.Song) error {
+	editor, err := i.playlistEditor(playlist)
+	if err != nil {
+		return err
+	}
+	err = i.loadPlaylistSongs(playlist)
+	if err != nil {
+		return err
+	}
+	if editor != nil {
+		err = editor.AddPlaylistSongs(playlist.Id, songIds(songs))
+		if err != nil {
+			return err
+		}
+	}
+	newSongs := make([]*models.Song, 0, len(playlist.Songs)+len(songs))
+	newSongs = append(newSongs, playlist.Songs...)
+	playlist.SetSongs(append(newSongs, songs...))
+	return i.savePlaylist(playlist)
+}
+
+func (i *Items) RemovePlaylistSongs(playlist *models.Playlist, indices []int) error {
+	editor, err := i.playlistEditor(playlist)
+	if err != nil {
+		return err
+	}
+	err = i.loadPlaylistSongs(playlist)
+	if err != nil {
+		return err
+	}
+	for _, index := range indices {
+		if index < 0 || index >= len(playlist.Songs) {
+			return fmt.Errorf("invalid index %d, playlist has %d songs", index, len(playlist.Songs))
+		}
+	}
+	if editor != nil {
+		err = editor.RemovePlaylistSongs(playlist.Id, indices)
+		if err != nil {
+			return err
+		}
+	}
+	playlist.RemoveSongs(indices)
+	return i.savePlaylist(playlist)
+}
+
+func (i *Items) MovePlaylistSong(playlist *models.Playlist, index, newIndex int) error {
+	editor, err := i.playlistEditor(playlist)
+	if err != nil {
+		return err
+	}
+	err = i.loadPlaylistSongs(playlist)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= len(playlist.Songs) || newIndex < 0 || newIndex >= len(playlist.Songs) {
+		return fmt.Errorf("invalid index %d -> %d, playlist has %d songs", index, newIndex, len(playlist.Songs))
+	}
+	if editor != nil {
+		err = editor.MovePlaylistSong(playlist.Id, index, newIndex)
+		if err != nil {
+			return err
+		}
+	}
+	playlist.MoveSong(index, newIndex)
+	return i.savePlaylist(playlist)
+}
+
+// SetPlaylistSongs replaces playlist songs and returns playlist that songs were saved to. If server does not
+// support editing playlists, songs are saved to new local playlist with same name.
+func (i *Items) SetPlaylistSongs(playlist *models.Playlist, songs []*models.Song) (*models.Playlist, error) {
+	editor, err := i.playlistEditor(playlist)
+	if err == errPlaylistsNotEditable {
+		logrus.Warningf("cannot overwrite playlist %s on server, save playlist locally", playlist.Name)
+		return i.CreatePlaylist(playlist.Name, songs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if editor != nil {
+		err = editor.SetPlaylistSongs(playlist.Id, songIds(songs))
+		if err != nil {
+			return nil, err
+		}
+	}
+	playlist.SetSongs(songs)
+	return playlist, i.savePlaylist(playlist)
+}
+
+// playlistEditor returns editor for playlist on server, or nil if playlist is saved locally.
+func (i *Items) playlistEditor(playlist *models.Playlist) (api.PlaylistEditor, error) {
+	if playlist.IsLocal() {
+		return nil, nil
+	}
+	editor, ok := i.browser.(api.PlaylistEditor)
+	if !ok {
+		return nil, errPlaylistsNotEditable
+	}
+	return editor, nil
+}
+
+// loadPlaylistSongs gets playlist songs, if they are not yet loaded.
+func (i *Items) loadPlaylistSongs(playlist *models.Playlist) error {
+	if len(playlist.Songs) == playlist.SongCount {
+		return nil
+	}
+	err := i.GetPlaylistSongs(playlist)
+	if err != nil {
+		return fmt.Errorf("get playlist songs: %v", err)
+	}
+	playlist.SetSongs(playlist.Songs)
+	return nil
+}
+
+// savePlaylist saves local playlist, or updates server playlist in local cache so that changes are visible
+// without updating cache from server.
+func (i *Items) savePlaylist(playlist *models.Playlist) error {
+	if playlist.IsLocal() {
+		err := i.local.save(playlist)
+		if err != nil {
+			return fmt.Errorf("save local playlist: %v", err)
+		}
+		return nil
+	}
+	if i.db == nil {
+		return nil
+	}
+	err := i.db.UpdatePlaylists([]*models.Playlist{playlist})
+	if err != nil {
+		logrus.Errorf("update local playlist: %v", err)
+	}
+	return nil
+}
+
+func songIds(songs []*models.Song) []models.Id {
+	ids := make([]models.Id, len(songs))
+	for i, v := range songs {
+		ids[i] = v.Id
+	}
+	return ids
+}
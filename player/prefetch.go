@@ -0,0 +1,147 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/sirupsen/logrus"
+	"time"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+// Upcoming songs are downloaded ahead of time, so that playback does not stall when current song completes.
+// First upcoming song is queued to Audio to play without a gap, and rest of them are kept in Player.prefetched.
+// Downloaded tracks must always match the beginning of upcoming songs: when queue changes, tracks that no longer
+// match are closed and downloaded again when needed. Prefetched tracks are only modified in player loop.
+
+// refreshPrefetch requests player loop to check prefetched tracks against queue.
+func (p *Player) refreshPrefetch() {
+	select {
+	case p.prefetchUpdated <- true:
+	default:
+	}
+}
+
+// upcomingSongs returns songs that should be downloaded ahead of time.
+func (p *Player) upcomingSongs() []*models.Song {
+	if !p.Audio.hasCurrentSong() || p.sleepAtSongEnd() {
+		return nil
+	}
+	return p.Queue.upcoming(config.AppConfig.Player.PrefetchDepth)
+}
+
+// updatePrefetch closes downloaded tracks that are not upcoming anymore and queues first prefetched track
+// to Audio, if it has no next song.
+func (p *Player) updatePrefetch() {
+	upcoming := p.upcomingSongs()
+	next := p.Audio.nextSong()
+	if next != nil && (len(upcoming) == 0 || upcoming[0] != next) {
+		p.Audio.dropNextSong()
+		next = nil
+	}
+	if next != nil {
+		upcoming = upcoming[1:]
+	}
+
+	valid := matchingSongs(p.prefetched, upcoming)
+	for _, v := range p.prefetched[valid:] {
+		logrus.Debugf("Drop prefetched song %s", v.metadata.song.Name)
+		closeTrack(v)
+	}
+	p.prefetched = p.prefetched[:valid]
+
+	if next == nil && len(p.prefetched) > 0 {
+		queued, err := p.Audio.queueTrack(p.prefetched[0])
+		if err != nil {
+			logrus.Errorf("queue track: %v", err)
+		}
+		if queued {
+			p.prefetched = p.prefetched[1:]
+		}
+	}
+}
+
+// addPrefetched appends downloaded track to prefetched tracks, if it is the next one that is missing.
+// Otherwise queue has changed during download and track is closed.
+func (p *Player) addPrefetched(track *audioTrack) {
+	p.updatePrefetch()
+	upcoming := p.upcomingSongs()
+	ready := p.prefetchedCount()
+	if ready >= len(upcoming) || upcoming[ready] != track.metadata.song {
+		logrus.Debugf("Drop downloaded song %s, queue has changed", track.metadata.song.Name)
+		closeTrack(track)
+		return
+	}
+	p.prefetched = append(p.prefetched, track)
+	p.updatePrefetch()
+}
+
+// prefetchedCount returns number of upcoming songs that are already downloaded.
+func (p *Player) prefetchedCount() int {
+	n := len(p.prefetched)
+	if p.Audio.hasNextSong() {
+		n += 1
+	}
+	return n
+}
+
+// prefetch starts downloading next upcoming song that is not downloaded yet, once current song is close to
+// its end. Next song must be ready before crossfade starts. Only one song is downloaded at a time.
+func (p *Player) prefetch() {
+	p.updatePrefetch()
+	upcoming := p.upcomingSongs()
+	ready := p.prefetchedCount()
+	if ready >= len(upcoming) || p.isDownloadingSong() || len(p.songDownloaded) > 0 {
+		return
+	}
+	window := time.Duration(config.AppConfig.Player.PrefetchS+config.AppConfig.Player.CrossfadeS) * time.Second
+	if p.Audio.timeLeft() >= window {
+		return
+	}
+	if p.Queue.SongError(upcoming[ready].Id) != nil {
+		// failed song is only retried when it is about to play
+		return
+	}
+	go p.downloadSong(p.Queue.nextIndex() + ready)
+}
+
+// closePrefetched closes all prefetched tracks.
+func (p *Player) closePrefetched() {
+	for _, v := range p.prefetched {
+		closeTrack(v)
+	}
+	p.prefetched = nil
+}
+
+// matchingSongs returns number of tracks in beginning of tracks that match songs.
+func matchingSongs(tracks []*audioTrack, songs []*models.Song) int {
+	for i, v := range tracks {
+		if i >= len(songs) || v.metadata.song != songs[i] {
+			return i
+		}
+	}
+	return len(tracks)
+}
+
+func closeTrack(track *audioTrack) {
+	err := track.Close()
+	if err != nil {
+		logrus.Errorf("close track %s: %v", track.metadata.song.Name, err)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"testing"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+func TestPlayer_updatePrefetch(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	config.AppConfig.Player.PrefetchDepth = 3
+	songs := testSongs()[:5]
+	newTrack := func(song *models.Song) *audioTrack {
+		metadata := songMetadata{
+			song:   song,
+			reader: &testReader{bytes.NewReader(testWav(20000))},
+			format: interfaces.AudioFormatWav,
+		}
+		track, err := newAudioTrack(metadata, 0, &decodeMetrics{})
+		if err != nil {
+			t.Fatalf("init track: %v", err)
+		}
+		return track
+	}
+
+	tests := []struct {
+		name           string
+		change         func(q *Queue)
+		wantNext       *models.Song
+		wantPrefetched []*models.Song
+	}{
+		{
+			name:           "unchanged",
+			change:         func(q *Queue) {},
+			wantNext:       songs[1],
+			wantPrefetched: []*models.Song{songs[2], songs[3]},
+		},
+		{
+			name:           "reorder prefetched",
+			change:         func(q *Queue) { q.Reorder(2, false) },
+			wantNext:       songs[1],
+			wantPrefetched: []*models.Song{},
+		},
+		{
+			name:           "remove next",
+			change:         func(q *Queue) { q.RemoveSong(1) },
+			wantNext:       songs[2],
+			wantPrefetched: []*models.Song{songs[3]},
+		},
+		{
+			name:           "play next",
+			change:         func(q *Queue) { q.PlayNext(songs[4:]) },
+			wantPrefetched: []*models.Song{},
+		},
+		{
+			name:           "clear",
+			change:         func(q *Queue) { q.ClearQueue(false) },
+			wantPrefetched: []*models.Song{},
+		},
+		{
+			name:           "repeat one",
+			change:         func(q *Queue) { q.SetRepeat(interfaces.RepeatModeOne) },
+			wantPrefetched: []*models.Song{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Player{
+				lock:  &sync.RWMutex{},
+				Audio: newAudio(newStreamOutput(nil, false)),
+				Queue: newQueue(),
+			}
+			p.Queue.AddSongs(songs)
+			p.Audio.current = newTrack(songs[0])
+			p.Audio.next = newTrack(songs[1])
+			p.prefetched = []*audioTrack{newTrack(songs[2]), newTrack(songs[3])}
+
+			tt.change(p.Queue)
+			p.updatePrefetch()
+			if got := p.Audio.nextSong(); got != tt.wantNext {
+				t.Errorf("next song, got: %v, want: %v", got, tt.wantNext)
+			}
+			prefetched := []*models.Song{}
+			for _, v := range p.prefetched {
+				prefetched = append(prefetched, v.metadata.song)
+			}
+			logDiff(t, tt.wantPrefetched, prefetched, "prefetched")
+		})
+	}
+}
+
+func TestAudio_playNextTrack(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	songs := testSongs()[:3]
+	newTrack := func(song *models.Song) *audioTrack {
+		metadata := songMetadata{
+			song:   song,
+			reader: &testReader{bytes.NewReader(testWav(20000))},
+			format: interfaces.AudioFormatWav,
+		}
+		track, err := newAudioTrack(metadata, 0, &decodeMetrics{})
+		if err != nil {
+			t.Fatalf("init track: %v", err)
+		}
+		return track
+	}
+
+	audio := newAudio(newStreamOutput(nil, false))
+	audio.current = newTrack(songs[0])
+	audio.next = newTrack(songs[1])
+	audio.ctrl.Paused = true
+
+	played, err := audio.playNextTrack(songs[2])
+	if err != nil {
+		t.Errorf("play next track: %v", err)
+	}
+	if played || audio.nextSong() != songs[1] {
+		t.Errorf("other song must not replace next track")
+	}
+
+	played, err = audio.playNextTrack(songs[1])
+	if err != nil {
+		t.Errorf("play next track: %v", err)
+	}
+	if !played {
+		t.Errorf("expected next track to play")
+	}
+	if audio.current == nil || audio.current.metadata.song != songs[1] {
+		t.Errorf("current song, got: %v, want: %v", audio.current, songs[1])
+	}
+	if audio.nextSong() != nil {
+		t.Errorf("next song must be cleared, got: %v", audio.nextSong())
+	}
+	if audio.ctrl.Paused {
+		t.Errorf("expected audio to continue")
+	}
+}
@@ -28,6 +28,9 @@ import (
 	"tryffel.net/go/jellycli/models"
 )
 
+// queueUndoLimit is number of queue edits that can be undone
+const queueUndoLimit = 50
+
 // queueItem: song + original index + random index for shuffling
 type queueItem struct {
 	song *models.Song
@@ -35,7 +38,7 @@ type queueItem struct {
 	// index is original priority, which is len(queue) at insertion time.
 	index int
 
-	// priority is random number between 0-len(queue).
+	// priority is position in shuffled queue.
 	priority int
 }
 
@@ -48,6 +51,8 @@ type queueList struct {
 
 	// is shuffling enabled
 	shuffle bool
+	// shuffleMode is used to order items when shuffling
+	shuffleMode interfaces.ShuffleMode
 }
 
 func (q *queueList) Less(i, j int) bool {
@@ -81,16 +86,90 @@ func (q *queueList) SetShuffling(enable bool) {
 	}
 	q.shuffle = enable
 
-	if enable && len(q.items) > 0 {
-		// make sure 1st stays 1st after shuffling
-		q.items[0].priority = 0
-		for _, v := range q.items[1:] {
-			v.priority = rand.Int()
-		}
+	if enable {
+		q.shuffleItems()
 	}
 	sort.Sort(q)
 }
 
+// SetShuffleMode sets how items are shuffled. If shuffling is enabled, items are shuffled again.
+func (q *queueList) SetShuffleMode(mode interfaces.ShuffleMode) {
+	q.shuffleMode = mode
+	if q.shuffle {
+		q.shuffleItems()
+		sort.Sort(q)
+	}
+}
+
+// shuffleItems sets item priorities in order of shuffle mode.
+func (q *queueList) shuffleItems() {
+	if len(q.items) == 0 {
+		return
+	}
+	// make sure 1st stays 1st after shuffling
+	q.items[0].priority = 0
+	for i, v := range shuffleItems(q.shuffleMode, q.items[1:]) {
+		v.priority = i + 1
+	}
+}
+
+// queueSnapshot is state of queueList, which is restored when undoing edits.
+type queueSnapshot struct {
+	items    []queueItem
+	maxIndex int
+	shuffle  bool
+}
+
+func (q *queueList) snapshot() queueSnapshot {
+	s := queueSnapshot{
+		items:    make([]queueItem, len(q.items)),
+		maxIndex: q.maxIndex,
+		shuffle:  q.shuffle,
+	}
+	for i, v := range q.items {
+		s.items[i] = *v
+	}
+	return s
+}
+
+// restoreSnapshot sets list to snapshot. First item is the song that is playing, and it stays first:
+// songs before it in snapshot have been played since snapshot was taken and are dropped, and if snapshot
+// does not contain the song, it is kept in front of snapshot items.
+func (q *queueList) restoreSnapshot(s queueSnapshot) {
+	items := make([]*queueItem, len(s.items))
+	for i := range s.items {
+		item := s.items[i]
+		items[i] = &item
+	}
+
+	if len(q.items) > 0 {
+		current := q.items[0]
+		found := false
+		for i, v := range items {
+			if v.song == current.song {
+				items = items[i:]
+				found = true
+				break
+			}
+		}
+		if !found {
+			// sort current item before others, regardless of shuffling
+			for _, v := range items {
+				if v.index <= current.index {
+					current.index = v.index - 1
+				}
+				if v.priority <= current.priority {
+					current.priority = v.priority - 1
+				}
+			}
+			items = append([]*queueItem{current}, items...)
+		}
+	}
+	q.items = items
+	q.maxIndex = s.maxIndex
+	q.shuffle = s.shuffle
+}
+
 // Clear. First: whether to clear first item too
 func (q *queueList) Clear(first bool) {
 	if q.Len() == 0 {
@@ -106,7 +185,11 @@ func (q *queueList) Clear(first bool) {
 
 func (q *queueList) AddSong(song *models.Song, playNext bool, playFirst bool) {
 	index := q.maxIndex
-	priority := rand.Int()
+	priority := 0
+	if len(q.items) > 0 {
+		// when shuffling, new item is played last
+		priority = q.items[len(q.items)-1].priority + 1
+	}
 	needsSort := false
 
 	if len(q.items) == 0 {
@@ -199,6 +282,35 @@ func (q *queueList) Reorder(index1 int, down bool) {
 	}
 }
 
+// ReorderRange shifts items in start...end by one like Reorder. Item next to range moves to the other side of it.
+func (q *queueList) ReorderRange(start, end int, down bool) {
+	first, last := start, end+1
+	if down {
+		first, last = start-1, end
+	}
+	if q.shuffle || start > end || first < 0 || last >= len(q.items) {
+		return
+	}
+
+	// items are in order of index, so rotate items and keep indices in place
+	indices := make([]int, 0, last-first+1)
+	for _, v := range q.items[first : last+1] {
+		indices = append(indices, v.index)
+	}
+	items := make([]*queueItem, 0, len(indices))
+	if down {
+		items = append(items, q.items[first+1:last+1]...)
+		items = append(items, q.items[first])
+	} else {
+		items = append(items, q.items[last])
+		items = append(items, q.items[first:last]...)
+	}
+	for i, v := range items {
+		v.index = indices[i]
+		q.items[first+i] = v
+	}
+}
+
 // Queue implements interfaces.QueueController
 type Queue struct {
 	lock               sync.RWMutex
@@ -206,6 +318,17 @@ type Queue struct {
 	history            []*models.Song
 	queueUpdatedFunc   []func([]*models.Song)
 	historyUpdatedFunc func([]*models.Song)
+
+	repeat interfaces.RepeatMode
+	// played is number of songs in history that were played from current queue.
+	// With RepeatModeAll these songs are queued again when queue is complete.
+	played int
+	// songErrors has reason for each song that could not be played, until it is played successfully
+	songErrors map[models.Id]error
+
+	// undo has queue states before latest edits and redo states before latest undos, latest last.
+	undo []queueSnapshot
+	redo []queueSnapshot
 }
 
 func newQueue() *Queue {
@@ -213,10 +336,34 @@ func newQueue() *Queue {
 		list:             newQueueList(),
 		history:          []*models.Song{},
 		queueUpdatedFunc: make([]func([]*models.Song), 0),
+		songErrors:       map[models.Id]error{},
 	}
 	return q
 }
 
+// SongError returns reason why song could not be played, or nil if song has not failed.
+func (q *Queue) SongError(id models.Id) error {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return q.songErrors[id]
+}
+
+// setSongError marks song failed or, if err is nil, clears it. Queue callbacks are notified if song's
+// status changed, so that they can show it.
+func (q *Queue) setSongError(id models.Id, err error) {
+	q.lock.Lock()
+	_, failed := q.songErrors[id]
+	if err == nil {
+		delete(q.songErrors, id)
+	} else {
+		q.songErrors[id] = err
+	}
+	if failed != (err != nil) {
+		q.notifyQueueUpdated()
+	}
+	q.lock.Unlock()
+}
+
 // GetQueue gets currently ongoing queue of items with complete info for each song.
 func (q *Queue) GetQueue() []*models.Song {
 	q.lock.RLock()
@@ -229,7 +376,11 @@ func (q *Queue) ClearQueue(first bool) {
 	q.lock.Lock()
 	defer q.lock.Unlock()
 	defer q.notifyQueueUpdated()
+	if q.list.Len() > 1 || (first && q.list.Len() == 1) {
+		q.saveUndo()
+	}
 	q.list.Clear(first)
+	q.played = 0
 }
 
 // AddSongs adds songs to the end of queue.
@@ -239,6 +390,9 @@ func (q *Queue) AddSongs(songs []*models.Song) {
 	defer q.lock.Unlock()
 	defer q.notifyQueueUpdated()
 
+	if len(songs) > 0 {
+		q.saveUndo()
+	}
 	for _, v := range songs {
 		q.list.AddSong(v, false, false)
 	}
@@ -248,6 +402,9 @@ func (q *Queue) AddSongs(songs []*models.Song) {
 
 func (q *Queue) PlayNext(songs []*models.Song) {
 	q.lock.Lock()
+	if len(songs) > 0 {
+		q.saveUndo()
+	}
 	for i := len(songs); i > 0; i-- {
 		q.list.AddSong(songs[i-1], true, false)
 	}
@@ -261,6 +418,7 @@ func (q *Queue) RemoveSong(index int) {
 	if index == 0 {
 		// if we remove first, we must notify player to move to next song
 	} else {
+		q.saveUndo()
 		q.list.RemoveSong(index)
 		changed = true
 	}
@@ -270,6 +428,31 @@ func (q *Queue) RemoveSong(index int) {
 	}
 }
 
+// RemoveSongs removes songs in given indices. First song cannot be removed.
+func (q *Queue) RemoveSongs(indices []int) {
+	remove := make([]int, 0, len(indices))
+	q.lock.Lock()
+	for _, v := range indices {
+		if v > 0 && v < q.list.Len() {
+			remove = append(remove, v)
+		}
+	}
+	if len(remove) > 0 {
+		q.saveUndo()
+		// remove from the end so that remaining indices stay valid
+		sort.Sort(sort.Reverse(sort.IntSlice(remove)))
+		for i, v := range remove {
+			if i == 0 || v != remove[i-1] {
+				q.list.RemoveSong(v)
+			}
+		}
+	}
+	q.lock.Unlock()
+	if len(remove) > 0 {
+		q.notifyQueueUpdated()
+	}
+}
+
 // Reorder sets item in index currentIndex to newIndex.
 // If either currentIndex or NewIndex is not valid, do nothing.
 // On successful order QueueChangedCallback gets called.
@@ -286,9 +469,13 @@ func (q *Queue) Reorder(index int, down bool) bool {
 		// illegal index
 	} else if index >= 0 && index < heapLen-2 && !down {
 		changed = true
-		q.list.Reorder(index, down)
 	} else if index >= 1 && down {
 		changed = true
+	}
+	if changed {
+		if !q.list.shuffle {
+			q.saveUndo()
+		}
 		q.list.Reorder(index, down)
 	}
 
@@ -300,6 +487,32 @@ func (q *Queue) Reorder(index int, down bool) bool {
 	return changed
 }
 
+// ReorderRange shifts songs in indices start...end (inclusive) by one like Reorder, keeping their order.
+// Returns true if reorder was made.
+func (q *Queue) ReorderRange(start, end int, down bool) bool {
+	q.lock.Lock()
+	changed := false
+
+	heapLen := q.list.Len()
+	if q.list.shuffle || start < 0 || start > end || end > heapLen-1 {
+		// illegal range
+	} else if down && start >= 1 {
+		changed = true
+	} else if !down && end < heapLen-1 {
+		changed = true
+	}
+	if changed {
+		q.saveUndo()
+		q.list.ReorderRange(start, end, down)
+	}
+
+	q.lock.Unlock()
+	if changed {
+		q.notifyQueueUpdated()
+	}
+	return changed
+}
+
 // GetHistory get's n past songs that has been played.
 func (q *Queue) GetHistory(n int) []*models.Song {
 	q.lock.RLock()
@@ -337,24 +550,59 @@ func (q *Queue) notifyHistoryUpdated() {
 	}
 }
 
-// remove first song from queue and move to history
+// remove first song from queue and move to history. With RepeatModeOne song is kept in queue.
+// With RepeatModeAll, songs that were played from queue are queued again from history once queue is complete.
 func (q *Queue) songComplete() {
+	q.completeSong(false)
+}
+
+// skipSong moves first song to history, ignoring RepeatModeOne.
+func (q *Queue) skipSong() {
+	q.completeSong(true)
+}
+
+func (q *Queue) completeSong(skip bool) {
 	q.lock.Lock()
-	defer q.notifyQueueUpdated()
-	defer q.notifyHistoryUpdated()
 	if q.list.Len() == 0 {
+		q.lock.Unlock()
 		return
 	}
+	defer q.notifyQueueUpdated()
+	defer q.notifyHistoryUpdated()
 
-	song := q.list.RemoveSong(0)
+	var song *models.Song
+	if q.repeat == interfaces.RepeatModeOne && !skip {
+		song = q.list.items[0].song
+	} else {
+		song = q.list.RemoveSong(0)
+		q.played += 1
+	}
 	if q.history == nil {
 		q.history = []*models.Song{song}
 	} else {
 		q.history = append([]*models.Song{song}, q.history...)
 	}
+
+	if q.list.Len() == 0 {
+		if q.repeat == interfaces.RepeatModeAll {
+			q.repeatPlayed()
+		}
+		q.played = 0
+	}
 	q.lock.Unlock()
 }
 
+// add played songs back to queue in the order they were played. Caller must hold lock.
+func (q *Queue) repeatPlayed() {
+	if q.played > len(q.history) {
+		q.played = len(q.history)
+	}
+	logrus.Debugf("Repeat queue, %d songs", q.played)
+	for i := q.played - 1; i >= 0; i-- {
+		q.list.AddSong(q.history[i], false, false)
+	}
+}
+
 // remove first item from history and move to queue
 func (q *Queue) playLastSong() {
 	q.lock.Lock()
@@ -371,9 +619,54 @@ func (q *Queue) playLastSong() {
 	} else {
 		q.history = q.history[1:]
 	}
+	if q.played > 0 {
+		q.played -= 1
+	}
 	q.lock.Unlock()
 }
 
+// nextIndex returns index of the song that is played after current one, or -1 if there is none.
+func (q *Queue) nextIndex() int {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	if q.list.Len() == 0 {
+		return -1
+	}
+	if q.repeat == interfaces.RepeatModeOne {
+		return 0
+	}
+	if q.list.Len() >= 2 {
+		return 1
+	}
+	return -1
+}
+
+// upcoming returns at most n songs that play after current song, in order. First song is in queue index
+// nextIndex().
+func (q *Queue) upcoming(n int) []*models.Song {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	songs := q.list.GetQueue()
+	if len(songs) == 0 || n <= 0 {
+		return nil
+	}
+	if q.repeat == interfaces.RepeatModeOne {
+		return songs[:1]
+	}
+	songs = songs[1:]
+	if len(songs) > n {
+		songs = songs[:n]
+	}
+	return songs
+}
+
+// SetRepeat sets repeat mode
+func (q *Queue) SetRepeat(mode interfaces.RepeatMode) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.repeat = mode
+}
+
 func (q *Queue) empty() bool {
 	q.lock.RLock()
 	defer q.lock.RUnlock()
@@ -387,11 +680,87 @@ func (q *Queue) SetShuffle(enabled bool) {
 		q.lock.Unlock()
 		return
 	}
+	q.saveUndo()
 	q.list.SetShuffling(enabled)
 	q.lock.Unlock()
 	q.notifyQueueUpdated()
 }
 
+// UndoQueue reverts latest edit to queue: adding, removing or reordering songs, clearing queue or toggling
+// shuffle. Song that is playing is kept first in queue. Returns false if there is nothing to undo.
+func (q *Queue) UndoQueue() bool {
+	return q.undoRedo(&q.undo, &q.redo)
+}
+
+// RedoQueue applies latest edit that was undone. Returns false if there is nothing to redo.
+func (q *Queue) RedoQueue() bool {
+	return q.undoRedo(&q.redo, &q.undo)
+}
+
+// undoRedo restores latest snapshot from stack and saves current state to other stack.
+func (q *Queue) undoRedo(from, to *[]queueSnapshot) bool {
+	q.lock.Lock()
+	if len(*from) == 0 {
+		q.lock.Unlock()
+		return false
+	}
+	snapshot := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, q.list.snapshot())
+	q.list.restoreSnapshot(snapshot)
+	q.lock.Unlock()
+	q.notifyQueueUpdated()
+	return true
+}
+
+// saveUndo saves current state before edit, so that edit can be undone. Edit clears redo stack.
+// Caller must hold lock.
+func (q *Queue) saveUndo() {
+	q.undo = append(q.undo, q.list.snapshot())
+	if len(q.undo) > queueUndoLimit {
+		copy(q.undo, q.undo[1:])
+		q.undo = q.undo[:queueUndoLimit]
+	}
+	q.redo = nil
+}
+
+// shuffleEnabled returns true if queue is shuffled.
+func (q *Queue) shuffleEnabled() bool {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return q.list.shuffle
+}
+
+// SetShuffleMode sets how queue is shuffled. If shuffle is enabled, queue is shuffled again.
+func (q *Queue) SetShuffleMode(mode interfaces.ShuffleMode) {
+	q.lock.Lock()
+	q.list.SetShuffleMode(mode)
+	shuffle := q.list.shuffle
+	q.lock.Unlock()
+	if shuffle {
+		q.notifyQueueUpdated()
+	}
+}
+
+// restore replaces queue and history, e.g. with songs from saved session. Queue keeps given order also when
+// shuffle is enabled, and disabling shuffle later keeps the order too.
+func (q *Queue) restore(songs []*models.Song, history []*models.Song, shuffle bool) {
+	q.lock.Lock()
+	q.list.Clear(true)
+	for _, v := range songs {
+		q.list.AddSong(v, false, false)
+	}
+	for _, v := range q.list.items {
+		v.priority = v.index
+	}
+	q.list.shuffle = shuffle
+	q.history = history
+	q.played = 0
+	q.lock.Unlock()
+	q.notifyHistoryUpdated()
+	q.notifyQueueUpdated()
+}
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
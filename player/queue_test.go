@@ -19,9 +19,11 @@
 package player
 
 import (
+	"errors"
 	"github.com/google/go-cmp/cmp"
 	"reflect"
 	"testing"
+	"tryffel.net/go/jellycli/interfaces"
 	"tryffel.net/go/jellycli/models"
 )
 
@@ -113,6 +115,39 @@ func TestQueue_RemoveSong(t *testing.T) {
 	}
 }
 
+func TestQueue_RemoveSongs(t *testing.T) {
+	songs := testSongs()
+	tests := []struct {
+		name      string
+		indices   []int
+		wantSongs []*models.Song
+	}{
+		{
+			name:      "remove multiple",
+			indices:   []int{1, 3, 4},
+			wantSongs: []*models.Song{songs[0], songs[2], songs[5], songs[6]},
+		},
+		{
+			name:      "unordered and duplicate indices",
+			indices:   []int{5, 2, 5},
+			wantSongs: []*models.Song{songs[0], songs[1], songs[3], songs[4], songs[6]},
+		},
+		{
+			name:      "first song and invalid indices are not removed",
+			indices:   []int{0, -1, 7, 6},
+			wantSongs: songs[:6],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := newQueue()
+			queue.AddSongs(songs[:7])
+			queue.RemoveSongs(tt.indices)
+			logDiff(t, tt.wantSongs, queue.GetQueue(), "songs after removal")
+		})
+	}
+}
+
 func Test_queue_Reorder(t *testing.T) {
 	songs := testSongs()
 
@@ -239,6 +274,80 @@ func Test_queue_Reorder(t *testing.T) {
 	}
 }
 
+func Test_queue_ReorderRange(t *testing.T) {
+	songs := testSongs()[:5]
+	tests := []struct {
+		name       string
+		start, end int
+		down       bool
+		want       bool
+		wantSongs  []*models.Song
+	}{
+		{
+			name:      "move up",
+			start:     2,
+			end:       3,
+			down:      true,
+			want:      true,
+			wantSongs: []*models.Song{songs[0], songs[2], songs[3], songs[1], songs[4]},
+		},
+		{
+			name:      "move down",
+			start:     1,
+			end:       3,
+			down:      false,
+			want:      true,
+			wantSongs: []*models.Song{songs[0], songs[4], songs[1], songs[2], songs[3]},
+		},
+		{
+			name:      "move single song to the end",
+			start:     3,
+			end:       3,
+			down:      false,
+			want:      true,
+			wantSongs: []*models.Song{songs[0], songs[1], songs[2], songs[4], songs[3]},
+		},
+		{
+			name:      "range at the beginning",
+			start:     0,
+			end:       1,
+			down:      true,
+			want:      false,
+			wantSongs: songs,
+		},
+		{
+			name:      "range at the end",
+			start:     3,
+			end:       4,
+			down:      false,
+			want:      false,
+			wantSongs: songs,
+		},
+		{
+			name:      "invalid range",
+			start:     3,
+			end:       2,
+			down:      true,
+			want:      false,
+			wantSongs: songs,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := newQueue()
+			queue.AddSongs(songs)
+			if got := queue.ReorderRange(tt.start, tt.end, tt.down); got != tt.want {
+				t.Errorf("ReorderRange() = %v, want %v", got, tt.want)
+			}
+			logDiff(t, tt.wantSongs, queue.GetQueue(), "songs after reorder")
+
+			// songs added after reorder are queued last
+			queue.AddSongs(songs[:1])
+			logDiff(t, songs[0], queue.GetQueue()[len(songs)], "song added after reorder")
+		})
+	}
+}
+
 func Test_queue_songComplete(t *testing.T) {
 	songs := testSongs()
 	tests := []struct {
@@ -588,6 +697,78 @@ func TestQueue_TestAddSongsDuringShouffle(t *testing.T) {
 	logDiff(t, wantSongs, gotSongs, "reversed shuffle")
 }
 
+func TestQueue_Repeat(t *testing.T) {
+	songs := testSongs()[:3]
+	tests := []struct {
+		name        string
+		repeat      interfaces.RepeatMode
+		complete    int
+		wantQueue   []*models.Song
+		wantHistory []*models.Song
+	}{
+		{
+			name:        "repeat off",
+			repeat:      interfaces.RepeatModeOff,
+			complete:    3,
+			wantQueue:   []*models.Song{},
+			wantHistory: []*models.Song{songs[2], songs[1], songs[0]},
+		},
+		{
+			name:        "repeat one",
+			repeat:      interfaces.RepeatModeOne,
+			complete:    2,
+			wantQueue:   songs,
+			wantHistory: []*models.Song{songs[0], songs[0]},
+		},
+		{
+			name:        "repeat all",
+			repeat:      interfaces.RepeatModeAll,
+			complete:    3,
+			wantQueue:   songs,
+			wantHistory: []*models.Song{songs[2], songs[1], songs[0]},
+		},
+		{
+			name:        "repeat all, second round",
+			repeat:      interfaces.RepeatModeAll,
+			complete:    4,
+			wantQueue:   songs[1:],
+			wantHistory: []*models.Song{songs[0], songs[2], songs[1], songs[0]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQueue()
+			q.SetRepeat(tt.repeat)
+			q.AddSongs(songs)
+			for i := 0; i < tt.complete; i++ {
+				q.songComplete()
+			}
+			logDiff(t, tt.wantQueue, q.GetQueue(), "queue")
+			logDiff(t, tt.wantHistory, q.GetHistory(10), "history")
+		})
+	}
+}
+
+func TestQueue_RepeatSkip(t *testing.T) {
+	songs := testSongs()[:3]
+	q := newQueue()
+	q.SetRepeat(interfaces.RepeatModeOne)
+	q.AddSongs(songs)
+
+	logDiff(t, 0, q.nextIndex(), "repeat one next index")
+	q.skipSong()
+	logDiff(t, songs[1:], q.GetQueue(), "skip song with repeat one")
+
+	q.SetRepeat(interfaces.RepeatModeAll)
+	logDiff(t, 1, q.nextIndex(), "repeat all next index")
+	q.songComplete()
+	q.playLastSong()
+	q.songComplete()
+	q.songComplete()
+	// song-1 was skipped before repeating whole queue, so it is played again
+	logDiff(t, songs, q.GetQueue(), "repeat all after previous song")
+}
+
 func logDiff(t *testing.T, x, y interface{}, msg string) {
 
 	diff := cmp.Diff(x, y)
@@ -651,3 +832,256 @@ func BenchmarkQueue_SetShuffle_10000(b *testing.B) {
 		q.SetShuffle(true)
 	}
 }
+
+func TestQueue_restore(t *testing.T) {
+	songs := testSongs()
+	q := newQueue()
+	q.AddSongs(songs[:2])
+	q.songComplete()
+
+	queue := []*models.Song{songs[5], songs[2], songs[7], songs[3]}
+	history := []*models.Song{songs[8], songs[4]}
+	q.restore(queue, history, true)
+
+	if got := q.GetQueue(); !reflect.DeepEqual(got, queue) {
+		t.Errorf("restored queue, got: %v, want: %v", got, queue)
+	}
+	if got := q.GetHistory(10); !reflect.DeepEqual(got, history) {
+		t.Errorf("restored history, got: %v, want: %v", got, history)
+	}
+
+	// shuffled queue keeps its order
+	q.SetShuffle(false)
+	if got := q.GetQueue(); !reflect.DeepEqual(got, queue) {
+		t.Errorf("disable shuffle, got: %v, want: %v", got, queue)
+	}
+
+	q.songComplete()
+	if got := q.GetQueue(); !reflect.DeepEqual(got, queue[1:]) {
+		t.Errorf("complete song, got: %v, want: %v", got, queue[1:])
+	}
+}
+
+func TestQueue_SongError(t *testing.T) {
+	songs := testSongs()
+	q := newQueue()
+	q.AddSongs(songs[:3])
+	notified := 0
+	q.AddQueueChangedCallback(func([]*models.Song) {
+		notified++
+	})
+
+	failure := errors.New("download failed")
+	q.setSongError(songs[1].Id, failure)
+	if got := q.SongError(songs[1].Id); got != failure {
+		t.Errorf("song error, got: %v, want: %v", got, failure)
+	}
+	if got := q.SongError(songs[0].Id); got != nil {
+		t.Errorf("song error, got: %v, want: nil", got)
+	}
+	q.setSongError(songs[1].Id, errors.New("decode failed"))
+	q.setSongError(songs[1].Id, nil)
+	if got := q.SongError(songs[1].Id); got != nil {
+		t.Errorf("cleared song error, got: %v, want: nil", got)
+	}
+	// only changes between failed and ok are notified
+	if notified != 2 {
+		t.Errorf("queue notified, got: %d times, want: 2", notified)
+	}
+}
+
+func TestQueue_upcoming(t *testing.T) {
+	songs := testSongs()[:4]
+	tests := []struct {
+		name   string
+		songs  []*models.Song
+		repeat interfaces.RepeatMode
+		n      int
+		want   []*models.Song
+	}{
+		{
+			name:  "empty queue",
+			songs: []*models.Song{},
+			n:     2,
+			want:  nil,
+		},
+		{
+			name:  "single song",
+			songs: songs[:1],
+			n:     2,
+			want:  []*models.Song{},
+		},
+		{
+			name:  "limited",
+			songs: songs,
+			n:     2,
+			want:  songs[1:3],
+		},
+		{
+			name:  "rest of queue",
+			songs: songs,
+			n:     5,
+			want:  songs[1:],
+		},
+		{
+			name:   "repeat one",
+			songs:  songs,
+			repeat: interfaces.RepeatModeOne,
+			n:      3,
+			want:   songs[:1],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQueue()
+			q.SetRepeat(tt.repeat)
+			q.AddSongs(tt.songs)
+			logDiff(t, tt.want, q.upcoming(tt.n), "upcoming")
+		})
+	}
+}
+
+func TestQueue_UndoQueue(t *testing.T) {
+	songs := testSongs()
+	tests := []struct {
+		name string
+		// init sets queue before edit
+		init []*models.Song
+		edit func(q *Queue)
+		// played is number of songs completed after edit
+		played int
+		// afterEdit is queue after edit
+		afterEdit []*models.Song
+		// undo is queue after undoing edit
+		undo []*models.Song
+		// redo is queue after redoing edit, if it differs from afterEdit
+		redo []*models.Song
+	}{
+		{
+			name:      "add songs",
+			init:      songs[:2],
+			edit:      func(q *Queue) { q.AddSongs(songs[2:4]) },
+			afterEdit: songs[:4],
+			undo:      songs[:2],
+		},
+		{
+			name:      "play next",
+			init:      songs[:3],
+			edit:      func(q *Queue) { q.PlayNext(songs[5:6]) },
+			afterEdit: []*models.Song{songs[0], songs[5], songs[1], songs[2]},
+			undo:      songs[:3],
+		},
+		{
+			name:      "remove song",
+			init:      songs[:4],
+			edit:      func(q *Queue) { q.RemoveSong(2) },
+			afterEdit: []*models.Song{songs[0], songs[1], songs[3]},
+			undo:      songs[:4],
+		},
+		{
+			name:      "reorder",
+			init:      songs[:4],
+			edit:      func(q *Queue) { q.Reorder(1, false) },
+			afterEdit: []*models.Song{songs[0], songs[2], songs[1], songs[3]},
+			undo:      songs[:4],
+		},
+		{
+			name:      "clear queue leaving first",
+			init:      songs[:4],
+			edit:      func(q *Queue) { q.ClearQueue(false) },
+			afterEdit: songs[:1],
+			undo:      songs[:4],
+		},
+		{
+			name:      "clear whole queue",
+			init:      songs[:4],
+			edit:      func(q *Queue) { q.ClearQueue(true) },
+			afterEdit: []*models.Song{},
+			undo:      songs[:4],
+			// song that is playing is not interrupted
+			redo: songs[:1],
+		},
+		{
+			name: "clear whole queue and play another song",
+			init: songs[:3],
+			edit: func(q *Queue) {
+				q.ClearQueue(true)
+				q.list.AddSong(songs[6], false, false)
+			},
+			afterEdit: songs[6:7],
+			// song that is playing is not interrupted
+			undo: []*models.Song{songs[6], songs[0], songs[1], songs[2]},
+		},
+		{
+			name:      "songs played after edit",
+			init:      songs[:5],
+			edit:      func(q *Queue) { q.RemoveSong(3) },
+			played:    2,
+			afterEdit: []*models.Song{songs[2], songs[4]},
+			// played songs are not queued again
+			undo: songs[2:5],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQueue()
+			q.list.AddSong(tt.init[0], false, false)
+			for _, v := range tt.init[1:] {
+				q.list.AddSong(v, false, false)
+			}
+			tt.edit(q)
+			for i := 0; i < tt.played; i++ {
+				q.songComplete()
+			}
+			logDiff(t, tt.afterEdit, q.GetQueue(), "after edit")
+
+			if !q.UndoQueue() {
+				t.Fatalf("undo returned false")
+			}
+			logDiff(t, tt.undo, q.GetQueue(), "undo")
+			if q.UndoQueue() {
+				t.Errorf("second undo returned true")
+			}
+
+			if !q.RedoQueue() {
+				t.Fatalf("redo returned false")
+			}
+			redo := tt.redo
+			if redo == nil {
+				redo = tt.afterEdit
+			}
+			logDiff(t, redo, q.GetQueue(), "redo")
+			if q.RedoQueue() {
+				t.Errorf("second redo returned true")
+			}
+		})
+	}
+}
+
+func TestQueue_UndoShuffle(t *testing.T) {
+	songs := testSongs()
+	q := newQueue()
+	q.AddSongs(songs)
+	q.SetShuffle(true)
+	shuffled := q.GetQueue()
+	q.RemoveSong(1)
+
+	q.UndoQueue()
+	logDiff(t, shuffled, q.GetQueue(), "undo remove")
+	q.UndoQueue()
+	logDiff(t, songs, q.GetQueue(), "undo shuffle")
+	if q.shuffleEnabled() {
+		t.Errorf("shuffle enabled after undo")
+	}
+	q.RedoQueue()
+	logDiff(t, shuffled, q.GetQueue(), "redo shuffle")
+	if !q.shuffleEnabled() {
+		t.Errorf("shuffle disabled after redo")
+	}
+
+	// new edit clears redo
+	q.AddSongs(songs[:1])
+	if q.RedoQueue() {
+		t.Errorf("redo after new edit returned true")
+	}
+}
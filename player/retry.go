@@ -0,0 +1,45 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// retry calls f until it succeeds, it has been retried given number of times or cancel is closed.
+// Delay before first retry is backoff, and it is doubled for each retry. Returns last error.
+func retry(retries int, backoff time.Duration, cancel <-chan struct{}, f func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= retries {
+			return err
+		}
+		logrus.Debugf("retry in %s (attempt %d/%d)", backoff, attempt+2, retries+1)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-cancel:
+			timer.Stop()
+			logrus.Debug("retry cancelled")
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
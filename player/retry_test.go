@@ -0,0 +1,98 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		retries      int
+		failures     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "success", retries: 2, failures: 0, wantAttempts: 1},
+		{name: "success on retry", retries: 2, failures: 2, wantAttempts: 3},
+		{name: "fail", retries: 2, failures: 5, wantAttempts: 3, wantErr: true},
+		{name: "no retries", retries: 0, failures: 5, wantAttempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			var delays []time.Duration
+			last := time.Now()
+			err := retry(tt.retries, time.Millisecond*10, nil, func() error {
+				now := time.Now()
+				if attempts > 0 {
+					delays = append(delays, now.Sub(last))
+				}
+				last = now
+				attempts++
+				if attempts <= tt.failures {
+					return errors.New("failed")
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error, got: %v, want error: %t", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts, got: %d, want: %d", attempts, tt.wantAttempts)
+			}
+			// backoff doubles
+			want := time.Millisecond * 10
+			for i, v := range delays {
+				if v < want {
+					t.Errorf("delay before retry %d, got: %s, want at least: %s", i+1, v, want)
+				}
+				want *= 2
+			}
+		})
+	}
+}
+
+func TestRetry_Cancel(t *testing.T) {
+	cancel := make(chan struct{})
+	attempts := 0
+	done := make(chan error)
+	go func() {
+		done <- retry(5, time.Hour, cancel, func() error {
+			attempts++
+			return errors.New("failed")
+		})
+	}()
+
+	close(cancel)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("retry was not cancelled")
+	}
+	if attempts != 1 {
+		t.Errorf("attempts, got: %d, want: 1", attempts)
+	}
+}
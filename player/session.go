@@ -0,0 +1,278 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+const (
+	// sessionSaveInterval is how often session is saved during playback
+	sessionSaveInterval = time.Second * 30
+	// sessionHistoryLength limits number of history songs to save
+	sessionHistoryLength = 100
+)
+
+// sessionStore saves player session between runs. Local database implements sessionStore when local cache
+// is enabled, else session is saved to a file.
+type sessionStore interface {
+	SaveSession(session *models.Session) error
+	// GetSession returns saved session, or nil if there is none.
+	GetSession() (*models.Session, error)
+}
+
+// sessionFile saves session as json file.
+type sessionFile struct {
+	file string
+}
+
+func newSessionFile(serverId string) (*sessionFile, error) {
+	err := os.MkdirAll(config.AppConfig.Player.LocalCacheDir, 0760)
+	if err != nil {
+		return nil, fmt.Errorf("create cache dir: %v", err)
+	}
+	return &sessionFile{file: path.Join(config.AppConfig.Player.LocalCacheDir, serverId+"-session.json")}, nil
+}
+
+func (s *sessionFile) SaveSession(session *models.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	// write whole file at once, so that interrupted write does not corrupt old session
+	tmp := s.file + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.file)
+}
+
+func (s *sessionFile) GetSession() (*models.Session, error) {
+	data, err := ioutil.ReadFile(s.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	session := &models.Session{}
+	err = json.Unmarshal(data, session)
+	if err != nil {
+		return nil, fmt.Errorf("parse session file: %v", err)
+	}
+	return session, nil
+}
+
+// initSession loads session from previous run. Session is not saved until it has been resumed or discarded,
+// so closing application before that keeps the session.
+func (p *Player) initSession() error {
+	if p.Items.db != nil {
+		p.sessionStore = p.Items.db
+	} else {
+		store, err := newSessionFile(p.api.GetId())
+		if err != nil {
+			return err
+		}
+		p.sessionStore = store
+	}
+
+	session, err := p.sessionStore.GetSession()
+	if err != nil {
+		return err
+	}
+	if session != nil && len(session.Queue) > 0 {
+		logrus.Infof("Found session from %s with %d songs", session.Saved.Format(time.RFC3339),
+			len(session.Queue))
+		p.savedSession = session
+	}
+	return nil
+}
+
+// SavedSession returns session saved on previous run, if it has not been resumed or discarded yet.
+func (p *Player) SavedSession() (*models.Session, bool) {
+	p.sessionLock.Lock()
+	defer p.sessionLock.Unlock()
+	return p.savedSession, p.savedSession != nil
+}
+
+// DiscardSession discards saved session. It is overwritten on next save.
+func (p *Player) DiscardSession() {
+	p.sessionLock.Lock()
+	defer p.sessionLock.Unlock()
+	if p.savedSession != nil {
+		logrus.Info("Discard saved session")
+	}
+	p.savedSession = nil
+}
+
+// ResumeSession restores queue, history, volume and shuffle from saved session, and loads first song
+// paused at saved position. Songs are looked up from their albums: songs whose id has changed are resolved
+// by their name and position in album, and songs that no longer exist are dropped.
+func (p *Player) ResumeSession() error {
+	p.sessionLock.Lock()
+	session := p.savedSession
+	p.sessionLock.Unlock()
+	if session == nil {
+		return errors.New("no saved session")
+	}
+
+	albums := map[models.Id][]*models.Song{}
+	queue := resolveSessionSongs(session.Queue, p.api.GetAlbumSongs, albums)
+	history := resolveSessionSongs(session.History, p.api.GetAlbumSongs, albums)
+	logrus.Infof("Resume session: %d/%d songs in queue, %d/%d songs in history", len(queue),
+		len(session.Queue), len(history), len(session.History))
+
+	p.Audio.SetVolume(interfaces.AudioVolume(session.Volume))
+	p.Audio.SetShuffle(session.Shuffle)
+	if len(queue) > 0 {
+		p.lock.Lock()
+		p.resumeSong = queue[0]
+		if len(session.Queue) > 0 && session.Queue[0].Matches(queue[0]) {
+			p.resumePosition = session.Position
+		} else {
+			// song that was playing no longer exists
+			p.resumePosition = 0
+		}
+		p.lock.Unlock()
+	}
+
+	p.sessionLock.Lock()
+	p.savedSession = nil
+	p.sessionLock.Unlock()
+	// restoring queue starts download of first song
+	p.Queue.restore(queue, history, session.Shuffle)
+	return nil
+}
+
+// resolveSessionSongs finds saved songs from their albums. Albums are cached to given map.
+func resolveSessionSongs(saved []models.SessionSong, getAlbumSongs func(album models.Id) ([]*models.Song, error),
+	albums map[models.Id][]*models.Song) []*models.Song {
+	songs := make([]*models.Song, 0, len(saved))
+	for _, v := range saved {
+		albumSongs, ok := albums[v.Album]
+		if !ok {
+			var err error
+			albumSongs, err = getAlbumSongs(v.Album)
+			if err != nil {
+				logrus.Warningf("resume session: get album %s songs: %v", v.Album, err)
+			}
+			albums[v.Album] = albumSongs
+		}
+
+		var song *models.Song
+		for _, albumSong := range albumSongs {
+			if v.Matches(albumSong) {
+				song = albumSong
+				break
+			}
+		}
+		if song == nil {
+			logrus.Warningf("resume session: song %s (%s) not found, skip", v.Name, v.Id)
+			continue
+		}
+		if song.Id != v.Id {
+			logrus.Debugf("resume session: song %s id changed from %s to %s", v.Name, v.Id, song.Id)
+		}
+		songs = append(songs, song)
+	}
+	return songs
+}
+
+// takeResumePosition returns position to resume song from, if song was restored from session
+// and it has not been played yet.
+func (p *Player) takeResumePosition(song *models.Song) (time.Duration, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.resumeSong == nil || p.resumeSong != song {
+		return 0, false
+	}
+	p.resumeSong = nil
+	return p.resumePosition, true
+}
+
+// SaveSession saves current queue, history, volume, shuffle and playback position.
+// If saved session from previous run is still pending, do nothing.
+func (p *Player) SaveSession() error {
+	p.sessionLock.Lock()
+	defer p.sessionLock.Unlock()
+	if p.sessionStore == nil || p.savedSession != nil {
+		return nil
+	}
+
+	status := p.Audio.getStatus()
+	queue := p.Queue.GetQueue()
+	history := p.Queue.GetHistory(sessionHistoryLength)
+	session := &models.Session{
+		Queue:   make([]models.SessionSong, len(queue)),
+		History: make([]models.SessionSong, len(history)),
+		Volume:  int(status.Volume),
+		Shuffle: status.Shuffle,
+		Saved:   time.Now(),
+	}
+	for i, v := range queue {
+		session.Queue[i] = models.NewSessionSong(v)
+	}
+	for i, v := range history {
+		session.History[i] = models.NewSessionSong(v)
+	}
+	if status.Song != nil && len(queue) > 0 && status.Song.Id == queue[0].Id {
+		session.Position = time.Duration(status.SongPast.MilliSeconds()) * time.Millisecond
+	}
+
+	p.lastSessionSave = session.Saved
+	err := p.sessionStore.SaveSession(session)
+	if err != nil {
+		return fmt.Errorf("save session: %v", err)
+	}
+	logrus.Debugf("Saved session with %d songs", len(queue))
+	return nil
+}
+
+// saveSessionPeriodically saves session during playback in case application does not shut down cleanly.
+func (p *Player) saveSessionPeriodically() {
+	p.sessionLock.Lock()
+	last := p.lastSessionSave
+	p.sessionLock.Unlock()
+	if time.Since(last) < sessionSaveInterval || p.Audio.getStatus().State != interfaces.AudioStatePlaying {
+		return
+	}
+	err := p.SaveSession()
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
+// closeSession disables saving session. This must be called before stopping audio, so that stopped player
+// does not overwrite the session.
+func (p *Player) closeSession() {
+	p.sessionLock.Lock()
+	defer p.sessionLock.Unlock()
+	p.sessionStore = nil
+}
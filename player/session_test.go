@@ -0,0 +1,96 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+func TestSessionFile(t *testing.T) {
+	config.AppConfig.Player.LocalCacheDir = t.TempDir()
+	store, err := newSessionFile("server-1")
+	if err != nil {
+		t.Fatalf("init session file: %v", err)
+	}
+
+	session, err := store.GetSession()
+	if err != nil || session != nil {
+		t.Errorf("get missing session, got: %v, %v, want: nil, nil", session, err)
+	}
+
+	want := &models.Session{
+		Queue:    []models.SessionSong{{Id: "song-1", Album: "album-1", Name: "song 1", Index: 1, DiscNumber: 1}},
+		History:  []models.SessionSong{{Id: "song-2", Album: "album-1", Name: "song 2", Index: 2, DiscNumber: 1}},
+		Position: time.Second * 95,
+		Volume:   35,
+		Shuffle:  true,
+		Saved:    time.Unix(1600000000, 0).UTC(),
+	}
+	err = store.SaveSession(want)
+	if err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+	got, err := store.GetSession()
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("get session, got: %v, want: %v", got, want)
+	}
+}
+
+func TestResolveSessionSongs(t *testing.T) {
+	albums := map[models.Id][]*models.Song{
+		"album-1": {
+			{Id: "song-1", Album: "album-1", Name: "song 1", Index: 1, DiscNumber: 1},
+			// id changed after rescan
+			{Id: "song-2b", Album: "album-1", Name: "song 2", Index: 2, DiscNumber: 1},
+		},
+	}
+	requests := 0
+	getAlbumSongs := func(album models.Id) ([]*models.Song, error) {
+		requests++
+		songs, ok := albums[album]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return songs, nil
+	}
+
+	saved := []models.SessionSong{
+		{Id: "song-2", Album: "album-1", Name: "song 2", Index: 2, DiscNumber: 1},
+		// removed from server
+		{Id: "song-3", Album: "album-1", Name: "song 3", Index: 3, DiscNumber: 1},
+		{Id: "song-4", Album: "album-2", Name: "song 4", Index: 1, DiscNumber: 1},
+		{Id: "song-1", Album: "album-1", Name: "song 1", Index: 1, DiscNumber: 1},
+	}
+	got := resolveSessionSongs(saved, getAlbumSongs, map[models.Id][]*models.Song{})
+	want := []*models.Song{albums["album-1"][1], albums["album-1"][0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolve songs, got: %v, want: %v", got, want)
+	}
+	if requests != 2 {
+		t.Errorf("album requests, got: %d, want: 2", requests)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+// favoriteShuffleWeight is how much more likely favorite songs are to be played early with weighted shuffle.
+const favoriteShuffleWeight = 3.0
+
+// shuffleItems returns items in random order defined by mode. Given slice is not modified.
+func shuffleItems(mode interfaces.ShuffleMode, items []*queueItem) []*queueItem {
+	switch mode {
+	case interfaces.ShuffleModeAlbum:
+		return shuffleAlbums(items)
+	case interfaces.ShuffleModeArtist:
+		return shuffleArtists(items)
+	case interfaces.ShuffleModeWeighted:
+		return shuffleWeighted(items)
+	default:
+		shuffled := make([]*queueItem, len(items))
+		for i, v := range rand.Perm(len(items)) {
+			shuffled[i] = items[v]
+		}
+		return shuffled
+	}
+}
+
+// shuffleAlbums orders albums randomly. Songs of each album are kept together in track order.
+func shuffleAlbums(items []*queueItem) []*queueItem {
+	var albums [][]*queueItem
+	albumIndex := map[models.Id]int{}
+	for _, v := range items {
+		i, ok := albumIndex[v.song.Album]
+		if !ok {
+			i = len(albums)
+			albumIndex[v.song.Album] = i
+			albums = append(albums, nil)
+		}
+		albums[i] = append(albums[i], v)
+	}
+
+	rand.Shuffle(len(albums), func(i, j int) {
+		albums[i], albums[j] = albums[j], albums[i]
+	})
+	shuffled := make([]*queueItem, 0, len(items))
+	for _, album := range albums {
+		sort.SliceStable(album, func(i, j int) bool {
+			a, b := album[i].song, album[j].song
+			if a.DiscNumber != b.DiscNumber {
+				return a.DiscNumber < b.DiscNumber
+			}
+			if a.Index != b.Index {
+				return a.Index < b.Index
+			}
+			return album[i].index < album[j].index
+		})
+		shuffled = append(shuffled, album...)
+	}
+	return shuffled
+}
+
+// shuffleArtists orders songs randomly and spreads songs of each artist evenly over the queue:
+// songs of artist with n songs are placed 1/n apart, starting from random offset.
+func shuffleArtists(items []*queueItem) []*queueItem {
+	artists := map[models.Id][]*queueItem{}
+	for _, v := range items {
+		artist := songArtist(v.song)
+		artists[artist] = append(artists[artist], v)
+	}
+
+	positions := make(map[*queueItem]float64, len(items))
+	for _, songs := range artists {
+		rand.Shuffle(len(songs), func(i, j int) {
+			songs[i], songs[j] = songs[j], songs[i]
+		})
+		spacing := 1 / float64(len(songs))
+		offset := rand.Float64() * spacing
+		for i, v := range songs {
+			positions[v] = offset + float64(i)*spacing
+		}
+	}
+
+	shuffled := make([]*queueItem, len(items))
+	copy(shuffled, items)
+	sort.Slice(shuffled, func(i, j int) bool {
+		return positions[shuffled[i]] < positions[shuffled[j]]
+	})
+	return shuffled
+}
+
+// songArtist returns primary artist of song.
+func songArtist(song *models.Song) models.Id {
+	if len(song.Artists) > 0 {
+		return song.Artists[0].Id
+	}
+	return song.AlbumArtist
+}
+
+// shuffleWeighted orders songs randomly so that songs with higher weight are more likely to be early in queue.
+func shuffleWeighted(items []*queueItem) []*queueItem {
+	// each song gets exponentially distributed key with rate equal to its weight, which is the same as
+	// picking songs one by one with probability proportional to their weight.
+	keys := make(map[*queueItem]float64, len(items))
+	for _, v := range items {
+		keys[v] = rand.ExpFloat64() / shuffleWeight(v.song)
+	}
+
+	shuffled := make([]*queueItem, len(items))
+	copy(shuffled, items)
+	sort.Slice(shuffled, func(i, j int) bool {
+		return keys[shuffled[i]] < keys[shuffled[j]]
+	})
+	return shuffled
+}
+
+// shuffleWeight returns weight of song in weighted shuffle. Favorite songs have higher weight,
+// and weight decreases as song is played more.
+func shuffleWeight(song *models.Song) float64 {
+	weight := 1.0
+	if song.Favorite {
+		weight *= favoriteShuffleWeight
+	}
+	return weight / (1 + math.Log1p(float64(song.PlayCount)))
+}
@@ -0,0 +1,164 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"fmt"
+	"testing"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+// shuffleTestSongs returns songs from 3 albums, each by different artist and with 4 songs.
+func shuffleTestSongs() []*models.Song {
+	songs := make([]*models.Song, 0, 12)
+	for album := 1; album <= 3; album++ {
+		for track := 1; track <= 4; track++ {
+			songs = append(songs, &models.Song{
+				Id:         models.Id(fmt.Sprintf("song-%d-%d", album, track)),
+				Album:      models.Id(fmt.Sprintf("album-%d", album)),
+				Index:      track,
+				DiscNumber: 1,
+				Artists:    []models.IdName{{Id: models.Id(fmt.Sprintf("artist-%d", album))}},
+			})
+		}
+	}
+	return songs
+}
+
+func TestQueue_SetShuffleMode(t *testing.T) {
+	modes := []interfaces.ShuffleMode{interfaces.ShuffleModeRandom, interfaces.ShuffleModeAlbum,
+		interfaces.ShuffleModeArtist, interfaces.ShuffleModeWeighted}
+	for _, mode := range modes {
+		t.Run(mode.String(), func(t *testing.T) {
+			songs := shuffleTestSongs()
+			q := newQueue()
+			q.AddSongs(songs)
+			q.SetShuffleMode(mode)
+			q.SetShuffle(true)
+
+			shuffled := q.GetQueue()
+			if len(shuffled) != len(songs) {
+				t.Fatalf("shuffled queue length, got: %d, want: %d", len(shuffled), len(songs))
+			}
+			if shuffled[0] != songs[0] {
+				t.Errorf("first song changed after shuffle: %s", shuffled[0].Id)
+			}
+			found := map[models.Id]bool{}
+			for _, v := range shuffled {
+				if found[v.Id] {
+					t.Errorf("duplicate song in shuffled queue: %s", v.Id)
+				}
+				found[v.Id] = true
+			}
+
+			switch mode {
+			case interfaces.ShuffleModeAlbum:
+				checkAlbumShuffle(t, shuffled[1:])
+			case interfaces.ShuffleModeArtist:
+				for i := 2; i < len(shuffled); i++ {
+					if songArtist(shuffled[i]) == songArtist(shuffled[i-1]) {
+						t.Errorf("songs of same artist next to each other: %s, %s", shuffled[i-1].Id, shuffled[i].Id)
+					}
+				}
+			}
+
+			q.SetShuffle(false)
+			got := q.GetQueue()
+			for i := range songs {
+				if got[i] != songs[i] {
+					t.Errorf("disable shuffle, got: %s at index %d, want: %s", got[i].Id, i, songs[i].Id)
+				}
+			}
+		})
+	}
+}
+
+// checkAlbumShuffle checks that songs of each album are together in track order.
+func checkAlbumShuffle(t *testing.T, songs []*models.Song) {
+	done := map[models.Id]bool{}
+	for i, v := range songs {
+		if i == 0 || songs[i-1].Album != v.Album {
+			if done[v.Album] {
+				t.Errorf("album %s is split in shuffled queue", v.Album)
+			}
+			done[v.Album] = true
+		} else if songs[i-1].Index >= v.Index {
+			t.Errorf("album %s not in track order: track %d before %d", v.Album, songs[i-1].Index, v.Index)
+		}
+	}
+}
+
+func TestShuffleWeighted(t *testing.T) {
+	tests := []struct {
+		name string
+		// preferred song should be first more often
+		preferred *models.Song
+		other     *models.Song
+		// minimum share of runs where preferred is first
+		minShare float64
+	}{
+		{
+			// expected share is 0.75
+			name:      "favorite",
+			preferred: &models.Song{Id: "favorite", Favorite: true},
+			other:     &models.Song{Id: "other"},
+			minShare:  0.68,
+		},
+		{
+			// expected share is 0.85
+			name:      "rarely played",
+			preferred: &models.Song{Id: "rarely played", PlayCount: 1},
+			other:     &models.Song{Id: "played often", PlayCount: 200},
+			minShare:  0.7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := 2000
+			first := 0
+			for i := 0; i < runs; i++ {
+				items := []*queueItem{{song: tt.other}, {song: tt.preferred}}
+				if shuffleItems(interfaces.ShuffleModeWeighted, items)[0].song == tt.preferred {
+					first++
+				}
+			}
+			share := float64(first) / float64(runs)
+			if share < tt.minShare {
+				t.Errorf("preferred song first in %.2f of runs, want at least %.2f", share, tt.minShare)
+			}
+		})
+	}
+}
+
+func TestParseShuffleMode(t *testing.T) {
+	for _, v := range []string{"random", "Album", " ARTIST ", "weighted"} {
+		mode, err := interfaces.ParseShuffleMode(v)
+		if err != nil {
+			t.Errorf("parse shuffle mode '%s': %v", v, err)
+			continue
+		}
+		if mode.Next() == mode {
+			t.Errorf("next shuffle mode of %s is the same mode", mode)
+		}
+	}
+	if _, err := interfaces.ParseShuffleMode("invalid"); err == nil {
+		t.Errorf("parse invalid shuffle mode, want error")
+	}
+}
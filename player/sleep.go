@@ -0,0 +1,133 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/sirupsen/logrus"
+	"time"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/interfaces"
+)
+
+// SetSleepTimer sets sleep timer. Duration is used only with interfaces.SleepModeTimer.
+// Setting timer cancels any ongoing fade.
+func (p *Player) SetSleepTimer(mode interfaces.SleepMode, duration time.Duration) {
+	timer := interfaces.SleepTimer{Mode: mode}
+	if mode == interfaces.SleepModeTimer {
+		if duration <= 0 {
+			timer.Mode = interfaces.SleepModeOff
+		} else {
+			timer.Duration = duration
+			timer.At = time.Now().Add(duration)
+		}
+	}
+	if timer.Mode == interfaces.SleepModeTimer {
+		logrus.Infof("Set sleep timer: %s", duration)
+	} else {
+		logrus.Infof("Set sleep timer: %s", timer.Mode)
+	}
+
+	p.lock.Lock()
+	p.sleep = timer
+	p.sleepFading = false
+	p.lock.Unlock()
+
+	p.Audio.resetFade()
+	if p.sleepAtSongEnd() {
+		// playback stops after current song
+		p.Audio.dropNextSong()
+	}
+	p.Audio.setSleepTimer(timer)
+}
+
+func (p *Player) getSleepTimer() interfaces.SleepTimer {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.sleep
+}
+
+// sleepAtSongEnd returns true if sleep timer stops playback when current song completes.
+func (p *Player) sleepAtSongEnd() bool {
+	switch p.getSleepTimer().Mode {
+	case interfaces.SleepModeEndOfTrack:
+		return true
+	case interfaces.SleepModeEndOfAlbum:
+		return p.lastSongOfAlbum()
+	default:
+		return false
+	}
+}
+
+// lastSongOfAlbum returns true if next song in queue is not from the same album as current song.
+func (p *Player) lastSongOfAlbum() bool {
+	songs := p.Queue.GetQueue()
+	next := p.Queue.nextIndex()
+	if len(songs) == 0 || next < 0 || next >= len(songs) {
+		return true
+	}
+	return songs[next].Album != songs[0].Album
+}
+
+// sleepTimeLeft returns how long there is until sleep timer fires. If timer is disabled or it does not fire
+// during current song, return false.
+func (p *Player) sleepTimeLeft() (time.Duration, bool) {
+	timer := p.getSleepTimer()
+	switch timer.Mode {
+	case interfaces.SleepModeTimer:
+		return time.Until(timer.At), true
+	case interfaces.SleepModeEndOfTrack, interfaces.SleepModeEndOfAlbum:
+		if p.sleepAtSongEnd() {
+			return p.Audio.timeLeft(), true
+		}
+	}
+	return 0, false
+}
+
+// checkSleepTimer starts fading audio out when sleep timer is about to fire, and fires timer
+// once it has elapsed. Timers for end of track and album are fired when song completes.
+func (p *Player) checkSleepTimer() {
+	left, ok := p.sleepTimeLeft()
+	if !ok {
+		return
+	}
+	if p.getSleepTimer().Mode == interfaces.SleepModeTimer && left <= 0 {
+		p.sleepTimerFired()
+		return
+	}
+
+	playing := p.getStatus().State == interfaces.AudioStatePlaying
+	fade := time.Duration(config.AppConfig.Player.SleepFadeS) * time.Second
+	p.lock.Lock()
+	startFade := !p.sleepFading && playing && left <= fade
+	if startFade {
+		p.sleepFading = true
+	}
+	p.lock.Unlock()
+	if startFade {
+		p.Audio.fadeOut(left)
+	}
+}
+
+// sleepTimerFired stops playback and disables sleep timer. Fade is reset only after playback has stopped,
+// so next song plays with the same volume as before the fade.
+func (p *Player) sleepTimerFired() {
+	logrus.Info("Sleep timer fired, stop playback")
+	p.Audio.StopMedia()
+	p.SetSleepTimer(interfaces.SleepModeOff, 0)
+}
@@ -0,0 +1,95 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/sirupsen/logrus"
+	"sync"
+	"testing"
+	"time"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+func TestPlayer_sleepAtSongEnd(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	songs := testSongs()[:3]
+	songs[0].Album = "album-1"
+	songs[1].Album = "album-1"
+	songs[2].Album = "album-2"
+
+	tests := []struct {
+		name     string
+		mode     interfaces.SleepMode
+		complete int
+		want     bool
+	}{
+		{name: "off", mode: interfaces.SleepModeOff, want: false},
+		{name: "timer", mode: interfaces.SleepModeTimer, want: false},
+		{name: "end of track", mode: interfaces.SleepModeEndOfTrack, want: true},
+		{name: "end of album, album continues", mode: interfaces.SleepModeEndOfAlbum, want: false},
+		{name: "end of album, last song of album", mode: interfaces.SleepModeEndOfAlbum, complete: 1, want: true},
+		{name: "end of album, last song in queue", mode: interfaces.SleepModeEndOfAlbum, complete: 2, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Player{
+				lock:  &sync.RWMutex{},
+				Audio: newAudio(newStreamOutput(nil, false)),
+				Queue: newQueue(),
+			}
+			p.Queue.AddSongs(songs)
+			for i := 0; i < tt.complete; i++ {
+				p.Queue.songComplete()
+			}
+			p.SetSleepTimer(tt.mode, time.Minute)
+			if got := p.sleepAtSongEnd(); got != tt.want {
+				t.Errorf("sleepAtSongEnd, got: %t, want: %t", got, tt.want)
+			}
+			if got := p.getStatus().Sleep.Mode; got != tt.mode {
+				t.Errorf("status sleep mode, got: %s, want: %s", got, tt.mode)
+			}
+		})
+	}
+}
+
+func TestPlayer_sleepTimeLeft(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	p := &Player{
+		lock:  &sync.RWMutex{},
+		Audio: newAudio(newStreamOutput(nil, false)),
+		Queue: newQueue(),
+	}
+	p.Queue.AddSongs([]*models.Song{{Id: "song-1", Album: "album-1"}})
+
+	if _, ok := p.sleepTimeLeft(); ok {
+		t.Errorf("sleep timer is off, expect no time left")
+	}
+
+	p.SetSleepTimer(interfaces.SleepModeTimer, time.Minute*10)
+	left, ok := p.sleepTimeLeft()
+	if !ok || left <= time.Minute*9 || left > time.Minute*10 {
+		t.Errorf("sleep timer, got: %s, want: 10m", left)
+	}
+
+	p.SetSleepTimer(interfaces.SleepModeTimer, 0)
+	if mode := p.getSleepTimer().Mode; mode != interfaces.SleepModeOff {
+		t.Errorf("zero timer, got mode: %s, want: off", mode)
+	}
+}
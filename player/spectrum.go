@@ -0,0 +1,177 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/faiface/beep"
+	"math"
+	"math/cmplx"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// spectrumWindow is number of samples analyzed for spectrum, must be power of two
+	spectrumWindow = 2048
+	// spectrumMinFrequency and spectrumMaxFrequency limit frequencies shown in spectrum
+	spectrumMinFrequency = 50
+	spectrumMaxFrequency = 16000
+	// spectrumRange is dynamic range of spectrum in dB, levels below -spectrumRange dBFS are shown as 0
+	spectrumRange = 60
+)
+
+// spectrumTap passes audio through as is and copies it to a ring buffer for spectrum analyzer.
+// Tap never blocks audio: samples are written with atomic operations, and reader may see samples
+// from two consecutive writes, which is fine for visualization.
+type spectrumTap struct {
+	// position is total number of samples written. Keep it first in struct for 64-bit alignment on 32-bit platforms.
+	position uint64
+	enabled  int32
+	// buffer contains mono samples as float32 bits
+	buffer [spectrumWindow]uint32
+
+	Streamer beep.Streamer
+
+	// analyzer is used by reader only
+	analyzerLock sync.Mutex
+	samples      []complex128
+	window       []float64
+	windowSum    float64
+}
+
+func newSpectrumTap() *spectrumTap {
+	s := &spectrumTap{
+		samples: make([]complex128, spectrumWindow),
+		window:  make([]float64, spectrumWindow),
+	}
+	for i := range s.window {
+		s.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/spectrumWindow)
+		s.windowSum += s.window[i]
+	}
+	return s
+}
+
+func (s *spectrumTap) Stream(samples [][2]float64) (n int, ok bool) {
+	n, ok = s.Streamer.Stream(samples)
+	if atomic.LoadInt32(&s.enabled) == 0 {
+		return n, ok
+	}
+	// tap is the only writer
+	position := atomic.LoadUint64(&s.position)
+	for i := range samples[:n] {
+		mono := float32((samples[i][0] + samples[i][1]) / 2)
+		atomic.StoreUint32(&s.buffer[(position+uint64(i))%spectrumWindow], math.Float32bits(mono))
+	}
+	atomic.AddUint64(&s.position, uint64(n))
+	return n, ok
+}
+
+func (s *spectrumTap) Err() error {
+	return s.Streamer.Err()
+}
+
+// setEnabled enables copying audio. When disabled, buffer is cleared.
+func (s *spectrumTap) setEnabled(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&s.enabled, 1)
+		return
+	}
+	atomic.StoreInt32(&s.enabled, 0)
+	for i := range s.buffer {
+		atomic.StoreUint32(&s.buffer[i], 0)
+	}
+}
+
+// spectrum fills levels with latest audio level of each frequency band, in range [0, 1].
+// Bands are spaced logarithmically between spectrumMinFrequency and spectrumMaxFrequency.
+func (s *spectrumTap) spectrum(levels []float64, sampleRate beep.SampleRate) {
+	if len(levels) == 0 {
+		return
+	}
+	s.analyzerLock.Lock()
+	defer s.analyzerLock.Unlock()
+
+	position := atomic.LoadUint64(&s.position)
+	for i := range s.samples {
+		bits := atomic.LoadUint32(&s.buffer[(position+uint64(i))%spectrumWindow])
+		s.samples[i] = complex(float64(math.Float32frombits(bits))*s.window[i], 0)
+	}
+	fft(s.samples)
+
+	binWidth := float64(sampleRate) / spectrumWindow
+	bins := spectrumWindow / 2
+	ratio := math.Pow(spectrumMaxFrequency/spectrumMinFrequency, 1/float64(len(levels)))
+	low := float64(spectrumMinFrequency)
+	for i := range levels {
+		high := low * ratio
+		first := int(math.Round(low / binWidth))
+		last := int(math.Round(high / binWidth))
+		if last <= first {
+			// band is narrower than bin
+			last = first + 1
+		}
+		if last > bins {
+			last = bins
+		}
+
+		peak := 0.0
+		for bin := first; bin < last; bin++ {
+			// amplitude of sine wave, where full scale sine is 1
+			amplitude := cmplx.Abs(s.samples[bin]) * 2 / s.windowSum
+			if amplitude > peak {
+				peak = amplitude
+			}
+		}
+		level := 0.0
+		if peak > 0 {
+			level = (20*math.Log10(peak) + spectrumRange) / spectrumRange
+		}
+		levels[i] = math.Max(0, math.Min(1, level))
+		low = high
+	}
+}
+
+// fft computes fast fourier transform in place. Length of x must be power of two.
+func fft(x []complex128) {
+	n := len(x)
+	// bit reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for length := 2; length <= n; length <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(length)))
+		for start := 0; start < n; start += length {
+			w := complex(1, 0)
+			for k := 0; k < length/2; k++ {
+				even := x[start+k]
+				odd := x[start+k+length/2] * w
+				x[start+k] = even + odd
+				x[start+k+length/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestFft(t *testing.T) {
+	x := make([]complex128, 64)
+	for i := range x {
+		x[i] = complex(math.Cos(2*math.Pi*5*float64(i)/64), 0)
+	}
+	fft(x)
+	for i, v := range x {
+		want := 0.0
+		if i == 5 || i == 64-5 {
+			want = 32
+		}
+		if math.Abs(cmplx.Abs(v)-want) > 1e-9 {
+			t.Errorf("bin %d, got: %.3f, want: %.3f", i, cmplx.Abs(v), want)
+		}
+	}
+}
+
+func TestSpectrumTap(t *testing.T) {
+	source := &sineStreamer{frequency: 1000}
+	tap := newSpectrumTap()
+	tap.Streamer = source
+	levels := make([]float64, 16)
+
+	samples := make([][2]float64, spectrumWindow)
+	tap.Stream(samples)
+	tap.spectrum(levels, 44100)
+	for i, v := range levels {
+		if v != 0 {
+			t.Errorf("disabled spectrum, band %d, got: %.2f, want: 0", i, v)
+		}
+	}
+
+	tap.setEnabled(true)
+	n, ok := tap.Stream(samples)
+	if n != len(samples) || !ok {
+		t.Fatalf("stream, got: %d, %t", n, ok)
+	}
+	reference := &sineStreamer{frequency: 1000}
+	want := make([][2]float64, spectrumWindow)
+	reference.Stream(want)
+	reference.Stream(want)
+	for i := range samples {
+		if samples[i] != want[i] {
+			t.Fatalf("tap modifies audio at %d, got: %v, want: %v", i, samples[i], want[i])
+		}
+	}
+
+	tap.spectrum(levels, 44100)
+	peak := 0
+	for i, v := range levels {
+		if v > levels[peak] {
+			peak = i
+		}
+	}
+	// bands are 50 Hz * (16000/50)^(i/16), 1 kHz is in band 8
+	if peak != 8 {
+		t.Errorf("peak band, got: %d, want: 8, levels: %.2f", peak, levels)
+	}
+	// -6 dBFS
+	if math.Abs(levels[peak]-0.9) > 0.02 {
+		t.Errorf("peak level, got: %.2f, want: 0.9", levels[peak])
+	}
+	if levels[0] > 0.1 || levels[15] > 0.1 {
+		t.Errorf("levels away from sine, got: %.2f", levels)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/interfaces"
+)
+
+// audioStateTransitions lists allowed transitions from each state. Transition to the same state is always
+// allowed and does nothing.
+var audioStateTransitions = map[interfaces.AudioState][]interfaces.AudioState{
+	interfaces.AudioStateIdle: {
+		interfaces.AudioStateLoading, interfaces.AudioStateBuffering, interfaces.AudioStateError},
+	interfaces.AudioStateLoading: {
+		interfaces.AudioStateIdle, interfaces.AudioStateBuffering, interfaces.AudioStateError},
+	interfaces.AudioStateBuffering: {
+		interfaces.AudioStateIdle, interfaces.AudioStateLoading, interfaces.AudioStatePlaying,
+		interfaces.AudioStatePaused, interfaces.AudioStateError},
+	interfaces.AudioStatePlaying: {
+		interfaces.AudioStateIdle, interfaces.AudioStateLoading, interfaces.AudioStateBuffering,
+		interfaces.AudioStatePaused, interfaces.AudioStateError},
+	interfaces.AudioStatePaused: {
+		interfaces.AudioStateIdle, interfaces.AudioStateLoading, interfaces.AudioStateBuffering,
+		interfaces.AudioStatePlaying, interfaces.AudioStateError},
+	interfaces.AudioStateError: {
+		interfaces.AudioStateIdle, interfaces.AudioStateLoading, interfaces.AudioStateBuffering},
+}
+
+// canTransition returns true if state can change from given state to another.
+func canTransition(from, to interfaces.AudioState) bool {
+	if from == to {
+		return true
+	}
+	for _, v := range audioStateTransitions[from] {
+		if v == to {
+			return true
+		}
+	}
+	return false
+}
+
+// changeState moves audio to given state, if transition is allowed. Error is only kept in error state.
+// Caller must hold output lock.
+func (a *Audio) changeState(state interfaces.AudioState, err error) bool {
+	if !canTransition(a.status.State, state) {
+		logrus.Warningf("invalid audio state transition: %s -> %s", a.status.State, state)
+		return false
+	}
+	if state != a.status.State {
+		logrus.Debugf("audio state: %s -> %s", a.status.State, state)
+	}
+	a.status.State = state
+	if state == interfaces.AudioStateError {
+		a.status.Error = err
+	} else {
+		a.status.Error = nil
+	}
+	return true
+}
+
+// setState moves audio to given state and flushes status, if transition is allowed.
+func (a *Audio) setState(state interfaces.AudioState, err error) {
+	a.output.Lock()
+	changed := a.status.State != state || a.status.Error != err
+	ok := a.changeState(state, err)
+	a.output.Unlock()
+	if ok && changed {
+		go a.flushStatus()
+	}
+}
+
+// songState returns state of loaded song: paused, buffering if it has run out of decoded audio, or playing.
+// Caller must hold output lock.
+func (a *Audio) songState() interfaces.AudioState {
+	if a.ctrl.Paused {
+		return interfaces.AudioStatePaused
+	}
+	if a.buffering {
+		return interfaces.AudioStateBuffering
+	}
+	return interfaces.AudioStatePlaying
+}
+
+// updateSongState updates state of loaded song, if there is one. Returns true if state changed.
+// Caller must hold output lock.
+func (a *Audio) updateSongState() bool {
+	if !a.status.State.HasSong() {
+		return false
+	}
+	state := a.songState()
+	if state == a.status.State {
+		return false
+	}
+	return a.changeState(state, nil)
+}
+
+// setBuffering sets whether current song is waiting for decoded audio. Caller must hold output lock.
+func (a *Audio) setBuffering(buffering bool) {
+	if a.buffering == buffering {
+		return
+	}
+	a.buffering = buffering
+	if a.updateSongState() {
+		go a.flushStatus()
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"bytes"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"testing"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		name string
+		from interfaces.AudioState
+		to   interfaces.AudioState
+		want bool
+	}{
+		{name: "load", from: interfaces.AudioStateIdle, to: interfaces.AudioStateLoading, want: true},
+		{name: "decode", from: interfaces.AudioStateLoading, to: interfaces.AudioStateBuffering, want: true},
+		{name: "play", from: interfaces.AudioStateBuffering, to: interfaces.AudioStatePlaying, want: true},
+		{name: "pause", from: interfaces.AudioStatePlaying, to: interfaces.AudioStatePaused, want: true},
+		{name: "underrun", from: interfaces.AudioStatePlaying, to: interfaces.AudioStateBuffering, want: true},
+		{name: "stop", from: interfaces.AudioStatePaused, to: interfaces.AudioStateIdle, want: true},
+		{name: "fail", from: interfaces.AudioStateLoading, to: interfaces.AudioStateError, want: true},
+		{name: "retry", from: interfaces.AudioStateError, to: interfaces.AudioStateLoading, want: true},
+		{name: "same", from: interfaces.AudioStatePlaying, to: interfaces.AudioStatePlaying, want: true},
+		{name: "play without song", from: interfaces.AudioStateIdle, to: interfaces.AudioStatePlaying, want: false},
+		{name: "pause without song", from: interfaces.AudioStateLoading, to: interfaces.AudioStatePaused, want: false},
+		{name: "play failed song", from: interfaces.AudioStateError, to: interfaces.AudioStatePlaying, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("canTransition(%s, %s), got: %t, want: %t", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAudio_state(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	audio := newAudio(newStreamOutput(nil, false))
+	wantState := func(want interfaces.AudioState) {
+		t.Helper()
+		if got := audio.getStatus().State; got != want {
+			t.Errorf("state, got: %s, want: %s", got, want)
+		}
+	}
+
+	wantState(interfaces.AudioStateIdle)
+	audio.setState(interfaces.AudioStatePlaying, nil)
+	wantState(interfaces.AudioStateIdle)
+
+	audio.setState(interfaces.AudioStateLoading, nil)
+	wantState(interfaces.AudioStateLoading)
+	audio.setState(interfaces.AudioStateError, errors.New("download failed"))
+	wantState(interfaces.AudioStateError)
+	if audio.getStatus().Error == nil {
+		t.Errorf("expect error to be set")
+	}
+
+	metadata := songMetadata{
+		song:   &models.Song{Duration: 1},
+		reader: &testReader{bytes.NewReader(testWav(20000))},
+		format: interfaces.AudioFormatWav,
+	}
+	track, err := audio.newTrack(metadata)
+	if err != nil {
+		t.Fatalf("decode song: %v", err)
+	}
+	err = audio.playTrack(track)
+	if err != nil {
+		t.Fatalf("play song: %v", err)
+	}
+	wantState(interfaces.AudioStatePlaying)
+	if audio.getStatus().Error != nil {
+		t.Errorf("expect error to be cleared")
+	}
+
+	audio.Pause()
+	wantState(interfaces.AudioStatePaused)
+	audio.output.Lock()
+	audio.setBuffering(true)
+	audio.output.Unlock()
+	wantState(interfaces.AudioStatePaused)
+	audio.Continue()
+	wantState(interfaces.AudioStateBuffering)
+	audio.output.Lock()
+	audio.setBuffering(false)
+	audio.output.Unlock()
+	wantState(interfaces.AudioStatePlaying)
+
+	audio.StopMedia()
+	wantState(interfaces.AudioStateIdle)
+}
+
+func TestAudio_trackCompleted(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	songs := testSongs()[:2]
+	audio := newAudio(newStreamOutput(nil, false))
+	newTrack := func(song *models.Song) *audioTrack {
+		metadata := songMetadata{
+			song:   song,
+			reader: &testReader{bytes.NewReader(testWav(20000))},
+			format: interfaces.AudioFormatWav,
+		}
+		track, err := audio.newTrack(metadata)
+		if err != nil {
+			t.Fatalf("decode song: %v", err)
+		}
+		return track
+	}
+	complete := func() {
+		audio.output.Lock()
+		audio.trackCompleted()
+		audio.output.Unlock()
+	}
+	wantState := func(want interfaces.AudioState) {
+		t.Helper()
+		if got := audio.getStatus().State; got != want {
+			t.Errorf("state, got: %s, want: %s", got, want)
+		}
+	}
+
+	err := audio.playTrack(newTrack(songs[0]))
+	if err != nil {
+		t.Fatalf("play song: %v", err)
+	}
+	_, err = audio.queueTrack(newTrack(songs[1]))
+	if err != nil {
+		t.Fatalf("queue song: %v", err)
+	}
+
+	complete()
+	wantState(interfaces.AudioStatePlaying)
+	if audio.getStatus().Song != songs[1] {
+		t.Errorf("expect next song to play")
+	}
+
+	// nothing queued to play next
+	complete()
+	wantState(interfaces.AudioStateLoading)
+	if audio.hasCurrentSong() {
+		t.Errorf("expect no current song")
+	}
+}
@@ -0,0 +1,180 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"errors"
+	"fmt"
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/flac"
+	"github.com/faiface/beep/mp3"
+	"github.com/faiface/beep/vorbis"
+	"github.com/faiface/beep/wav"
+	"io"
+	"tryffel.net/go/jellycli/interfaces"
+)
+
+// decodeBufferSize is the number of samples to decode at once when skipping audio.
+const decodeBufferSize = 4096
+
+// songStream is a decoded song that supports seeking regardless of audio format.
+// Not all decoders support seeking, and decoders that do, require the whole stream to be seekable and
+// might try to read it completely before playback. If decoder can seek without reading the stream on open,
+// it is given seekable source and songStream uses decoder's own Seek. Otherwise songStream hides io.Seeker
+// from decoder and seeks by decoding (and discarding) audio up to the requested position. Seeking backwards
+// then rewinds the source, which requires source to implement io.Seeker, and decodes song again from the start.
+type songStream struct {
+	source      io.ReadCloser
+	audioFormat interfaces.AudioFormat
+	decoder     beep.StreamSeekCloser
+	format      beep.Format
+	// seekable is true if decoder reads seekable source and supports seeking it
+	seekable bool
+	// position in samples
+	position int
+}
+
+func newSongStream(source io.ReadCloser, format interfaces.AudioFormat) (*songStream, error) {
+	s := &songStream{
+		source:      source,
+		audioFormat: format,
+	}
+	err := s.decode()
+	return s, err
+}
+
+// decode initializes new decoder that reads source from its current position.
+func (s *songStream) decode() error {
+	var err error
+	reader := &sourceReader{reader: s.source}
+	s.seekable = false
+	switch s.audioFormat {
+	case interfaces.AudioFormatMp3:
+		// mp3 decoder scans the whole stream on open if it is seekable
+		s.decoder, s.format, err = mp3.Decode(reader)
+	case interfaces.AudioFormatFlac:
+		// flac decoder does not implement seeking
+		s.decoder, s.format, err = flac.Decode(reader)
+	case interfaces.AudioFormatWav:
+		// wav decoder seeks by computing offset in stream
+		if seeker, ok := s.source.(io.ReadSeeker); ok {
+			s.decoder, s.format, err = wav.Decode(&seekableSourceReader{seeker})
+			s.seekable = true
+		} else {
+			s.decoder, s.format, err = wav.Decode(reader)
+		}
+	case interfaces.AudioFormatOgg:
+		// vorbis decoder scans the whole stream on open if it is seekable
+		s.decoder, s.format, err = vorbis.Decode(reader)
+	default:
+		return fmt.Errorf("unknown audio format: %s", s.audioFormat)
+	}
+	if err != nil {
+		return fmt.Errorf("%s decoder: %v", s.audioFormat, err)
+	}
+	s.position = 0
+	return nil
+}
+
+func (s *songStream) Stream(samples [][2]float64) (n int, ok bool) {
+	n, ok = s.decoder.Stream(samples)
+	s.position += n
+	return
+}
+
+func (s *songStream) Err() error {
+	return s.decoder.Err()
+}
+
+func (s *songStream) Len() int {
+	return s.decoder.Len()
+}
+
+func (s *songStream) Position() int {
+	return s.position
+}
+
+// Seek seeks to given sample. If position is past the end of the song, stream is seeked to the end.
+func (s *songStream) Seek(p int) error {
+	if p < 0 {
+		p = 0
+	}
+	if s.seekable {
+		if length := s.decoder.Len(); p > length {
+			p = length
+		}
+		err := s.decoder.Seek(p)
+		if err != nil {
+			return fmt.Errorf("%s decoder: %v", s.audioFormat, err)
+		}
+		s.position = p
+		return nil
+	}
+
+	if p < s.position {
+		seeker, ok := s.source.(io.Seeker)
+		if !ok {
+			return errors.New("stream does not support seeking backwards")
+		}
+		_, err := seeker.Seek(0, io.SeekStart)
+		if err != nil {
+			return fmt.Errorf("rewind stream: %v", err)
+		}
+		err = s.decode()
+		if err != nil {
+			return fmt.Errorf("decode stream: %v", err)
+		}
+	}
+
+	buf := make([][2]float64, decodeBufferSize)
+	for s.position < p {
+		toRead := p - s.position
+		if toRead > len(buf) {
+			toRead = len(buf)
+		}
+		_, ok := s.Stream(buf[:toRead])
+		if !ok {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *songStream) Close() error {
+	return s.source.Close()
+}
+
+// sourceReader hides io.Seeker from decoders. Closing sourceReader does nothing,
+// since source is closed by songStream and it must stay open when decoders are replaced.
+type sourceReader struct {
+	reader io.Reader
+}
+
+func (s *sourceReader) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
+
+func (s *sourceReader) Close() error {
+	return nil
+}
+
+// seekableSourceReader allows decoder to seek source. Like sourceReader, closing it does nothing.
+type seekableSourceReader struct {
+	io.ReadSeeker
+}
@@ -0,0 +1,180 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"tryffel.net/go/jellycli/interfaces"
+)
+
+type testReader struct {
+	*bytes.Reader
+}
+
+func (t *testReader) Close() error {
+	return nil
+}
+
+// testWav creates 16-bit stereo wav file where each sample's value is its index.
+func testWav(samples int) []byte {
+	return testWavRate(samples, 44100)
+}
+
+// testWavRate creates wav with given sample rate.
+func testWavRate(samples int, sampleRate int) []byte {
+	buf := &bytes.Buffer{}
+	dataSize := uint32(samples * 4)
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(36)+dataSize)
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(16))
+	binary.Write(buf, binary.LittleEndian, uint16(1))
+	binary.Write(buf, binary.LittleEndian, uint16(2))
+	binary.Write(buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(buf, binary.LittleEndian, uint32(sampleRate*4))
+	binary.Write(buf, binary.LittleEndian, uint16(4))
+	binary.Write(buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, dataSize)
+	for i := 0; i < samples; i++ {
+		binary.Write(buf, binary.LittleEndian, int16(i))
+		binary.Write(buf, binary.LittleEndian, int16(i))
+	}
+	return buf.Bytes()
+}
+
+func TestSongStream_Seek(t *testing.T) {
+	source := &testReader{bytes.NewReader(testWav(20000))}
+	stream, err := newSongStream(source, interfaces.AudioFormatWav)
+	if err != nil {
+		t.Fatalf("decode stream: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		seek int
+		want int
+	}{
+		{name: "forward", seek: 5000, want: 5000},
+		{name: "forward again", seek: 15000, want: 15000},
+		{name: "backwards", seek: 1000, want: 1000},
+		{name: "start", seek: 0, want: 0},
+		{name: "negative", seek: -10, want: 0},
+		{name: "past end", seek: 30000, want: 20000},
+	}
+
+	samples := make([][2]float64, 1)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := stream.Seek(tt.seek)
+			if err != nil {
+				t.Errorf("seek: %v", err)
+				return
+			}
+			if stream.Position() != tt.want {
+				t.Errorf("position: want %d, got %d", tt.want, stream.Position())
+			}
+			n, _ := stream.Stream(samples)
+			if tt.want >= 20000 {
+				if n != 0 {
+					t.Errorf("expect stream to be complete")
+				}
+				return
+			}
+			got := int(samples[0][0]*(1<<15-1) + 0.5)
+			if got != tt.want {
+				t.Errorf("sample value: want %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// countingReader counts bytes read from it. It does not implement io.Seeker.
+type countingReader struct {
+	reader *bytes.Reader
+	read   int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.read += n
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return nil
+}
+
+// countingSeeker counts bytes read from seekable source.
+type countingSeeker struct {
+	countingReader
+}
+
+func (c *countingSeeker) Seek(offset int64, whence int) (int64, error) {
+	return c.reader.Seek(offset, whence)
+}
+
+func TestSongStream_Seek_native(t *testing.T) {
+	source := &countingSeeker{countingReader{reader: bytes.NewReader(testWav(20000))}}
+	stream, err := newSongStream(source, interfaces.AudioFormatWav)
+	if err != nil {
+		t.Fatalf("decode stream: %v", err)
+	}
+	read := source.read
+	for _, p := range []int{15000, 1000} {
+		err = stream.Seek(p)
+		if err != nil {
+			t.Fatalf("seek: %v", err)
+		}
+		if stream.Position() != p {
+			t.Errorf("position: want %d, got %d", p, stream.Position())
+		}
+	}
+	if source.read != read {
+		t.Errorf("expect seek not to decode stream, read %d bytes", source.read-read)
+	}
+	samples := make([][2]float64, 1)
+	stream.Stream(samples)
+	if got := int(samples[0][0]*(1<<15-1) + 0.5); got != 1000 {
+		t.Errorf("sample value: want %d, got %d", 1000, got)
+	}
+}
+
+func TestSongStream_Seek_notSeekable(t *testing.T) {
+	source := &countingReader{reader: bytes.NewReader(testWav(20000))}
+	stream, err := newSongStream(source, interfaces.AudioFormatWav)
+	if err != nil {
+		t.Fatalf("decode stream: %v", err)
+	}
+	err = stream.Seek(5000)
+	if err != nil {
+		t.Fatalf("seek forward: %v", err)
+	}
+	samples := make([][2]float64, 1)
+	stream.Stream(samples)
+	if got := int(samples[0][0]*(1<<15-1) + 0.5); got != 5000 {
+		t.Errorf("sample value: want %d, got %d", 5000, got)
+	}
+	if err := stream.Seek(1000); err == nil {
+		t.Errorf("expect seeking backwards to fail")
+	}
+}
@@ -0,0 +1,205 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/faiface/beep"
+	"math"
+)
+
+const (
+	// stretchFrame is length of a single frame in samples
+	stretchFrame = 2048
+	// stretchHop is distance between output frames, frames overlap by half
+	stretchHop = stretchFrame / 2
+	// stretchTolerance is how many samples frame may be moved to align it with previous frame
+	stretchTolerance = 512
+	// stretchCorrelationStep skips samples when aligning frames, to reduce cpu usage
+	stretchCorrelationStep = 4
+)
+
+// timeStretch changes playback speed while preserving pitch. It uses waveform similarity overlap-add (WSOLA):
+// output is built from overlapping frames, where frames are read from streamer at rate times the output hop,
+// and each frame is aligned to best match the previous one. At normal speed streamer is passed through as is.
+// TimeStretch is not safe for concurrent use, caller must hold output lock.
+type timeStretch struct {
+	Streamer beep.Streamer
+	rate     float64
+
+	window []float64
+	// input is buffered audio from streamer, input[0] being sample inputStart
+	input      [][2]float64
+	inputStart int
+	// nominal is the position in input of next frame, before alignment
+	nominal float64
+	// previous is position of previous frame in input, or -1 if there is none
+	previous int
+	// overlap is latter half of previous frame, added to next frame
+	overlap [][2]float64
+	// output is synthesized audio that has not yet been streamed
+	output    [][2]float64
+	outputBuf [][2]float64
+}
+
+func newTimeStretch() *timeStretch {
+	t := &timeStretch{
+		rate:      1,
+		window:    make([]float64, stretchFrame),
+		overlap:   make([][2]float64, stretchHop),
+		outputBuf: make([][2]float64, stretchHop),
+		previous:  -1,
+	}
+	// periodic hann window, which sums to 1 when frames overlap by half
+	for i := range t.window {
+		t.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/stretchFrame)
+	}
+	return t
+}
+
+// setRate sets speed of playback. Returning to normal speed drops audio that is already buffered,
+// which is less than 100 ms.
+func (t *timeStretch) setRate(rate float64) {
+	t.rate = rate
+	if rate == 1 {
+		t.reset()
+	}
+}
+
+func (t *timeStretch) reset() {
+	t.input = t.input[:0]
+	t.inputStart = 0
+	t.nominal = 0
+	t.previous = -1
+	t.output = nil
+	for i := range t.overlap {
+		t.overlap[i] = [2]float64{}
+	}
+}
+
+// lookahead returns number of samples read from streamer that have not yet been streamed.
+func (t *timeStretch) lookahead() int {
+	if t.rate == 1 && t.previous < 0 {
+		return 0
+	}
+	streamed := t.nominal - float64(len(t.output))*t.rate
+	lookahead := float64(t.inputStart+len(t.input)) - streamed
+	if lookahead < 0 {
+		return 0
+	}
+	return int(math.Round(lookahead))
+}
+
+func (t *timeStretch) Stream(samples [][2]float64) (n int, ok bool) {
+	if t.rate == 1 && t.previous < 0 {
+		return t.Streamer.Stream(samples)
+	}
+	for n < len(samples) {
+		if len(t.output) == 0 {
+			t.synthesize()
+		}
+		copied := copy(samples[n:], t.output)
+		t.output = t.output[copied:]
+		n += copied
+	}
+	return n, true
+}
+
+func (t *timeStretch) Err() error {
+	return t.Streamer.Err()
+}
+
+// synthesize adds next frame to output.
+func (t *timeStretch) synthesize() {
+	position := int(math.Round(t.nominal))
+	t.fill(position + stretchTolerance + stretchFrame - t.inputStart)
+	if t.previous >= 0 {
+		position = t.align(position)
+	}
+
+	frame := t.input[position-t.inputStart:][:stretchFrame]
+	for i := 0; i < stretchHop; i++ {
+		for c := 0; c < 2; c++ {
+			t.outputBuf[i][c] = t.overlap[i][c] + frame[i][c]*t.window[i]
+			t.overlap[i][c] = frame[i+stretchHop][c] * t.window[i+stretchHop]
+		}
+	}
+	t.output = t.outputBuf
+	t.previous = position
+	t.nominal += stretchHop * t.rate
+
+	// next frame is searched around next nominal position, and compared to natural continuation of this frame
+	keep := int(math.Round(t.nominal)) - stretchTolerance
+	if continuation := t.previous + stretchHop; continuation < keep {
+		keep = continuation
+	}
+	if drop := keep - t.inputStart; drop > 0 {
+		t.input = append(t.input[:0], t.input[drop:]...)
+		t.inputStart = keep
+	}
+}
+
+// align returns position near given position, where frame matches best with the continuation of previous frame.
+// On ties, e.g. silence, given position is kept.
+func (t *timeStretch) align(position int) int {
+	target := t.input[t.previous+stretchHop-t.inputStart:][:stretchHop]
+	best := position
+	bestScore := t.similarity(target, position)
+	start := position - stretchTolerance
+	if start < t.inputStart {
+		start = t.inputStart
+	}
+	for candidate := start; candidate <= position+stretchTolerance; candidate++ {
+		score := t.similarity(target, candidate)
+		if score > bestScore {
+			best = candidate
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// similarity returns normalized cross-correlation of target and input at given position.
+func (t *timeStretch) similarity(target [][2]float64, position int) float64 {
+	frame := t.input[position-t.inputStart:][:len(target)]
+	var correlation, energy float64
+	for i := 0; i < len(target); i += stretchCorrelationStep {
+		mono := frame[i][0] + frame[i][1]
+		correlation += mono * (target[i][0] + target[i][1])
+		energy += mono * mono
+	}
+	if energy == 0 {
+		return 0
+	}
+	return correlation / math.Sqrt(energy)
+}
+
+// fill reads streamer until input has given number of samples. If streamer is drained, input is filled with silence.
+func (t *timeStretch) fill(length int) {
+	for len(t.input) < length {
+		offset := len(t.input)
+		for len(t.input) < length {
+			t.input = append(t.input, [2]float64{})
+		}
+		n, ok := t.Streamer.Stream(t.input[offset:])
+		if !ok || n == 0 {
+			return
+		}
+		t.input = t.input[:offset+n]
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"math"
+	"testing"
+)
+
+// sineStreamer streams sine wave of given frequency and counts streamed samples
+type sineStreamer struct {
+	frequency float64
+	position  int
+}
+
+func (s *sineStreamer) Stream(samples [][2]float64) (int, bool) {
+	for i := range samples {
+		value := 0.5 * math.Sin(2*math.Pi*s.frequency*float64(s.position)/44100)
+		samples[i] = [2]float64{value, value}
+		s.position++
+	}
+	return len(samples), true
+}
+
+func (s *sineStreamer) Err() error {
+	return nil
+}
+
+func TestTimeStretch_Stream(t *testing.T) {
+	tests := []struct {
+		name string
+		rate float64
+	}{
+		{name: "normal", rate: 1},
+		{name: "slow", rate: 0.5},
+		{name: "fast", rate: 1.5},
+		{name: "fastest", rate: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &sineStreamer{frequency: 440}
+			stretch := newTimeStretch()
+			stretch.Streamer = source
+			stretch.setRate(tt.rate)
+
+			samples := make([][2]float64, 44100)
+			n, ok := stretch.Stream(samples)
+			if n != len(samples) || !ok {
+				t.Fatalf("stream, got: %d, %t", n, ok)
+			}
+
+			// consumed source, excluding lookahead, matches rate
+			consumed := float64(source.position-stretch.lookahead()) / float64(len(samples))
+			if math.Abs(consumed-tt.rate) > 0.01 {
+				t.Errorf("consumed source, got: %.3f x output, want: %.3f", consumed, tt.rate)
+			}
+
+			// pitch is preserved: skip first frame, which fades in, and count zero crossings for 0.5 s
+			crossings := 0
+			for i := stretchFrame + 1; i < stretchFrame+22050; i++ {
+				if (samples[i-1][0] < 0) != (samples[i][0] < 0) {
+					crossings++
+				}
+			}
+			frequency := float64(crossings)
+			if math.Abs(frequency-source.frequency) > 10 {
+				t.Errorf("frequency, got: %.0f Hz, want: %.0f Hz", frequency, source.frequency)
+			}
+		})
+	}
+}
+
+func TestTimeStretch_setRate(t *testing.T) {
+	source := &sineStreamer{frequency: 440}
+	stretch := newTimeStretch()
+	stretch.Streamer = source
+	stretch.setRate(2)
+	stretch.Stream(make([][2]float64, 1000))
+	if stretch.lookahead() == 0 {
+		t.Errorf("expect lookahead when stretching")
+	}
+
+	stretch.setRate(1)
+	if got := stretch.lookahead(); got != 0 {
+		t.Errorf("lookahead at normal speed, got: %d, want: 0", got)
+	}
+	position := source.position
+	stretch.Stream(make([][2]float64, 1000))
+	if got := source.position - position; got != 1000 {
+		t.Errorf("normal speed streams source as is, got: %d samples, want: 1000", got)
+	}
+}
@@ -37,7 +37,15 @@ import (
 	"tryffel.net/go/jellycli/storage/migrations"
 )
 
-const schemaLevel = 1
+const schemaLevel = 4
+
+// schemas contains database migrations. Migration at index i upgrades schema to level i+1.
+var schemas = []string{
+	migrations.SchemaV1,
+	migrations.SchemaV2,
+	migrations.SchemaV3,
+	migrations.SchemaV4,
+}
 
 // Db implements storing relational data to local database as cache.
 // Schema reflects the data coming from server and tries to store updated content
@@ -92,6 +100,12 @@ func NewDb(id string) (*Db, error) {
 
 // create file + schema
 func (db *Db) initDb() error {
+	return db.migrate(0)
+}
+
+// migrate upgrades database schema from given level to latest level.
+func (db *Db) migrate(level int) error {
+	logrus.Infof("Migrate database schema from level %d to %d", level, schemaLevel)
 
 	tx, err := db.engine.Begin()
 	if err != nil {
@@ -114,12 +128,19 @@ func (db *Db) initDb() error {
 
 	defer commit()
 
-	_, err = tx.Exec(migrations.SchemaV1)
+	for i := level; i < schemaLevel; i++ {
+		_, err = tx.Exec(schemas[i])
+		if err != nil {
+			return fmt.Errorf("migrate schema to level %d: %v", i+1, err)
+		}
+	}
+
+	_, err = tx.Exec("DELETE FROM schema")
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec("INSERT INTO schema VALUES (1)")
+	_, err = tx.Exec("INSERT INTO schema VALUES (?)", schemaLevel)
 	if err == nil {
 		txOk = true
 	}
@@ -140,6 +161,10 @@ func (db *Db) checkSchema() error {
 		return err
 	}
 
+	if schema < schemaLevel {
+		return db.migrate(schema)
+	}
+
 	if schema != schemaLevel {
 		return fmt.Errorf("database schema is invalid: supported %d, database: %d", schemaLevel, schema)
 	}
@@ -19,8 +19,11 @@
 package storage
 
 import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
 	"path"
 	"testing"
+	"tryffel.net/go/jellycli/storage/migrations"
 )
 
 func testDb(t *testing.T) *Db {
@@ -47,3 +50,40 @@ func TestNewDb(t *testing.T) {
 	db := testDb(t)
 	closeDb(t, db)
 }
+
+func TestDbMigrate(t *testing.T) {
+	id := "test-123"
+	file := path.Join(t.TempDir(), id+".db")
+
+	engine, err := sqlx.Connect("sqlite3", fmt.Sprintf("file:%s?_fk=true&_cslike=false", file))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	_, err = engine.Exec(migrations.SchemaV1)
+	if err != nil {
+		t.Fatalf("init schema v1: %v", err)
+	}
+	_, err = engine.Exec("INSERT INTO schema VALUES (1)")
+	if err != nil {
+		t.Fatalf("set schema level: %v", err)
+	}
+	err = engine.Close()
+	if err != nil {
+		t.Fatalf("close db: %v", err)
+	}
+
+	db, err := newDb(file, id)
+	if err != nil {
+		t.Fatalf("migrate db: %v", err)
+	}
+	defer closeDb(t, db)
+
+	level := 0
+	err = db.engine.Get(&level, "SELECT level FROM schema")
+	if err != nil {
+		t.Fatalf("get schema level: %v", err)
+	}
+	if level != schemaLevel {
+		t.Errorf("schema level, got: %d, want: %d", level, schemaLevel)
+	}
+}
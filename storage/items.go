@@ -253,15 +253,19 @@ func (db *Db) GetAlbums(query *interfaces.QueryOpts) (albums []*models.Album, n
 }
 
 func (db *Db) UpdateSongs(songs []*models.Song) error {
-	sql := `INSERT INTO songs(id, name, duration, song_index, disc_number, favorite, album)
+	sql := `INSERT INTO songs(id, name, duration, song_index, disc_number, favorite, album,
+	track_gain, album_gain, track_peak, album_peak, play_count)
 	VALUES %s
 	ON CONFLICT(id) DO UPDATE SET
     name=excluded.name, duration=excluded.duration,
 	song_index=excluded.song_index, disc_number=excluded.disc_number,
-	favorite=excluded.favorite, album=excluded.album;
+	favorite=excluded.favorite, album=excluded.album,
+	track_gain=excluded.track_gain, album_gain=excluded.album_gain,
+	track_peak=excluded.track_peak, album_peak=excluded.album_peak,
+	play_count=excluded.play_count;
 `
 
-	args := make([]interface{}, len(songs)*7)
+	args := make([]interface{}, len(songs)*12)
 
 	argFmt := ""
 
@@ -269,16 +273,22 @@ func (db *Db) UpdateSongs(songs []*models.Song) error {
 		if i > 0 {
 			argFmt += ", "
 		}
-		argFmt += "(?, ?, ?, ?, ?, ?, ?)"
+		argFmt += "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 
-		args[i*7] = v.Id
-		args[i*7+1] = v.Name
-		args[i*7+2] = v.Duration
+		args[i*12] = v.Id
+		args[i*12+1] = v.Name
+		args[i*12+2] = v.Duration
 
-		args[i*7+3] = v.Index
-		args[i*7+4] = v.DiscNumber
-		args[i*7+5] = v.Favorite
-		args[i*7+6] = v.Album
+		args[i*12+3] = v.Index
+		args[i*12+4] = v.DiscNumber
+		args[i*12+5] = v.Favorite
+		args[i*12+6] = v.Album
+
+		args[i*12+7] = v.TrackGain
+		args[i*12+8] = v.AlbumGain
+		args[i*12+9] = v.TrackPeak
+		args[i*12+10] = v.AlbumPeak
+		args[i*12+11] = v.PlayCount
 	}
 
 	sql = fmt.Sprintf(sql, argFmt)
@@ -371,9 +381,8 @@ func (db *Db) UpdatePlaylists(playlists []*models.Playlist) error {
 	}
 
 	// playlist songs
-	sql = `DELETE FROM playlist_songs WHERE playlist IN %s; 
-	INSERT INTO playlist_songs(playlist_index, playlist, song) VALUES %s;
-`
+	sql = `DELETE FROM playlist_songs WHERE playlist IN %s;`
+	insertSql := `INSERT INTO playlist_songs(playlist_index, playlist, song) VALUES %s;`
 	args = make([]interface{}, 0, len(playlists))
 
 	argFmt = ""
@@ -387,9 +396,9 @@ func (db *Db) UpdatePlaylists(playlists []*models.Playlist) error {
 		args = append(args, pl.Id)
 	}
 
-	for i, pl := range playlists {
+	for _, pl := range playlists {
 		for songIndex, song := range pl.Songs {
-			if i > 0 || songIndex > 0 {
+			if argFmt != "" {
 				argFmt += ", "
 			}
 			argFmt += "(?, ?, ?)"
@@ -400,7 +409,11 @@ func (db *Db) UpdatePlaylists(playlists []*models.Playlist) error {
 	}
 	argPlaylists += ")"
 
-	sql = fmt.Sprintf(sql, argPlaylists, argFmt)
+	sql = fmt.Sprintf(sql, argPlaylists)
+	// playlists can be empty
+	if argFmt != "" {
+		sql += fmt.Sprintf(insertSql, argFmt)
+	}
 	_, err = tx.Exec(sql, args...)
 	if err != nil {
 		return err
@@ -420,10 +433,10 @@ func (db *Db) GetPlaylists() ([]*models.Playlist, error) {
 	pl.id AS id,
 		pl.name AS name,
 		COUNT(ps.song) AS song_count,
-		SUM(s.duration) AS duration
+		COALESCE(SUM(s.duration), 0) AS duration
 	FROM playlists pl
-	JOIN playlist_songs ps ON pl.id = ps.playlist
-	JOIN songs s ON ps.song = s.id
+	LEFT JOIN playlist_songs ps ON pl.id = ps.playlist
+	LEFT JOIN songs s ON ps.song = s.id
 	GROUP BY pl.id
 	ORDER BY pl.name;`
 
@@ -443,3 +456,26 @@ func (db *Db) GetPlaylists() ([]*models.Playlist, error) {
 	}
 	return playlists, nil
 }
+
+// DeletePlaylist deletes playlist and its songs.
+func (db *Db) DeletePlaylist(id models.Id) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	sql := `DELETE FROM playlist_songs WHERE playlist = ?;
+	DELETE FROM playlists WHERE id = ?;`
+	_, err = tx.Exec(sql, id, id)
+	if err != nil {
+		return err
+	}
+
+	err = db.updateKey(keyPlaylists, tx)
+	if err != nil {
+		return err
+	}
+	tx.ok = true
+	return nil
+}
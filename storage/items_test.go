@@ -23,6 +23,7 @@ import (
 	"testing"
 	"tryffel.net/go/jellycli/api"
 	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
 )
 
 func TestDb_UpdateArtists(t *testing.T) {
@@ -204,3 +205,44 @@ func TestDb_UpdatePlaylists(t *testing.T) {
 		}
 	}
 }
+
+func TestDb_DeletePlaylist(t *testing.T) {
+	db := testDb(t)
+	if db == nil {
+		return
+	}
+	defer closeDb(t, db)
+
+	err := db.UpdateSongs(api.MockSongs)
+	if err != nil {
+		t.Fatalf("insert songs: %v", err)
+	}
+
+	empty := &models.Playlist{Id: "playlist-empty", Name: "empty"}
+	playlists := append([]*models.Playlist{empty}, api.MockPlaylists...)
+	err = db.UpdatePlaylists(playlists)
+	if err != nil {
+		t.Fatalf("insert playlists: %v", err)
+	}
+
+	err = db.DeletePlaylist(api.MockPlaylists[0].Id)
+	if err != nil {
+		t.Fatalf("delete playlist: %v", err)
+	}
+
+	gotPlaylists, err := db.GetPlaylists()
+	if err != nil {
+		t.Fatalf("get playlists: %v", err)
+	}
+	want := []*models.Playlist{empty, api.MockPlaylists[1]}
+	if len(gotPlaylists) != len(want) {
+		t.Fatalf("playlists count, got: %d, want: %d", len(gotPlaylists), len(want))
+	}
+	for i, v := range gotPlaylists {
+		v.Songs = want[i].Songs
+		diff := cmp.Diff(v, want[i])
+		if diff != "" {
+			t.Errorf("playlist differs: %s", diff)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package migrations
+
+// SchemaV2 adds ReplayGain values to songs.
+const SchemaV2 = `
+
+ALTER TABLE songs ADD COLUMN track_gain REAL NOT NULL DEFAULT 0;
+ALTER TABLE songs ADD COLUMN album_gain REAL NOT NULL DEFAULT 0;
+ALTER TABLE songs ADD COLUMN track_peak REAL NOT NULL DEFAULT 0;
+ALTER TABLE songs ADD COLUMN album_peak REAL NOT NULL DEFAULT 0;
+
+`
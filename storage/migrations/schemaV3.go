@@ -0,0 +1,46 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package migrations
+
+// SchemaV3 adds player session, which is restored on next start.
+const SchemaV3 = `
+
+-- session has at most one row.
+CREATE TABLE session (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	position INTEGER NOT NULL,
+	volume INTEGER NOT NULL,
+	shuffle BOOL NOT NULL,
+	saved INTEGER NOT NULL
+);
+
+-- session_songs contains queue and history of session. Songs are not required to exist in songs table.
+CREATE TABLE session_songs (
+	list TEXT NOT NULL,
+	list_index INTEGER NOT NULL,
+	id TEXT NOT NULL,
+	album TEXT NOT NULL,
+	name TEXT NOT NULL,
+	song_index INTEGER NOT NULL,
+	disc_number INTEGER NOT NULL,
+
+	UNIQUE(list, list_index)
+);
+
+`
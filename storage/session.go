@@ -0,0 +1,149 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"tryffel.net/go/jellycli/models"
+)
+
+const (
+	sessionQueue   = "queue"
+	sessionHistory = "history"
+
+	// sessionSongBatch limits songs per insert, as sqlite limits number of variables in single statement.
+	sessionSongBatch = 100
+)
+
+// SaveSession replaces saved session with given session.
+func (db *Db) SaveSession(session *models.Session) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	_, err = tx.Exec("DELETE FROM session_songs")
+	if err != nil {
+		return err
+	}
+
+	sql := `INSERT INTO session(id, position, volume, shuffle, saved) VALUES (1, ?, ?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET
+	position=excluded.position, volume=excluded.volume,
+	shuffle=excluded.shuffle, saved=excluded.saved;`
+
+	_, err = tx.Exec(sql, session.Position.Milliseconds(), session.Volume, session.Shuffle,
+		sqlTime{session.Saved})
+	if err != nil {
+		return err
+	}
+
+	err = insertSessionSongs(tx, sessionQueue, session.Queue)
+	if err != nil {
+		return fmt.Errorf("save queue: %v", err)
+	}
+	err = insertSessionSongs(tx, sessionHistory, session.History)
+	if err != nil {
+		return fmt.Errorf("save history: %v", err)
+	}
+	tx.ok = true
+	return nil
+}
+
+func insertSessionSongs(tx *tx, list string, songs []models.SessionSong) error {
+	sql := `INSERT INTO session_songs(list, list_index, id, album, name, song_index, disc_number) VALUES %s`
+
+	for start := 0; start < len(songs); start += sessionSongBatch {
+		end := start + sessionSongBatch
+		if end > len(songs) {
+			end = len(songs)
+		}
+		batch := songs[start:end]
+
+		args := make([]interface{}, len(batch)*7)
+		argFmt := ""
+		for i, v := range batch {
+			if i > 0 {
+				argFmt += ", "
+			}
+			argFmt += "(?, ?, ?, ?, ?, ?, ?)"
+
+			args[i*7] = list
+			args[i*7+1] = start + i
+			args[i*7+2] = v.Id
+			args[i*7+3] = v.Album
+			args[i*7+4] = v.Name
+			args[i*7+5] = v.Index
+			args[i*7+6] = v.DiscNumber
+		}
+
+		_, err := tx.Exec(fmt.Sprintf(sql, argFmt), args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSession returns saved session. If there is no session, return nil.
+func (db *Db) GetSession() (*models.Session, error) {
+	row := struct {
+		Position int64   `db:"position"`
+		Volume   int     `db:"volume"`
+		Shuffle  bool    `db:"shuffle"`
+		Saved    sqlTime `db:"saved"`
+	}{}
+
+	err := db.engine.Get(&row, "SELECT position, volume, shuffle, saved FROM session WHERE id = 1")
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		Position: time.Duration(row.Position) * time.Millisecond,
+		Volume:   row.Volume,
+		Shuffle:  row.Shuffle,
+		Saved:    row.Saved.Time,
+	}
+
+	session.Queue, err = db.getSessionSongs(sessionQueue)
+	if err != nil {
+		return nil, fmt.Errorf("get queue: %v", err)
+	}
+	session.History, err = db.getSessionSongs(sessionHistory)
+	if err != nil {
+		return nil, fmt.Errorf("get history: %v", err)
+	}
+	return session, nil
+}
+
+func (db *Db) getSessionSongs(list string) ([]models.SessionSong, error) {
+	sql := `SELECT id, album, name, song_index, disc_number FROM session_songs
+	WHERE list = ? ORDER BY list_index`
+
+	songs := []models.SessionSong{}
+	err := db.engine.Select(&songs, sql, list)
+	return songs, err
+}
@@ -0,0 +1,81 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package storage
+
+import (
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+	"tryffel.net/go/jellycli/models"
+)
+
+func TestDb_SaveSession(t *testing.T) {
+	db := testDb(t)
+	if db == nil {
+		return
+	}
+	defer closeDb(t, db)
+
+	session, err := db.GetSession()
+	if err != nil {
+		t.Errorf("get empty session: %v", err)
+	}
+	if session != nil {
+		t.Errorf("expect no session, got: %v", session)
+	}
+
+	queue := make([]models.SessionSong, 250)
+	for i := range queue {
+		queue[i] = models.SessionSong{
+			Id:         models.Id(fmt.Sprintf("song-%d", i)),
+			Album:      "album-1",
+			Name:       fmt.Sprintf("Song %d", i),
+			Index:      i + 1,
+			DiscNumber: 1,
+		}
+	}
+
+	want := &models.Session{
+		Queue:    queue,
+		History:  []models.SessionSong{{Id: "song-a", Album: "album-2", Name: "Song A", Index: 3, DiscNumber: 2}},
+		Position: time.Second * 83,
+		Volume:   40,
+		Shuffle:  true,
+		Saved:    time.Unix(1600000000, 0),
+	}
+
+	err = db.SaveSession(&models.Session{Queue: queue[:10], Volume: 10})
+	if err != nil {
+		t.Errorf("save session: %v", err)
+	}
+	err = db.SaveSession(want)
+	if err != nil {
+		t.Errorf("overwrite session: %v", err)
+	}
+
+	got, err := db.GetSession()
+	if err != nil {
+		t.Errorf("get session: %v", err)
+	}
+	diff := cmp.Diff(want, got)
+	if diff != "" {
+		t.Errorf("session differs: %s", diff)
+	}
+}
@@ -20,6 +20,7 @@ package widgets
 
 import (
 	"fmt"
+	"github.com/gdamore/tcell"
 	"github.com/rivo/uniseg"
 	"gitlab.com/tslocum/cview"
 	"strings"
@@ -36,12 +37,17 @@ type albumSong struct {
 	index       int
 	// is song being played now
 	playing bool
+	// is song marked for bulk operations
+	marked bool
+	// is song selected in list
+	selected bool
 
 	// allow overriding text input. If updateTextFunc != nil, use that to update, else use default album text format
 	updateTextFunc func(a *albumSong)
 }
 
 func (a *albumSong) SetSelected(selected twidgets.Selection) {
+	a.selected = selected != twidgets.Deselected
 	switch selected {
 	case twidgets.Selected:
 		a.SetBackgroundColor(config.Color.BackgroundSelected)
@@ -49,11 +55,26 @@ func (a *albumSong) SetSelected(selected twidgets.Selection) {
 	case twidgets.Blurred:
 		a.SetBackgroundColor(config.Color.TextDisabled)
 	case twidgets.Deselected:
-		a.SetBackgroundColor(config.Color.Background)
+		a.SetBackgroundColor(a.background())
 		a.SetTextColor(config.Color.Text)
 	}
 }
 
+func (a *albumSong) setMarked(marked bool) {
+	a.marked = marked
+	if !a.selected {
+		a.SetBackgroundColor(a.background())
+	}
+}
+
+// background returns background color when song is not selected.
+func (a *albumSong) background() tcell.Color {
+	if a.marked {
+		return config.Color.BackgroundMarked
+	}
+	return config.Color.Background
+}
+
 func (a *albumSong) SetRect(x, y, w, h int) {
 	_, _, ch, cw := a.GetRect()
 	a.TextView.SetRect(x, y, w, h)
@@ -145,6 +166,17 @@ func (a *albumSong) getAlignedDuration(text string) string {
 	return out
 }
 
+// songsAt returns songs in given indices.
+func songsAt(songs []*albumSong, indices []int) []*models.Song {
+	out := make([]*models.Song, 0, len(indices))
+	for _, v := range indices {
+		if v >= 0 && v < len(songs) {
+			out = append(out, songs[v].song)
+		}
+	}
+	return out
+}
+
 func (a *albumSong) SetPlaying(playing bool) {
 	a.playing = playing
 }
@@ -245,6 +277,8 @@ func NewAlbumview(playSong func(song *models.Song),
 				a.context.InstantMix(song.song)
 			}
 		})
+		a.addSongActions(a.context, func() []*albumSong { return a.songs })
+		a.multiSelect = true
 	}
 
 	if a.context != nil {
@@ -257,6 +291,13 @@ func NewAlbumview(playSong func(song *models.Song),
 		a.dropDown.AddOption("Open in browser", func() {
 			a.context.OpenInBrowser(a.album)
 		})
+		a.dropDown.AddOption("Add to playlist", func() {
+			songs := make([]*models.Song, len(a.songs))
+			for i, v := range a.songs {
+				songs[i] = v.song
+			}
+			a.context.AddSongsToPlaylist(songs)
+		})
 	}
 
 	a.itemList.initContextMenuList()
@@ -264,6 +305,7 @@ func NewAlbumview(playSong func(song *models.Song),
 }
 
 func (a *AlbumView) SetAlbum(album *models.Album, songs []*models.Song) {
+	a.clearMarked()
 	a.list.Clear()
 	a.resetReduce()
 	a.songs = make([]*albumSong, len(songs))
@@ -19,14 +19,25 @@
 package widgets
 
 import (
+	"fmt"
 	"github.com/sirupsen/logrus"
 	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/ui/widgets/modal"
 	"tryffel.net/go/jellycli/util"
 )
 
 // all operations that are callable from context menus
 type contextOperator interface {
-	AddSongToPlaylist(song *models.Song) error
+	QueueSongs(songs []*models.Song, next bool)
+	ToggleFavorite(songs []*models.Song)
+	AddSongsToPlaylist(songs []*models.Song)
+	RenamePlaylist(playlist *models.Playlist)
+	DeletePlaylist(playlist *models.Playlist)
+	RemovePlaylistSongs(playlist *models.Playlist, indices []int)
+	MovePlaylistSong(playlist *models.Playlist, index, newIndex int)
+	LoadPlaylist(playlist *models.Playlist, mode playlistLoadMode)
+	SaveQueueAsPlaylist()
+	OverwritePlaylistWithQueue()
 	ViewAlbumArtist(album *models.Album)
 	ViewSongArtist(song *models.Song)
 	ViewSongAlbum(song *models.Song)
@@ -35,8 +46,255 @@ type contextOperator interface {
 	OpenInBrowser(item models.Item)
 }
 
-func (w *Window) AddSongToPlaylist(song *models.Song) error {
-	return nil
+// QueueSongs adds songs to the end of queue, or after current song if next is true.
+func (w *Window) QueueSongs(songs []*models.Song, next bool) {
+	if len(songs) == 0 {
+		return
+	}
+	if next {
+		w.mediaQueue.PlayNext(songs)
+	} else {
+		w.mediaQueue.AddSongs(songs)
+	}
+}
+
+// ToggleFavorite removes songs from favorites if all of them are favorites, else adds them to favorites.
+func (w *Window) ToggleFavorite(songs []*models.Song) {
+	if len(songs) == 0 {
+		return
+	}
+	favorite := false
+	for _, v := range songs {
+		if !v.Favorite {
+			favorite = true
+			break
+		}
+	}
+	err := w.mediaItems.SetFavorite(songs, favorite)
+	if err != nil {
+		logrus.Errorf("set favorite: %v", err)
+		w.showMessage(fmt.Sprintf("Could not set favorite:\n%v", err), 8, -1, false)
+		return
+	}
+
+	text := fmt.Sprintf("'%s'", songs[0].Name)
+	if len(songs) > 1 {
+		text = fmt.Sprintf("%d songs", len(songs))
+	}
+	if favorite {
+		w.showMessage(fmt.Sprintf("Added %s to favorites", text), 3, -1, false)
+	} else {
+		w.showMessage(fmt.Sprintf("Removed %s from favorites", text), 3, -1, false)
+	}
+}
+
+// AddSongsToPlaylist asks user to select playlist or name for new playlist, and adds songs to it.
+func (w *Window) AddSongsToPlaylist(songs []*models.Song) {
+	if len(songs) == 0 {
+		return
+	}
+	playlists, err := w.mediaItems.GetPlaylists()
+	if err != nil {
+		logrus.Errorf("get playlists: %v", err)
+		return
+	}
+
+	title := "Add song to playlist"
+	if len(songs) > 1 {
+		title = fmt.Sprintf("Add %d songs to playlist", len(songs))
+	}
+	picker := newPlaylistPicker(title, "Add", true, playlists, func(playlist *models.Playlist, newName string) {
+		if playlist == nil {
+			created, err := w.mediaItems.CreatePlaylist(newName, songs)
+			if err != nil {
+				w.playlistEditFailed(err)
+				return
+			}
+			w.playlistSaved(nil, created)
+			return
+		}
+		err := w.mediaItems.AddPlaylistSongs(playlist, songs)
+		if err != nil {
+			w.playlistEditFailed(err)
+			return
+		}
+		w.playlistChanged(playlist, -1)
+	})
+	picker.SetDoneFunc(w.wrapCloseModal(picker))
+	w.showModal(picker, 10, 60, false)
+}
+
+func (w *Window) RenamePlaylist(playlist *models.Playlist) {
+	input := modal.NewInput("Rename playlist", "Name", func(name string) {
+		err := w.mediaItems.RenamePlaylist(playlist, name)
+		if err != nil {
+			w.playlistEditFailed(err)
+			return
+		}
+		w.playlistChanged(playlist, -1)
+	})
+	input.SetText(playlist.Name)
+	input.SetDoneFunc(w.wrapCloseModal(input))
+	w.showModal(input, 5, 50, false)
+}
+
+func (w *Window) DeletePlaylist(playlist *models.Playlist) {
+	confirm := modal.NewConfirm("Delete playlist", func(accepted bool) {
+		if !accepted {
+			return
+		}
+		err := w.mediaItems.DeletePlaylist(playlist)
+		if err != nil {
+			w.playlistEditFailed(err)
+			return
+		}
+		w.updatePlaylists()
+		w.setViewWidget(w.playlists, false)
+	})
+	confirm.SetQuestion(fmt.Sprintf("Delete playlist '%s'?", playlist.Name))
+	confirm.SetDoneFunc(w.wrapCloseModal(confirm))
+	w.showModal(confirm, 8, 50, false)
+}
+
+func (w *Window) RemovePlaylistSongs(playlist *models.Playlist, indices []int) {
+	err := w.mediaItems.RemovePlaylistSongs(playlist, indices)
+	if err != nil {
+		w.playlistEditFailed(err)
+		return
+	}
+	w.playlistChanged(playlist, -1)
+}
+
+func (w *Window) MovePlaylistSong(playlist *models.Playlist, index, newIndex int) {
+	err := w.mediaItems.MovePlaylistSong(playlist, index, newIndex)
+	if err != nil {
+		w.playlistEditFailed(err)
+		return
+	}
+	w.playlistChanged(playlist, newIndex)
+}
+
+// LoadPlaylist adds playlist songs to queue.
+func (w *Window) LoadPlaylist(playlist *models.Playlist, mode playlistLoadMode) {
+	if len(playlist.Songs) != playlist.SongCount {
+		err := w.mediaItems.GetPlaylistSongs(playlist)
+		if err != nil {
+			logrus.Errorf("get playlist songs: %v", err)
+			return
+		}
+	}
+	if len(playlist.Songs) == 0 {
+		return
+	}
+
+	switch mode {
+	case playlistReplace:
+		w.mediaPlayer.StopMedia()
+		w.mediaQueue.ClearQueue(true)
+		w.mediaQueue.AddSongs(playlist.Songs)
+	case playlistAppend:
+		w.mediaQueue.AddSongs(playlist.Songs)
+	case playlistPlayNext:
+		w.mediaQueue.PlayNext(playlist.Songs)
+	}
+}
+
+// SaveQueueAsPlaylist asks name for playlist and creates new playlist from queue.
+func (w *Window) SaveQueueAsPlaylist() {
+	songs := w.mediaQueue.GetQueue()
+	if len(songs) == 0 {
+		w.showMessage("Queue is empty", 3, -1, false)
+		return
+	}
+	input := modal.NewInput("Save queue as playlist", "Name", func(name string) {
+		playlist, err := w.mediaItems.CreatePlaylist(name, songs)
+		if err != nil {
+			w.playlistEditFailed(err)
+			return
+		}
+		w.playlistSaved(nil, playlist)
+	})
+	input.SetDoneFunc(w.wrapCloseModal(input))
+	w.showModal(input, 5, 50, false)
+}
+
+// OverwritePlaylistWithQueue asks user to select playlist and replaces its songs with queue.
+func (w *Window) OverwritePlaylistWithQueue() {
+	songs := w.mediaQueue.GetQueue()
+	if len(songs) == 0 {
+		w.showMessage("Queue is empty", 3, -1, false)
+		return
+	}
+	playlists, err := w.mediaItems.GetPlaylists()
+	if err != nil {
+		logrus.Errorf("get playlists: %v", err)
+		return
+	}
+	if len(playlists) == 0 {
+		w.showMessage("No playlists", 3, -1, false)
+		return
+	}
+
+	picker := newPlaylistPicker("Overwrite playlist with queue", "Overwrite", false, playlists,
+		func(playlist *models.Playlist, _ string) {
+			confirm := modal.NewConfirm("Overwrite playlist", func(accepted bool) {
+				if !accepted {
+					return
+				}
+				saved, err := w.mediaItems.SetPlaylistSongs(playlist, songs)
+				if err != nil {
+					w.playlistEditFailed(err)
+					return
+				}
+				w.playlistSaved(playlist, saved)
+			})
+			confirm.SetQuestion(fmt.Sprintf("Replace %d songs in playlist '%s' with %d songs in queue?",
+				playlist.SongCount, playlist.Name, len(songs)))
+			confirm.SetDoneFunc(w.wrapCloseModal(confirm))
+			w.showModal(confirm, 8, 50, false)
+		})
+	picker.SetDoneFunc(w.wrapCloseModal(picker))
+	w.showModal(picker, 8, 60, false)
+}
+
+// playlistSaved updates views after songs were saved to new or existing playlist, and notifies user
+// if songs were saved to local playlist because server could not save them.
+func (w *Window) playlistSaved(original, saved *models.Playlist) {
+	w.playlistChanged(saved, -1)
+	if saved.IsLocal() && (original == nil || !original.IsLocal()) {
+		w.showMessage(fmt.Sprintf("Server could not save playlist,\n'%s' is saved locally only", saved.Name),
+			4, -1, false)
+	}
+}
+
+// playlistChanged updates views that show given playlist. If selected is not -1, select song in that index.
+func (w *Window) playlistChanged(playlist *models.Playlist, selected int) {
+	if w.playlist.playlist != nil && w.playlist.playlist.Id == playlist.Id {
+		if selected == -1 {
+			selected = w.playlist.list.GetSelectedIndex()
+		}
+		w.playlist.SetPlaylist(playlist)
+		if len(playlist.Songs) > 0 {
+			w.playlist.list.SetSelected(limit(selected, 0, len(playlist.Songs)-1))
+		}
+	}
+	w.updatePlaylists()
+}
+
+// updatePlaylists updates playlist count and list of playlists.
+func (w *Window) updatePlaylists() {
+	playlists, err := w.mediaItems.GetPlaylists()
+	if err != nil {
+		logrus.Errorf("get playlists: %v", err)
+		return
+	}
+	w.mediaNav.SetCount(MediaPlaylists, len(playlists))
+	w.playlists.SetPlaylists(playlists)
+}
+
+func (w *Window) playlistEditFailed(err error) {
+	logrus.Errorf("edit playlist: %v", err)
+	w.showMessage(fmt.Sprintf("Could not edit playlist:\n%v", err), 8, -1, false)
 }
 
 func (w *Window) ViewAlbumArtist(album *models.Album) {
@@ -30,7 +30,7 @@ type History struct {
 }
 
 func NewHistory() *History {
-	h := &History{NewQueue()}
+	h := &History{NewQueue(nil)}
 	h.printDescription()
 	return h
 }
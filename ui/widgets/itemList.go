@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"github.com/gdamore/tcell"
 	"gitlab.com/tslocum/cview"
+	sortpkg "sort"
 	"strings"
 	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/twidgets"
@@ -42,6 +43,10 @@ const stripCharacters = "-+_.:,;&#%!'"
 // with items original index, not reduced index.
 // If there are external changes to item list (such as paging, refresh etc),
 // call itemList.resetReduce to reset reducer state.
+//
+// To enable marking multiple items for bulk operations, set itemList.multiSelect = true.
+// Space toggles selected item and shift+up / shift+down marks a range of items. Filter is then
+// activated with '/' only. Use itemList.selection to get marked items.
 type itemList struct {
 	*twidgets.Banner
 	*previous
@@ -63,6 +68,14 @@ type itemList struct {
 	reduceIndices     []int
 
 	listSelectFunc func(index int)
+
+	multiSelect bool
+	// marked has indices of marked items in items
+	marked map[int]bool
+	// markAnchor is list index where marking a range started, -1 if not marking range
+	markAnchor int
+	// inputCaptures handle list input after marking items, until one of them consumes event
+	inputCaptures []func(event *tcell.EventKey) *tcell.EventKey
 }
 
 func newItemList(listSelectfunc func(index int)) *itemList {
@@ -73,6 +86,8 @@ func newItemList(listSelectfunc func(index int)) *itemList {
 		description: cview.NewTextView(),
 		prevBtn:     newButton("Back"),
 		prevFunc:    nil,
+		marked:      map[int]bool{},
+		markAnchor:  -1,
 	}
 
 	itemList.list = twidgets.NewScrollList(itemList.selectitem)
@@ -104,9 +119,27 @@ func newItemList(listSelectfunc func(index int)) *itemList {
 	itemList.prevBtn.SetSelectedFunc(itemList.goBack)
 
 	itemList.list.PreInputHandler = itemList.InputHandler()
+	itemList.list.SetInputCapture(itemList.captureInput)
 	return itemList
 }
 
+// addInputCapture adds handler for list input. Handler receives events that are not used for marking items
+// or consumed by handlers added before it.
+func (i *itemList) addInputCapture(capture func(event *tcell.EventKey) *tcell.EventKey) {
+	i.inputCaptures = append(i.inputCaptures, capture)
+}
+
+func (i *itemList) captureInput(event *tcell.EventKey) *tcell.EventKey {
+	event = i.markInput(event)
+	for _, capture := range i.inputCaptures {
+		if event == nil {
+			return nil
+		}
+		event = capture(event)
+	}
+	return event
+}
+
 // init context menu list. Context menu list has to contain at least one item
 // before calling this.
 func (i *itemList) initContextMenuList() {
@@ -121,7 +154,7 @@ func (i *itemList) initContextMenuList() {
 func (i *itemList) InputHandler() func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
 	return func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
 		r := event.Rune()
-		if r == ' ' {
+		if (r == ' ' && !i.multiSelect) || r == '/' {
 			if i.reduceEnabled && config.AppConfig.Gui.EnableResultsFiltering {
 				if i.setReducerVisible != nil {
 					i.setReducerVisible(true)
@@ -195,6 +228,7 @@ func (i *itemList) reduce(input string) {
 	}
 	i.list.Clear()
 	i.list.AddItems(items...)
+	i.markAnchor = -1
 	i.reduceIndices = indices
 	i.reduceInput.SetLabel(fmt.Sprintf("Filter (%d)", len(items)))
 }
@@ -226,6 +260,7 @@ func (i *itemList) resetReduce() {
 		i.reduceInput.SetLabel("Filter")
 		i.list.Clear()
 		i.list.AddItems(i.items...)
+		i.markAnchor = -1
 		i.setReducerVisible(false)
 		i.reduceVisible = false
 	}
@@ -240,11 +275,100 @@ func (i *itemList) selectitem(index int) {
 }
 
 func (i *itemList) getSelectedIndex() int {
-	var index int
+	return i.itemIndex(i.list.GetSelectedIndex())
+}
+
+// itemIndex returns index in items for index in list. These differ when list is filtered.
+func (i *itemList) itemIndex(listIndex int) int {
 	if i.reduceVisible {
-		index = i.reduceIndices[index]
+		return i.reduceIndices[listIndex]
+	}
+	return listIndex
+}
+
+// listLen returns number of items visible in list.
+func (i *itemList) listLen() int {
+	if i.reduceVisible {
+		return len(i.reduceIndices)
+	}
+	return len(i.items)
+}
+
+// markInput marks items for bulk operations. Space toggles selected item, shift+up / shift+down marks range
+// and escape clears marked items.
+func (i *itemList) markInput(event *tcell.EventKey) *tcell.EventKey {
+	if !i.multiSelect || i.listLen() == 0 {
+		return event
+	}
+	key := event.Key()
+	current := i.list.GetSelectedIndex()
+	if (key == tcell.KeyUp || key == tcell.KeyDown) && event.Modifiers()&tcell.ModShift != 0 {
+		next := current + 1
+		if key == tcell.KeyUp {
+			next = current - 1
+		}
+		next = limit(next, 0, i.listLen()-1)
+		if i.markAnchor == -1 {
+			i.markAnchor = current
+			i.setMarked(i.itemIndex(current), true)
+		}
+		if abs(next-i.markAnchor) > abs(current-i.markAnchor) {
+			i.setMarked(i.itemIndex(next), true)
+		} else if next != current {
+			// moving back towards anchor shrinks range
+			i.setMarked(i.itemIndex(current), false)
+		}
+		i.list.SetSelected(next)
+		return nil
+	}
+
+	i.markAnchor = -1
+	switch {
+	case key == tcell.KeyRune && event.Rune() == ' ':
+		index := i.itemIndex(current)
+		i.setMarked(index, !i.marked[index])
+		return nil
+	case key == tcell.KeyEscape && len(i.marked) > 0:
+		i.clearMarked()
+		return nil
+	}
+	return event
+}
+
+func (i *itemList) setMarked(index int, marked bool) {
+	if marked {
+		i.marked[index] = true
 	} else {
-		index = i.list.GetSelectedIndex()
+		delete(i.marked, index)
+	}
+	if item, ok := i.items[index].(interface{ setMarked(bool) }); ok {
+		item.setMarked(marked)
+	}
+}
+
+// clearMarked unmarks all items.
+func (i *itemList) clearMarked() {
+	for index := range i.marked {
+		if index < len(i.items) {
+			i.setMarked(index, false)
+		}
+	}
+	i.marked = map[int]bool{}
+	i.markAnchor = -1
+}
+
+// selection returns indices of marked items in ascending order. If no item is marked, selected item is returned.
+func (i *itemList) selection() []int {
+	if len(i.marked) == 0 {
+		if i.listLen() == 0 {
+			return []int{}
+		}
+		return []int{i.getSelectedIndex()}
+	}
+	indices := make([]int, 0, len(i.marked))
+	for index := range i.marked {
+		indices = append(indices, index)
 	}
-	return index
+	sortpkg.Ints(indices)
+	return indices
 }
@@ -19,6 +19,7 @@
 package widgets
 
 import (
+	"github.com/gdamore/tcell"
 	"gitlab.com/tslocum/cview"
 	"reflect"
 	"testing"
@@ -122,6 +123,74 @@ func Test_itemList_reduce(t *testing.T) {
 	}
 }
 
+func Test_itemList_markInput(t *testing.T) {
+	list := newItemList(nil)
+	list.multiSelect = true
+	for i := 0; i < 5; i++ {
+		list.items = append(list.items, newTestListItem(i))
+	}
+	list.list.AddItems(list.items...)
+
+	input := func(key tcell.Key, r rune, mod tcell.ModMask) {
+		if list.markInput(tcell.NewEventKey(key, r, mod)) != nil {
+			t.Errorf("key %v not handled", key)
+		}
+	}
+	wantSelection := func(want []int, msg string) {
+		if got := list.selection(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: selection, got: %v, want: %v", msg, got, want)
+		}
+	}
+
+	wantSelection([]int{0}, "no marked items")
+
+	input(tcell.KeyDown, 0, tcell.ModShift)
+	input(tcell.KeyDown, 0, tcell.ModShift)
+	wantSelection([]int{0, 1, 2}, "mark range")
+	input(tcell.KeyUp, 0, tcell.ModShift)
+	wantSelection([]int{0, 1}, "shrink range")
+
+	list.list.SetSelected(3)
+	input(tcell.KeyRune, ' ', tcell.ModNone)
+	wantSelection([]int{0, 1, 3}, "toggle item")
+	input(tcell.KeyRune, ' ', tcell.ModNone)
+	wantSelection([]int{0, 1}, "toggle item again")
+
+	input(tcell.KeyEscape, 0, tcell.ModNone)
+	wantSelection([]int{3}, "clear marked items")
+}
+
+func Test_itemList_captureInput(t *testing.T) {
+	list := newItemList(nil)
+	list.multiSelect = true
+	list.items = append(list.items, newTestListItem(0), newTestListItem(1))
+	list.list.AddItems(list.items...)
+
+	var first, second []tcell.Key
+	list.addInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		first = append(first, event.Key())
+		if event.Key() == tcell.KeyEnter {
+			return nil
+		}
+		return event
+	})
+	list.addInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		second = append(second, event.Key())
+		return event
+	})
+
+	list.captureInput(tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone))
+	list.captureInput(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	list.captureInput(tcell.NewEventKey(tcell.KeyDelete, 0, tcell.ModNone))
+
+	if want := []tcell.Key{tcell.KeyEnter, tcell.KeyDelete}; !reflect.DeepEqual(first, want) {
+		t.Errorf("first capture, got: %v, want: %v", first, want)
+	}
+	if want := []tcell.Key{tcell.KeyDelete}; !reflect.DeepEqual(second, want) {
+		t.Errorf("second capture, got: %v, want: %v", second, want)
+	}
+}
+
 // some benchmarks for filtering results.
 // Intel i7-2600K @ 3.40GHz
 // Name											   total rounds        time
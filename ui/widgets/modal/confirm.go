@@ -0,0 +1,101 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package modal
+
+import (
+	"github.com/gdamore/tcell"
+	"gitlab.com/tslocum/cview"
+	"tryffel.net/go/jellycli/config"
+)
+
+// Confirm asks user to accept or decline. Enter or 'y' accepts, escape or 'n' declines.
+// Answer is passed to answer function after modal has been closed.
+type Confirm struct {
+	*cview.TextView
+	visible  bool
+	closeCb  func()
+	answerCb func(accepted bool)
+}
+
+func NewConfirm(title string, answerFunc func(accepted bool)) *Confirm {
+	c := &Confirm{
+		TextView: cview.NewTextView(),
+		answerCb: answerFunc,
+	}
+
+	colors := config.Color.Modal
+	c.SetBackgroundColor(colors.Background)
+	c.SetBorder(true)
+	c.SetTitle(title)
+	c.SetBorderColor(config.Color.Border)
+	c.SetTitleColor(config.Color.TextSecondary)
+	c.SetTextColor(colors.Text)
+	c.SetBorderPadding(0, 1, 2, 2)
+	return c
+}
+
+// SetQuestion sets question text. Key hints are appended to it.
+func (c *Confirm) SetQuestion(text string) {
+	c.SetText(text + "\n\n[Enter/y] Yes    [Esc/n] No")
+}
+
+func (c *Confirm) SetDoneFunc(doneFunc func()) {
+	c.closeCb = doneFunc
+}
+
+func (c *Confirm) View() cview.Primitive {
+	return c
+}
+
+func (c *Confirm) SetVisible(visible bool) {
+	c.visible = visible
+}
+
+func (c *Confirm) Focus(delegate func(p cview.Primitive)) {
+	c.TextView.SetBorderColor(config.Color.BorderFocus)
+	c.TextView.Focus(delegate)
+}
+
+func (c *Confirm) Blur() {
+	c.TextView.SetBorderColor(config.Color.Border)
+	c.TextView.Blur()
+}
+
+func (c *Confirm) answer(accepted bool) {
+	if c.closeCb != nil {
+		c.closeCb()
+	}
+	if c.answerCb != nil {
+		c.answerCb(accepted)
+	}
+}
+
+func (c *Confirm) InputHandler() func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
+		key := event.Key()
+		switch {
+		case key == tcell.KeyEnter || key == tcell.KeyRune && (event.Rune() == 'y' || event.Rune() == 'Y'):
+			c.answer(true)
+		case key == tcell.KeyEscape || key == tcell.KeyRune && (event.Rune() == 'n' || event.Rune() == 'N'):
+			c.answer(false)
+		default:
+			c.TextView.InputHandler()(event, setFocus)
+		}
+	}
+}
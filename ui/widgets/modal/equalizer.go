@@ -0,0 +1,234 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package modal
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"gitlab.com/tslocum/cview"
+	"math"
+	"strings"
+	"tryffel.net/go/jellycli/config"
+)
+
+// Equalizer is a modal for adjusting equalizer bands live. Changes are stored to config.AppConfig.
+type Equalizer struct {
+	*cview.TextView
+	visible bool
+	closeCb func()
+
+	enabled  bool
+	preset   string
+	gains    []float64
+	selected int
+
+	// changedFunc is called every time equalizer is changed
+	changedFunc func(enabled bool, gains []float64)
+}
+
+func NewEqualizer(changedFunc func(enabled bool, gains []float64)) *Equalizer {
+	e := &Equalizer{
+		TextView:    cview.NewTextView(),
+		changedFunc: changedFunc,
+	}
+
+	colors := config.Color.Modal
+	e.SetBackgroundColor(colors.Background)
+	e.SetBorder(true)
+	e.SetTitle("Equalizer")
+	e.SetBorderColor(config.Color.Border)
+	e.SetTitleColor(config.Color.TextSecondary)
+	e.SetTextColor(colors.Text)
+	e.SetDynamicColors(true)
+	e.SetBorderPadding(0, 1, 2, 2)
+	e.Load()
+	return e
+}
+
+func (e *Equalizer) SetDoneFunc(doneFunc func()) {
+	e.closeCb = doneFunc
+}
+
+func (e *Equalizer) View() cview.Primitive {
+	return e
+}
+
+func (e *Equalizer) SetVisible(visible bool) {
+	e.visible = visible
+}
+
+func (e *Equalizer) Focus(delegate func(p cview.Primitive)) {
+	e.TextView.SetBorderColor(config.Color.BorderFocus)
+	e.TextView.Focus(delegate)
+}
+
+func (e *Equalizer) Blur() {
+	e.TextView.SetBorderColor(config.Color.Border)
+	e.TextView.Blur()
+}
+
+// Load reads equalizer settings from config.
+func (e *Equalizer) Load() {
+	conf := config.AppConfig.Player
+	e.enabled = conf.EqualizerEnabled
+	e.preset = conf.EqualizerPreset
+	e.gains = append([]float64{}, config.EqualizerGains(e.preset, conf.EqualizerBands)...)
+	e.setContent()
+}
+
+func (e *Equalizer) InputHandler() func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
+		key := event.Key()
+		switch key {
+		case tcell.KeyEscape:
+			e.closeCb()
+		case tcell.KeyUp:
+			e.selectBand(e.selected - 1)
+		case tcell.KeyDown:
+			e.selectBand(e.selected + 1)
+		case tcell.KeyLeft:
+			e.adjust(-1)
+		case tcell.KeyRight:
+			e.adjust(1)
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'k':
+				e.selectBand(e.selected - 1)
+			case 'j':
+				e.selectBand(e.selected + 1)
+			case 'h':
+				e.adjust(-1)
+			case 'l':
+				e.adjust(1)
+			case '0':
+				e.adjust(-e.gains[e.selected])
+			case 'e':
+				e.enabled = !e.enabled
+				e.apply()
+			case 'p':
+				e.nextPreset()
+			}
+		}
+	}
+}
+
+func (e *Equalizer) selectBand(index int) {
+	if index < 0 || index >= len(e.gains) {
+		return
+	}
+	e.selected = index
+	e.setContent()
+}
+
+// adjust changes gain of selected band. Modifying any preset switches to custom preset.
+func (e *Equalizer) adjust(delta float64) {
+	gain := e.gains[e.selected] + delta
+	if gain > config.EqualizerMaxGaindB {
+		gain = config.EqualizerMaxGaindB
+	} else if gain < -config.EqualizerMaxGaindB {
+		gain = -config.EqualizerMaxGaindB
+	}
+	if gain == e.gains[e.selected] {
+		return
+	}
+	e.gains[e.selected] = gain
+	e.preset = config.EqualizerPresetCustom
+	e.enabled = true
+	e.apply()
+}
+
+func (e *Equalizer) nextPreset() {
+	index := 0
+	for i, v := range config.EqualizerPresetNames {
+		if v == e.preset {
+			index = (i + 1) % len(config.EqualizerPresetNames)
+			break
+		}
+	}
+	e.preset = config.EqualizerPresetNames[index]
+	e.gains = append([]float64{}, config.EqualizerGains(e.preset, config.AppConfig.Player.EqualizerBands)...)
+	e.enabled = true
+	e.apply()
+}
+
+// apply stores settings to config and applies them to player
+func (e *Equalizer) apply() {
+	conf := &config.AppConfig.Player
+	conf.EqualizerEnabled = e.enabled
+	conf.EqualizerPreset = e.preset
+	if e.preset == config.EqualizerPresetCustom {
+		conf.EqualizerBands = append([]float64{}, e.gains...)
+	}
+	e.setContent()
+	if e.changedFunc != nil {
+		e.changedFunc(e.enabled, append([]float64{}, e.gains...))
+	}
+}
+
+func (e *Equalizer) setContent() {
+	state := "off"
+	if e.enabled {
+		state = "on"
+	}
+	text := fmt.Sprintf("Equalizer: [yellow]%s[-]   Preset: [yellow]%s[-]\n\n", state, e.preset)
+
+	for i, gain := range e.gains {
+		line := fmt.Sprintf("%7s %s %+5.1f dB", frequencyName(config.EqualizerFrequencies[i]), gainBar(gain), gain)
+		if i == e.selected {
+			line = "[yellow]> " + line + "[-]"
+		} else {
+			line = "  " + line
+		}
+		text += line + "\n"
+	}
+
+	text += "\nUp/Down: select band, Left/Right: adjust band\n" +
+		"e: enable / disable, p: next preset, 0: reset band\n" +
+		"Escape: save and close"
+	e.SetText(text)
+}
+
+func frequencyName(frequency float64) string {
+	if frequency >= 1000 {
+		return fmt.Sprintf("%.0f kHz", frequency/1000)
+	}
+	return fmt.Sprintf("%.0f Hz", frequency)
+}
+
+// gainBar draws horizontal bar for gain, centered at 0 dB
+func gainBar(gain float64) string {
+	steps := int(math.Round(gain))
+	bar := strings.Builder{}
+	for i := -config.EqualizerMaxGaindB; i < 0; i++ {
+		if steps <= i {
+			bar.WriteRune('█')
+		} else {
+			bar.WriteRune('─')
+		}
+	}
+	bar.WriteRune('│')
+	for i := 1; i <= config.EqualizerMaxGaindB; i++ {
+		if steps >= i {
+			bar.WriteRune('█')
+		} else {
+			bar.WriteRune('─')
+		}
+	}
+	return bar.String()
+}
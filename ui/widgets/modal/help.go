@@ -160,14 +160,18 @@ func (h *Help) shortcutsPage() string {
 * Open context menu: Alt+Enter
 * Close application: Ctrl-C
 * Filter list items: 
-	activate list with Key Up / Key Down, then press Whitespace ' ' 
+	activate list with Key Up / Key Down, then press '/' (or Whitespace ' ' in lists without selection)
     to activate filter. Start typing and see list items reducing. Press enter to activate list again
 	and press ESC to cancel filter and return to original list.
+* Select multiple songs: 
+	Whitespace ' ' toggles song, Shift+Up / Shift+Down selects range and ESC clears selection.
+	Context menu actions (add to queue, play next, add to playlist, favorite etc.) apply to selected songs.
 
 [yellow]Queue[-]:
-* Delete song: Del
-* Move up song: Ctrl-K
-* Move down song: Ctrl-J
+* Delete selected songs: Del
+* Move up selected songs: Ctrl-K
+* Move down selected songs: Ctrl-J
+* Undo / redo queue edit: Ctrl-Z / Ctrl-Y
 * Clear queue with 'clear'. This does not remove current song
 
 
@@ -178,9 +182,24 @@ You can use mouse (if enabled) to navigate in application.
 
 [yellow]Audio[-]:
 * Shuffle: %s
+* Shuffle mode (random / album / artist / weighted): %s
+* Repeat (off / all / one): %s
 * Mute: %s
+* Equalizer: %s
+* Playback speed up / down: %s / %s
+* Sleep timer (15 / 30 / 60 min / end of track / end of album / off): %s
+* Show / hide spectrum: %s
+* Next quality profile: %s
 `, util.PackKeyBindingName(config.KeyBinds.Global.Shuffle, 20),
+		util.PackKeyBindingName(config.KeyBinds.Global.ShuffleMode, 20),
+		util.PackKeyBindingName(config.KeyBinds.Global.Repeat, 20),
 		util.PackKeyBindingName(config.KeyBinds.Global.MuteUnmute, 20),
+		util.PackKeyBindingName(config.KeyBinds.NavigationBar.Equalizer, 20),
+		util.PackKeyBindingName(config.KeyBinds.Global.SpeedUp, 20),
+		util.PackKeyBindingName(config.KeyBinds.Global.SpeedDown, 20),
+		util.PackKeyBindingName(config.KeyBinds.Global.SleepTimer, 20),
+		util.PackKeyBindingName(config.KeyBinds.Global.Spectrum, 20),
+		util.PackKeyBindingName(config.KeyBinds.Global.Quality, 20),
 	)
 }
 
@@ -227,6 +246,13 @@ func (h *Help) statsPage() string {
 	text += fmt.Sprintf("Memory allocated: %s",
 		h.stats.HeapString())
 
+	playback := h.stats.Playback
+	text += "\n\n[yellow]Playback[-]\n"
+	text += fmt.Sprintf("Decode buffer: %.1f s / %.1f s (%d %%)\nNext song buffered: %.1f s\n"+
+		"Underruns: %d (%.1f s of silence)",
+		playback.Buffered.Seconds(), playback.BufferSize.Seconds(), playback.BufferHealth(),
+		playback.NextBuffered.Seconds(), playback.Underruns, playback.UnderrunTime.Seconds())
+
 	text += "\n\n[yellow]Local storage[-]\n"
 	text += fmt.Sprintf("Database file: %s\nDatabase size: %s\nLast updated: %s",
 		h.stats.StorageInfo.DbFile,
@@ -256,6 +282,8 @@ Source code: https://github.com/tryffel/jellycli
 [yellow::b]Features [-:-:-]
 * View artists, songs, albums, playlists, favorite artists and albums, genres, similar albums and artists
 * Queue: add songs and albums, reorder & delete songs, clear queue
+* Edit playlists: create, rename, delete, add, remove and reorder songs
+* Save queue as playlist and load playlist to queue. Playlists are saved locally if server cannot save them
 * Control (and view) play state through Dbus integration
 * Remote control over Jellyfin server. Currently implemented:
     * [x] Play / pause / stop
@@ -263,8 +291,16 @@ Source code: https://github.com/tryffel/jellycli
     * [x] Next/previous track
     * [x] Control queue
 	* [x] Shuffle
-    * [ ] Seeking, see (https://github.com/tryffel/jellycli/issues/8
-* Supported formats (server transcodes everything else to mp3): mp3,ogg,flac,wav
+	* [x] Repeat
+    * [x] Seeking
+* Equalizer with presets
+* Playback speed 0.5x - 2x, pitch is preserved
+* Shuffle modes: random, albums, artists spread apart, weighted by favorites and play count
+* Sleep timer with fade-out
+* Resume previous session on start
+* Audio spectrum in status bar
+* Quality profiles for transcoding on server
+* Supported formats (server transcodes everything else, e.g. opus and aac): mp3, ogg (vorbis), flac, wav
 * headless mode (--no-gui)
 
 Platforms tested:
@@ -0,0 +1,91 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package modal
+
+import (
+	"github.com/gdamore/tcell"
+	"gitlab.com/tslocum/cview"
+	"strings"
+	"tryffel.net/go/jellycli/config"
+)
+
+// Input asks user for single line of text. Enter accepts, escape cancels. Accepted text is passed to
+// accept function after modal has been closed. Empty text is not accepted.
+type Input struct {
+	*cview.InputField
+	visible  bool
+	closeCb  func()
+	acceptCb func(text string)
+}
+
+func NewInput(title, label string, acceptFunc func(text string)) *Input {
+	i := &Input{
+		InputField: cview.NewInputField(),
+		acceptCb:   acceptFunc,
+	}
+
+	colors := config.Color.Modal
+	i.SetBackgroundColor(colors.Background)
+	i.SetBorder(true)
+	i.SetTitle(title)
+	i.SetBorderColor(config.Color.Border)
+	i.SetTitleColor(config.Color.TextSecondary)
+	i.SetBorderPadding(1, 1, 2, 2)
+	i.SetLabel(label + " ")
+	i.SetLabelColor(colors.Text)
+	i.SetFieldBackgroundColor(config.Color.BackgroundSelected)
+	i.SetFieldTextColor(config.Color.TextSelected)
+	i.InputField.SetDoneFunc(i.done)
+	return i
+}
+
+func (i *Input) SetDoneFunc(doneFunc func()) {
+	i.closeCb = doneFunc
+}
+
+func (i *Input) View() cview.Primitive {
+	return i
+}
+
+func (i *Input) SetVisible(visible bool) {
+	i.visible = visible
+}
+
+func (i *Input) Focus(delegate func(p cview.Primitive)) {
+	i.InputField.SetBorderColor(config.Color.BorderFocus)
+	i.InputField.Focus(delegate)
+}
+
+func (i *Input) Blur() {
+	i.InputField.SetBorderColor(config.Color.Border)
+	i.InputField.Blur()
+}
+
+func (i *Input) done(key tcell.Key) {
+	if key != tcell.KeyEnter && key != tcell.KeyEscape {
+		return
+	}
+	text := strings.TrimSpace(i.GetText())
+	if i.closeCb != nil {
+		i.closeCb()
+	}
+	if key == tcell.KeyEnter && text != "" && i.acceptCb != nil {
+		i.acceptCb(text)
+	}
+}
@@ -28,6 +28,18 @@ import (
 	"tryffel.net/go/twidgets"
 )
 
+// playlistLoadMode tells how playlist songs are added to queue.
+type playlistLoadMode int
+
+const (
+	// playlistReplace clears queue and plays playlist
+	playlistReplace playlistLoadMode = iota
+	// playlistAppend adds songs to the end of queue
+	playlistAppend
+	// playlistPlayNext adds songs right after current song
+	playlistPlayNext
+)
+
 // AlbumView shows user a header (album name, info, buttons) and list of songs
 type PlaylistView struct {
 	*itemList
@@ -58,7 +70,7 @@ func NewPlaylistView(playSong func(song *models.Song), playSongs func(songs []*m
 	p.itemList = newItemList(p.playSong)
 	p.list.ItemHeight = 2
 	p.list.Padding = 1
-	p.list.SetInputCapture(p.listHandler)
+	p.addInputCapture(p.listHandler)
 	p.list.Grid.SetColumns(1, -1)
 
 	p.playBtn.SetSelectedFunc(p.playAll)
@@ -102,6 +114,38 @@ func NewPlaylistView(playSong func(song *models.Song), playSongs func(songs []*m
 				p.context.InstantMix(song.song)
 			}
 		})
+		p.addSongActions(p.context, func() []*albumSong { return p.songs })
+		p.list.AddContextItem("Remove from playlist", 0, func(index int) {
+			selected := p.selection()
+			p.clearMarked()
+			p.context.RemovePlaylistSongs(p.playlist, selected)
+		})
+		p.list.AddContextItem("Move up", 0, func(index int) {
+			selected := p.getSelectedIndex()
+			if selected > 0 {
+				p.context.MovePlaylistSong(p.playlist, selected, selected-1)
+			}
+		})
+		p.list.AddContextItem("Move down", 0, func(index int) {
+			selected := p.getSelectedIndex()
+			if selected < len(p.songs)-1 {
+				p.context.MovePlaylistSong(p.playlist, selected, selected+1)
+			}
+		})
+
+		p.multiSelect = true
+
+		p.options.AddOption("Replace queue", func() {
+			p.context.LoadPlaylist(p.playlist, playlistReplace)
+		})
+
+		p.options.AddOption("Add to queue", func() {
+			p.context.LoadPlaylist(p.playlist, playlistAppend)
+		})
+
+		p.options.AddOption("Play next", func() {
+			p.context.LoadPlaylist(p.playlist, playlistPlayNext)
+		})
 
 		p.options.AddOption("Instant mix", func() {
 			p.context.InstantMix(p.playlist)
@@ -110,6 +154,14 @@ func NewPlaylistView(playSong func(song *models.Song), playSongs func(songs []*m
 		p.options.AddOption("Open in browser", func() {
 			p.context.OpenInBrowser(p.playlist)
 		})
+
+		p.options.AddOption("Rename", func() {
+			p.context.RenamePlaylist(p.playlist)
+		})
+
+		p.options.AddOption("Delete", func() {
+			p.context.DeletePlaylist(p.playlist)
+		})
 	}
 
 	p.list.ContextMenuList().SetBorder(true)
@@ -123,6 +175,7 @@ func NewPlaylistView(playSong func(song *models.Song), playSongs func(songs []*m
 }
 
 func (p *PlaylistView) SetPlaylist(playlist *models.Playlist) {
+	p.clearMarked()
 	p.list.Clear()
 	p.resetReduce()
 	p.playlist = playlist
@@ -0,0 +1,140 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package widgets
+
+import (
+	"github.com/gdamore/tcell"
+	"gitlab.com/tslocum/cview"
+	"strings"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+// playlistPicker is a modal for selecting playlist. If new playlists are allowed, filling name creates new
+// playlist instead.
+type playlistPicker struct {
+	*cview.Form
+	visible bool
+	closeCb func()
+
+	playlists []*models.Playlist
+	playlist  *cview.DropDown
+	selected  int
+	name      *cview.InputField
+
+	// selectFunc is called with either selected playlist or name for new playlist
+	selectFunc func(playlist *models.Playlist, newName string)
+}
+
+func (p *playlistPicker) SetDoneFunc(doneFunc func()) {
+	p.closeCb = doneFunc
+}
+
+func (p *playlistPicker) View() cview.Primitive {
+	return p
+}
+
+func (p *playlistPicker) SetVisible(visible bool) {
+	p.visible = visible
+}
+
+func newPlaylistPicker(title, action string, allowNew bool, playlists []*models.Playlist,
+	selectFunc func(playlist *models.Playlist, newName string)) *playlistPicker {
+	p := &playlistPicker{
+		Form:       cview.NewForm(),
+		playlists:  playlists,
+		playlist:   cview.NewDropDown(),
+		name:       cview.NewInputField(),
+		selectFunc: selectFunc,
+	}
+
+	p.SetTitle(" " + title + " ")
+	p.SetBackgroundColor(config.Color.Modal.Background)
+	p.SetBorder(true)
+
+	p.playlist.SetLabel("Playlist")
+	for i, v := range playlists {
+		index := i
+		p.playlist.AddOption(v.Name, func() {
+			p.selected = index
+		})
+	}
+	if len(playlists) > 0 {
+		p.playlist.SetCurrentOption(0)
+		p.AddFormItem(p.playlist)
+	}
+
+	if allowNew {
+		p.name.SetLabel("New playlist")
+		p.name.SetPlaceholder("name")
+		p.name.SetPlaceholderTextColor(config.Color.TextDisabled)
+		p.name.SetFieldTextColor(config.Color.Text)
+		p.AddFormItem(p.name)
+		p.name.SetInputCapture(p.inputCapture)
+	}
+
+	p.AddButton(action, p.ok)
+	p.AddButton("Cancel", p.cancel)
+
+	p.GetButton(0).SetInputCapture(p.inputCapture)
+	p.GetButton(1).SetInputCapture(p.inputCapture)
+
+	p.SetCancelFunc(p.cancel)
+	return p
+}
+
+func (p *playlistPicker) ok() {
+	name := strings.TrimSpace(p.name.GetText())
+	index := p.selected
+	p.cancel()
+	if p.selectFunc == nil {
+		return
+	}
+	if name != "" {
+		p.selectFunc(nil, name)
+	} else if index >= 0 && index < len(p.playlists) {
+		p.selectFunc(p.playlists[index], "")
+	}
+}
+
+func (p *playlistPicker) cancel() {
+	if p.closeCb != nil {
+		p.closeCb()
+	}
+}
+
+func (p *playlistPicker) InputHandler() func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
+		if event.Key() == tcell.KeyEscape {
+			p.cancel()
+			return
+		}
+		p.Form.InputHandler()(event, setFocus)
+	}
+}
+
+func (p *playlistPicker) inputCapture(e *tcell.EventKey) *tcell.EventKey {
+	switch e.Key() {
+	case tcell.KeyUp:
+		return tcell.NewEventKey(tcell.KeyBacktab, e.Rune(), e.Modifiers())
+	case tcell.KeyDown:
+		return tcell.NewEventKey(tcell.KeyTab, e.Rune(), e.Modifiers())
+	}
+	return e
+}
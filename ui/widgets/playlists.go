@@ -54,6 +54,9 @@ func NewPlaylistCover(index int, playlist *models.Playlist) *PlaylistCover {
 	ar := printArtists(a.artists, 40)
 	text := fmt.Sprintf("%d. %s\n%d songs, %s", index, playlist.Name,
 		playlist.SongCount, util.SecToStringApproximate(playlist.Duration))
+	if playlist.IsLocal() {
+		text += " (local)"
+	}
 	if ar != "" {
 		text += "\n" + ar
 	}
@@ -38,21 +38,28 @@ type Queue struct {
 	playSongsFunc func(songs []*models.Song)
 
 	controller interfaces.QueueController
+	// songErrorFunc returns why song could not be played, if it has failed
+	songErrorFunc func(id models.Id) error
 
 	clearBtn  *button
 	clearFunc func()
+
+	context contextOperator
+	options *dropDown
 }
 
-//NewQueue initializes new album view
-func NewQueue() *Queue {
+//NewQueue initializes new album view. If operator is nil, queue has no options.
+func NewQueue(operator contextOperator) *Queue {
 	q := &Queue{
 		itemList: newItemList(nil),
 		clearBtn: newButton("Clear"),
+		context:  operator,
+		options:  newDropDown("Options"),
 	}
 
 	q.list.ItemHeight = 2
 	q.list.Padding = 0
-	q.list.SetInputCapture(q.listHandler)
+	q.addInputCapture(q.listHandler)
 	q.list.Grid.SetColumns(1, -1)
 
 	q.clearBtn.SetSelectedFunc(q.clearQueue)
@@ -66,6 +73,38 @@ func NewQueue() *Queue {
 	q.Banner.Grid.AddItem(q.list, 4, 0, 1, 8, 4, 10, false)
 
 	selectables := []twidgets.Selectable{q.prevBtn, q.clearBtn, q.list}
+	if q.context != nil {
+		q.options.AddOption("Save as new playlist", func() {
+			q.context.SaveQueueAsPlaylist()
+		})
+		q.options.AddOption("Overwrite playlist", func() {
+			q.context.OverwritePlaylistWithQueue()
+		})
+		q.Banner.Grid.AddItem(q.options, 3, 4, 1, 1, 1, 10, false)
+		selectables = []twidgets.Selectable{q.prevBtn, q.clearBtn, q.options, q.list}
+
+		q.list.AddContextItem("Remove from queue", 0, func(index int) {
+			q.removeSelected()
+		})
+		q.list.AddContextItem("Move up", 0, func(index int) {
+			q.moveSelected(true)
+		})
+		q.list.AddContextItem("Move down", 0, func(index int) {
+			q.moveSelected(false)
+		})
+		q.list.AddContextItem("Add to playlist", 0, func(index int) {
+			songs := songsAt(q.songs, q.selection())
+			q.clearMarked()
+			q.context.AddSongsToPlaylist(songs)
+		})
+		q.list.AddContextItem("Toggle favorite", 0, func(index int) {
+			songs := songsAt(q.songs, q.selection())
+			q.clearMarked()
+			q.context.ToggleFavorite(songs)
+		})
+		q.multiSelect = true
+		q.initContextMenuList()
+	}
 	q.Banner.Selectable = selectables
 	q.printDescription()
 	return q
@@ -82,8 +121,14 @@ func (q *Queue) AddSong(song *models.Song, index int) {
 	q.list.AddItem(s)
 }
 
-// SetSongs clears current songs and sets new ones
+// SetSongs clears current songs and sets new ones. Marked songs stay marked.
 func (q *Queue) SetSongs(songs []*models.Song) {
+	marked := make(map[*models.Song]bool, len(q.marked))
+	for index := range q.marked {
+		if index < len(q.songs) {
+			marked[q.songs[index].song] = true
+		}
+	}
 	q.Clear()
 	q.songs = make([]*albumSong, len(songs))
 	items := make([]twidgets.ListItem, len(songs))
@@ -98,13 +143,21 @@ func (q *Queue) SetSongs(songs []*models.Song) {
 		q.songs[0].playing = true
 	}
 	q.list.AddItems(items...)
+	q.items = items
+	for i, v := range songs {
+		if marked[v] {
+			q.setMarked(i, true)
+		}
+	}
 	q.printDescription()
 }
 
 // Clear removes all songs
 func (q *Queue) Clear() {
+	q.clearMarked()
 	q.list.Clear()
 	q.songs = []*albumSong{}
+	q.items = []twidgets.ListItem{}
 	q.printDescription()
 }
 
@@ -125,19 +178,18 @@ func (q *Queue) listHandler(key *tcell.EventKey) *tcell.EventKey {
 	case tcell.KeyEnter:
 		return nil
 	case tcell.KeyCtrlJ:
-		if q.controller != nil {
-			index := q.list.GetSelectedIndex()
-			_ = q.controller.Reorder(index, false)
-		}
+		q.moveSelected(false)
 	case tcell.KeyCtrlK:
+		q.moveSelected(true)
+	case tcell.KeyDEL, tcell.KeyDelete:
+		q.removeSelected()
+	case tcell.KeyCtrlZ:
 		if q.controller != nil {
-			index := q.list.GetSelectedIndex()
-			_ = q.controller.Reorder(index, true)
+			q.controller.UndoQueue()
 		}
-	case tcell.KeyDEL, tcell.KeyDelete:
+	case tcell.KeyCtrlY:
 		if q.controller != nil {
-			index := q.list.GetSelectedIndex()
-			q.controller.RemoveSong(index)
+			q.controller.RedoQueue()
 		}
 	}
 	return key
@@ -154,6 +206,10 @@ func (q *Queue) updateSongText(song *albumSong) {
 	} else {
 		name = fmt.Sprintf("%d. %s", song.index, song.song.Name)
 	}
+	if q.songErrorFunc != nil && q.songErrorFunc(song.song.Id) != nil {
+		song.SetTextColor(config.Color.TextSongFailed)
+		name += " (failed)"
+	}
 
 	text := song.getAlignedDuration(name)
 	if len(song.song.Artists) > 0 {
@@ -163,6 +219,51 @@ func (q *Queue) updateSongText(song *albumSong) {
 	song.SetText(text)
 }
 
+// removeSelected removes marked songs, or selected song if no song is marked.
+func (q *Queue) removeSelected() {
+	if q.controller == nil {
+		return
+	}
+	indices := q.selection()
+	q.clearMarked()
+	q.controller.RemoveSongs(indices)
+}
+
+// moveSelected moves marked songs, or selected song if no song is marked, one step earlier or later.
+// Each continuous range of marked songs is moved separately.
+func (q *Queue) moveSelected(earlier bool) {
+	if q.controller == nil || len(q.songs) == 0 {
+		return
+	}
+	step := 1
+	if earlier {
+		step = -1
+	}
+	current := q.list.GetSelectedIndex()
+	selected := current
+	for _, r := range indexRanges(q.selection()) {
+		moved := q.controller.ReorderRange(r[0], r[1], earlier)
+		if moved && current >= r[0] && current <= r[1] {
+			selected = current + step
+		}
+	}
+	// keep moved song selected
+	q.list.SetSelected(selected)
+}
+
+// indexRanges splits sorted indices into continuous ranges of [start, end].
+func indexRanges(indices []int) [][2]int {
+	ranges := make([][2]int, 0, 1)
+	for i, v := range indices {
+		if i > 0 && v == indices[i-1]+1 {
+			ranges[len(ranges)-1][1] = v
+		} else {
+			ranges = append(ranges, [2]int{v, v})
+		}
+	}
+	return ranges
+}
+
 // call clearing queue
 func (q *Queue) clearQueue() {
 	if q.clearFunc != nil {
@@ -90,7 +90,8 @@ func NewSongList(playSong func(song *models.Song), playSongs func(songs []*model
 			song := p.songs[selected]
 			p.context.InstantMix(song.song)
 		})
-
+		p.addSongActions(p.context, func() []*albumSong { return p.songs })
+		p.multiSelect = true
 	}
 
 	p.reduceEnabled = true
@@ -100,11 +101,33 @@ func NewSongList(playSong func(song *models.Song), playSongs func(songs []*model
 	return p
 }
 
+// addSongActions adds context menu items that act on marked songs, or selected song if no song is marked.
+func (i *itemList) addSongActions(context contextOperator, songs func() []*albumSong) {
+	selected := func() []*models.Song {
+		indices := i.selection()
+		i.clearMarked()
+		return songsAt(songs(), indices)
+	}
+	i.list.AddContextItem("Add to queue", 0, func(index int) {
+		context.QueueSongs(selected(), false)
+	})
+	i.list.AddContextItem("Play next", 0, func(index int) {
+		context.QueueSongs(selected(), true)
+	})
+	i.list.AddContextItem("Add to playlist", 0, func(index int) {
+		context.AddSongsToPlaylist(selected())
+	})
+	i.list.AddContextItem("Toggle favorite", 0, func(index int) {
+		context.ToggleFavorite(selected())
+	})
+}
+
 func (s *SongList) setTitle(title string) {
 	s.title = title
 }
 
 func (s *SongList) SetSongs(songs []*models.Song, page interfaces.Paging) {
+	s.clearMarked()
 	s.list.Clear()
 	s.resetReduce()
 	s.page = page
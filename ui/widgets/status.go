@@ -22,7 +22,9 @@ import (
 	"fmt"
 	"github.com/gdamore/tcell"
 	"gitlab.com/tslocum/cview"
+	"math"
 	"sync"
+	"time"
 	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
 	"tryffel.net/go/jellycli/models"
@@ -43,11 +45,26 @@ const (
 	// yellow heart, utf8. Not visible on all editors.
 	charFavorite = "💛"
 	btnShuffle   = "Shuffle"
+	btnRepeat    = "Repeat"
+	btnRepeatOne = "Rep. 1"
 
 	btnStyleStart = "[white:red:b]"
 	btnStyleStop  = "[-:-:-]"
+
+	// spectrumBands is number of bands in spectrum, each drawn as single character
+	spectrumBands = 24
+	// spectrumDecay is how much displayed level may fall on each refresh, so that bars fall smoothly
+	spectrumDecay = 0.06
+	// spectrumPeakLevel is level after which bar is drawn with peak color
+	spectrumPeakLevel = 0.85
+
+	// statusNoticeDuration is how long playback error is shown
+	statusNoticeDuration = time.Second * 10
 )
 
+// spectrumBlocks are characters for levels in spectrum bar, from lowest to highest.
+var spectrumBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
 func btn(button string) string {
 	return btnStyleStart + button + btnStyleStop + " "
 }
@@ -71,6 +88,7 @@ type Status struct {
 	btnBackward *cview.Button
 	btnStop     *cview.Button
 	btnShuffle  *cview.Button
+	btnRepeat   *cview.Button
 
 	buttons   []*cview.Button
 	shortCuts []string
@@ -86,6 +104,15 @@ type Status struct {
 	actionCb func(state interfaces.AudioStatus)
 
 	player interfaces.Player
+
+	showSpectrum bool
+	// spectrum contains displayed levels, spectrumLevels latest levels from player
+	spectrum       []float64
+	spectrumLevels []float64
+
+	// notice is latest playback error, shown until noticeUntil
+	notice      string
+	noticeUntil time.Time
 }
 
 func (s *Status) MouseHandler() func(action cview.MouseAction, event *tcell.EventMouse, setFocus func(p cview.Primitive)) (consumed bool, capture cview.Primitive) {
@@ -95,6 +122,8 @@ func (s *Status) MouseHandler() func(action cview.MouseAction, event *tcell.Even
 func newStatus(ctrl interfaces.Player) *Status {
 	s := &Status{frame: cview.NewBox()}
 	s.player = ctrl
+	s.spectrum = make([]float64, spectrumBands)
+	s.spectrumLevels = make([]float64, spectrumBands)
 
 	colors := config.Color.Status
 	s.detailsMainColor = colors.Text
@@ -122,18 +151,20 @@ func newStatus(ctrl interfaces.Player) *Status {
 	s.btnBackward = cview.NewButton(btnBackward)
 	s.btnStop = cview.NewButton(btnStop)
 	s.btnShuffle = cview.NewButton(btnShuffle)
+	s.btnRepeat = cview.NewButton(btnRepeat)
 
 	s.progress = NewProgressBar(40, 100)
 	s.volume = NewProgressBar(10, 100)
 
 	state := interfaces.AudioStatus{
-		State:    interfaces.AudioStateStopped,
+		State:    interfaces.AudioStateIdle,
 		Song:     nil,
 		Artist:   nil,
 		Album:    nil,
 		SongPast: 0,
 		Volume:   50,
 		Muted:    false,
+		Speed:    interfaces.AudioSpeedNormal,
 	}
 
 	s.state = state
@@ -159,6 +190,8 @@ func newStatus(ctrl interfaces.Player) *Status {
 
 	s.btnShuffle.SetBackgroundColor(colors.Background)
 	s.btnShuffle.SetLabelColor(config.Color.Status.VolumeMuted)
+	s.btnRepeat.SetBackgroundColor(colors.Background)
+	s.btnRepeat.SetLabelColor(config.Color.Status.VolumeMuted)
 	return s
 }
 
@@ -173,18 +206,25 @@ func (s *Status) Draw(screen tcell.Screen) {
 		songDuration = util.SecToString(s.state.Song.Duration)
 		songDuration = " " + songDuration + " "
 	}
+	speed := ""
+	if s.state.Speed != interfaces.AudioSpeedNormal {
+		speed = fmt.Sprintf("%gx ", s.state.Speed)
+	}
+	sleep := sleepLabel(s.state.Sleep)
+	state := stateLabel(s.state.State)
 
 	volume := " Volume " + s.volume.Draw(int(s.state.Volume))
-	topRowFree := w - len(songPast) - len(songDuration) - utf8.RuneCountInString(volume) - 5
+	topRowFree := w - len(songPast) - len(songDuration) - len(speed) - len(sleep) - len(state) -
+		utf8.RuneCountInString(volume) - 5
 
 	showShuffleBtn := false
 	showShuffleSmall := false
 	if w > 100 {
 		showShuffleBtn = true
-		topRowFree -= 6
+		topRowFree -= 14
 	} else if w > 60 {
 		showShuffleSmall = true
-		topRowFree -= 3
+		topRowFree -= 6
 	}
 
 	s.progress.SetWidth(topRowFree * 10 / 11)
@@ -193,7 +233,7 @@ func (s *Status) Draw(screen tcell.Screen) {
 	defer s.lock.RUnlock()
 
 	progressBar := s.progress.Draw(s.state.SongPast.Seconds())
-	progress := songPast + progressBar + songDuration
+	progress := songPast + progressBar + songDuration + speed + sleep + state
 	progressLen := utf8.RuneCountInString(progress)
 	topX := x + 1
 	colors := config.Color.Status
@@ -228,7 +268,7 @@ func (s *Status) Draw(screen tcell.Screen) {
 		}
 	}
 	if showShuffleBtn {
-		s.btnShuffle.SetLabel("Shuffle")
+		s.btnShuffle.SetLabel(shuffleLabel(s.state.ShuffleMode))
 		shuffleX := x + w - volumeLen - 11
 		// draw two empty characters around the button the separate it from status box.
 		cview.Print(screen, "         ", shuffleX, btnY-2, 9, cview.AlignLeft, colors.Shortcuts)
@@ -237,6 +277,18 @@ func (s *Status) Draw(screen tcell.Screen) {
 		cview.Print(screen, util.PackKeyBindingName(config.KeyBinds.Global.Shuffle, 5),
 			shuffleX+3, btnY-1, shuffleX+8, cview.AlignLeft, colors.Shortcuts)
 
+		if s.state.Repeat == interfaces.RepeatModeOne {
+			s.btnRepeat.SetLabel(btnRepeatOne)
+		} else {
+			s.btnRepeat.SetLabel(btnRepeat)
+		}
+		repeatX := shuffleX - 8
+		cview.Print(screen, "        ", repeatX, btnY-2, 8, cview.AlignLeft, colors.Shortcuts)
+		s.btnRepeat.SetRect(repeatX+1, btnY-2, 6, 1)
+		s.btnRepeat.Draw(screen)
+		cview.Print(screen, util.PackKeyBindingName(config.KeyBinds.Global.Repeat, 5),
+			repeatX+2, btnY-1, repeatX+7, cview.AlignLeft, colors.Shortcuts)
+
 	} else if showShuffleSmall {
 		s.btnShuffle.SetLabel("S")
 		shuffleX := x + w - volumeLen - 4
@@ -244,8 +296,118 @@ func (s *Status) Draw(screen tcell.Screen) {
 		cview.Print(screen, "   ", shuffleX, btnY-2, 3, cview.AlignLeft, colors.Shortcuts)
 		s.btnShuffle.SetRect(shuffleX+1, btnY-2, 1, 1)
 		s.btnShuffle.Draw(screen)
+
+		if s.state.Repeat == interfaces.RepeatModeOne {
+			s.btnRepeat.SetLabel("1")
+		} else {
+			s.btnRepeat.SetLabel("R")
+		}
+		repeatX := shuffleX - 2
+		cview.Print(screen, "  ", repeatX, btnY-2, 2, cview.AlignLeft, colors.Shortcuts)
+		s.btnRepeat.SetRect(repeatX+1, btnY-2, 1, 1)
+		s.btnRepeat.Draw(screen)
 	}
 	s.WriteStatus(screen, x+30, y)
+	if s.showSpectrum && w > 80 {
+		s.drawSpectrum(screen, x+w-spectrumBands-1, btnY)
+	}
+}
+
+func (s *Status) drawSpectrum(screen tcell.Screen, x, y int) {
+	colors := config.Color.Status
+	for i, v := range s.spectrum {
+		block := int(math.Round(v * float64(len(spectrumBlocks)-1)))
+		color := colors.Spectrum
+		if v >= spectrumPeakLevel {
+			color = colors.SpectrumPeak
+		}
+		cview.Print(screen, string(spectrumBlocks[block]), x+i, y, 1, cview.AlignLeft, color)
+	}
+}
+
+// toggleSpectrum shows or hides spectrum and returns whether it is shown.
+func (s *Status) toggleSpectrum() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.showSpectrum = !s.showSpectrum
+	for i := range s.spectrum {
+		s.spectrum[i] = 0
+	}
+	return s.showSpectrum
+}
+
+// updateSpectrum gets latest levels from player. Returns false if spectrum is hidden.
+func (s *Status) updateSpectrum() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.showSpectrum {
+		return false
+	}
+	s.player.Spectrum(s.spectrumLevels)
+	for i, v := range s.spectrumLevels {
+		s.spectrum[i] = math.Max(v, s.spectrum[i]-spectrumDecay)
+	}
+	return true
+}
+
+// stateLabel returns short description of player state, or empty string if state is obvious from
+// play button and progress bar.
+func stateLabel(state interfaces.AudioState) string {
+	switch state {
+	case interfaces.AudioStateLoading, interfaces.AudioStateBuffering, interfaces.AudioStatePaused:
+		return state.String() + " "
+	default:
+		return ""
+	}
+}
+
+// qualityLabel returns active quality profile and format of the stream that is playing, e.g.
+// 'mobile: mp3 128 kbps'.
+func qualityLabel(state interfaces.AudioStatus) string {
+	label := state.Quality
+	if state.Format == interfaces.AudioFormatNil {
+		return label
+	}
+	if label != "" {
+		label += ": "
+	}
+	label += state.Format.String()
+	if state.Bitrate > 0 {
+		label += fmt.Sprintf(" %d kbps", state.Bitrate)
+	}
+	return label
+}
+
+// shuffleLabel returns label for shuffle button, which shows shuffle mode unless mode is random.
+func shuffleLabel(mode interfaces.ShuffleMode) string {
+	switch mode {
+	case interfaces.ShuffleModeAlbum:
+		return "Album"
+	case interfaces.ShuffleModeArtist:
+		return "Artist"
+	case interfaces.ShuffleModeWeighted:
+		return "Weight"
+	default:
+		return btnShuffle
+	}
+}
+
+// sleepLabel returns short description of sleep timer, or empty string if timer is off.
+func sleepLabel(timer interfaces.SleepTimer) string {
+	switch timer.Mode {
+	case interfaces.SleepModeTimer:
+		left := int(time.Until(timer.At).Seconds())
+		if left < 0 {
+			left = 0
+		}
+		return "Sleep " + util.SecToString(left) + " "
+	case interfaces.SleepModeEndOfTrack:
+		return "Sleep: track "
+	case interfaces.SleepModeEndOfAlbum:
+		return "Sleep: album "
+	default:
+		return ""
+	}
 }
 
 func (s *Status) GetRect() (int, int, int, int) {
@@ -276,11 +438,15 @@ func (s *Status) GetFocusable() cview.Focusable {
 }
 
 func (s *Status) WriteStatus(screen tcell.Screen, x, y int) {
-	if s.state.State != interfaces.AudioStateStopped &&
+	w, _ := screen.Size()
+	notice := ""
+	if s.state.State == interfaces.AudioStateError || time.Now().Before(s.noticeUntil) {
+		notice = s.notice
+	}
+	if s.state.State.HasSong() &&
 		(s.state.Song != nil && s.state.Album != nil && s.state.Artist != nil) {
 		xi := x
 		x += 2
-		w, _ := screen.Size()
 		if s.state.Song.Favorite {
 			cview.Print(screen, charFavorite, x, y, 2, cview.AlignLeft, config.Color.TextSelected)
 			x += 3
@@ -291,9 +457,18 @@ func (s *Status) WriteStatus(screen tcell.Screen, x, y int) {
 		cview.Print(screen, effect(s.state.Artist.Name, "b")+" ", x, y, w, cview.AlignLeft, s.detailsMainColor)
 		x += len(s.state.Artist.Name) + 1
 		x = xi + 4
+		if notice != "" {
+			cview.Print(screen, notice, x, y+1, w, cview.AlignLeft, config.Color.Status.Error)
+			return
+		}
 		cview.Print(screen, s.state.Album.Name+" ", x, y+1, w, cview.AlignLeft, s.detailsMainColor)
 		x += len(s.state.Album.Name) + 1
-		cview.Print(screen, fmt.Sprintf("(%d)", s.state.Album.Year), x, y+1, w, cview.AlignLeft, s.detailsMainColor)
+		year := fmt.Sprintf("(%d) ", s.state.Album.Year)
+		cview.Print(screen, year, x, y+1, w, cview.AlignLeft, s.detailsMainColor)
+		x += len(year) + 1
+		cview.Print(screen, qualityLabel(s.state), x, y+1, w, cview.AlignLeft, config.Color.Status.Shortcuts)
+	} else if notice != "" {
+		cview.Print(screen, notice, x+2, y, w, cview.AlignLeft, config.Color.Status.Error)
 	}
 }
 
@@ -304,15 +479,21 @@ func (s *Status) UpdateState(state interfaces.AudioStatus, song *models.SongInfo
 	if state.Song != nil {
 		s.progress.SetMaximum(state.Song.Duration)
 	}
+	if state.State == interfaces.AudioStateError && state.Error != nil {
+		// keep showing error for a while after player has moved on to next song
+		s.notice = "Error: " + state.Error.Error()
+		s.noticeUntil = time.Now().Add(statusNoticeDuration)
+	}
 	s.state = state
 	s.DrawButtons()
 }
 
 func (s *Status) DrawButtons() {
-	if s.state.Paused || s.state.State == interfaces.AudioStateStopped {
-		s.btnPlay.SetLabel(btnPlay)
-	} else {
+	switch s.state.State {
+	case interfaces.AudioStateLoading, interfaces.AudioStateBuffering, interfaces.AudioStatePlaying:
 		s.btnPlay.SetLabel(btnPause)
+	default:
+		s.btnPlay.SetLabel(btnPlay)
 	}
 
 	if s.state.Shuffle {
@@ -323,4 +504,12 @@ func (s *Status) DrawButtons() {
 		s.btnShuffle.SetBackgroundColor(config.Color.Background)
 		s.btnShuffle.SetLabelColor(config.Color.Status.VolumeMuted)
 	}
+
+	if s.state.Repeat != interfaces.RepeatModeOff {
+		s.btnRepeat.SetBackgroundColor(config.Color.BackgroundSelected)
+		s.btnRepeat.SetLabelColor(config.Color.Text)
+	} else {
+		s.btnRepeat.SetBackgroundColor(config.Color.Background)
+		s.btnRepeat.SetLabelColor(config.Color.Status.VolumeMuted)
+	}
 }
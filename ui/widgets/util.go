@@ -200,3 +200,10 @@ func limit(value, lower, upper int) int {
 	}
 	return value
 }
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
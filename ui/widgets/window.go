@@ -39,13 +39,15 @@ type Window struct {
 	layout *twidgets.ModalLayout
 
 	// Widgets
-	navBar   *twidgets.NavBar
-	status   *Status
-	mediaNav *MediaNavigation
-	help     *modal.Help
-	message  *modal.Message
-	queue    *Queue
-	history  *History
+	navBar    *twidgets.NavBar
+	status    *Status
+	mediaNav  *MediaNavigation
+	help      *modal.Help
+	message   *modal.Message
+	equalizer *modal.Equalizer
+	resume    *modal.Confirm
+	queue     *Queue
+	history   *History
 
 	artistAlbumList *ArtistAlbumList
 	albumList       *AlbumList
@@ -75,6 +77,9 @@ type Window struct {
 
 	hasModal  bool
 	lastFocus cview.Primitive
+
+	// spectrumStop stops spectrum refresher, nil if it is not running
+	spectrumStop chan struct{}
 }
 
 func NewWindow(p interfaces.Player, i interfaces.ItemController, q interfaces.QueueController) Window {
@@ -147,11 +152,16 @@ func NewWindow(p interfaces.Player, i interfaces.ItemController, q interfaces.Qu
 	w.help.SetDoneFunc(w.wrapCloseModal(w.help))
 	w.message = modal.NewMessage()
 	w.message.SetDoneFunc(w.closeMessage)
+	w.equalizer = modal.NewEqualizer(func(enabled bool, gains []float64) {
+		go w.mediaPlayer.SetEqualizer(enabled, gains)
+	})
+	w.equalizer.SetDoneFunc(w.closeEqualizer)
 
-	w.queue = NewQueue()
+	w.queue = NewQueue(&w)
 	previousWidgets = append(previousWidgets, w.queue)
 	w.queue.clearFunc = w.clearQueue
 	w.queue.controller = w.mediaQueue
+	w.queue.songErrorFunc = w.mediaQueue.SongError
 	w.mediaQueue.AddQueueChangedCallback(func(songs []*models.Song) {
 		w.app.QueueUpdateDraw(func() {
 			index := w.queue.list.GetSelectedIndex()
@@ -161,6 +171,7 @@ func NewWindow(p interfaces.Player, i interfaces.ItemController, q interfaces.Qu
 	})
 
 	w.history = NewHistory()
+	w.history.songErrorFunc = w.mediaQueue.SongError
 	previousWidgets = append(previousWidgets, w.history)
 
 	w.mediaQueue.SetHistoryChangedCallback(func(songs []*models.Song) {
@@ -171,10 +182,10 @@ func NewWindow(p interfaces.Player, i interfaces.ItemController, q interfaces.Qu
 
 	w.layout.Grid().SetBackgroundColor(config.Color.Background)
 	w.mediaPlayer.AddStatusCallback(w.statusCb)
-	navBarLabels := []string{"Help", "Queue", "History", "Search"}
+	navBarLabels := []string{"Help", "Queue", "History", "Search", "Equalizer"}
 
 	sc := config.KeyBinds.NavigationBar
-	navBarShortucts := []tcell.Key{sc.Help, sc.Queue, sc.History, sc.Search}
+	navBarShortucts := []tcell.Key{sc.Help, sc.Queue, sc.History, sc.Search, sc.Equalizer}
 
 	for i, v := range navBarLabels {
 		btn := cview.NewButton(v)
@@ -190,6 +201,9 @@ func NewWindow(p interfaces.Player, i interfaces.ItemController, q interfaces.Qu
 		v.SetBackCallback(w.goBack)
 	}
 
+	if session, ok := w.mediaPlayer.SavedSession(); ok {
+		w.offerResume(session)
+	}
 	return w
 }
 
@@ -293,9 +307,29 @@ func (w *Window) mediaCtrl(event *tcell.EventKey) bool {
 	case ctrls.Shuffle:
 		shuffle := !w.status.state.Shuffle
 		go w.mediaPlayer.SetShuffle(shuffle)
+	case ctrls.ShuffleMode:
+		mode := w.status.state.ShuffleMode.Next()
+		go w.mediaPlayer.SetShuffleMode(mode)
+	case ctrls.Repeat:
+		repeat := w.status.state.Repeat.Next()
+		go w.mediaPlayer.SetRepeat(repeat)
 	case ctrls.MuteUnmute:
 		mute := !w.status.state.Muted
 		go w.mediaPlayer.SetMute(mute)
+	case ctrls.SpeedUp:
+		speed := w.status.state.Speed.Add(interfaces.AudioSpeedStep)
+		go w.mediaPlayer.SetSpeed(speed)
+	case ctrls.SpeedDown:
+		speed := w.status.state.Speed.Add(-interfaces.AudioSpeedStep)
+		go w.mediaPlayer.SetSpeed(speed)
+	case ctrls.SleepTimer:
+		mode, duration := nextSleepTimer(w.status.state.Sleep)
+		go w.mediaPlayer.SetSleepTimer(mode, duration)
+	case ctrls.Spectrum:
+		w.toggleSpectrum()
+	case ctrls.Quality:
+		profile := nextQualityProfile(w.status.state.Quality)
+		go w.mediaPlayer.SetQuality(profile)
 
 	default:
 		return false
@@ -303,6 +337,75 @@ func (w *Window) mediaCtrl(event *tcell.EventKey) bool {
 	return true
 }
 
+// spectrumRefresh is interval to redraw spectrum
+const spectrumRefresh = time.Millisecond * 50
+
+func (w *Window) toggleSpectrum() {
+	enabled := w.status.toggleSpectrum()
+	w.mediaPlayer.EnableSpectrum(enabled)
+	if enabled && w.spectrumStop == nil {
+		w.spectrumStop = make(chan struct{})
+		go w.refreshSpectrum(w.spectrumStop)
+	} else if !enabled && w.spectrumStop != nil {
+		close(w.spectrumStop)
+		w.spectrumStop = nil
+	}
+	w.app.QueueUpdateDraw(func() {})
+}
+
+// refreshSpectrum redraws spectrum until stop is closed or spectrum is hidden.
+func (w *Window) refreshSpectrum(stop chan struct{}) {
+	ticker := time.NewTicker(spectrumRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !w.status.updateSpectrum() {
+				return
+			}
+			w.app.QueueUpdateDraw(func() {})
+		}
+	}
+}
+
+// sleepTimerPresets are durations that sleep timer key cycles through, before end of track and end of album.
+var sleepTimerPresets = []time.Duration{time.Minute * 15, time.Minute * 30, time.Minute * 60}
+
+// nextSleepTimer returns next sleep timer, cycling off -> presets -> end of track -> end of album -> off.
+func nextSleepTimer(timer interfaces.SleepTimer) (interfaces.SleepMode, time.Duration) {
+	switch timer.Mode {
+	case interfaces.SleepModeOff:
+		return interfaces.SleepModeTimer, sleepTimerPresets[0]
+	case interfaces.SleepModeTimer:
+		for i, v := range sleepTimerPresets[:len(sleepTimerPresets)-1] {
+			if timer.Duration == v {
+				return interfaces.SleepModeTimer, sleepTimerPresets[i+1]
+			}
+		}
+		return interfaces.SleepModeEndOfTrack, 0
+	case interfaces.SleepModeEndOfTrack:
+		return interfaces.SleepModeEndOfAlbum, 0
+	default:
+		return interfaces.SleepModeOff, 0
+	}
+}
+
+// nextQualityProfile returns name of quality profile after given one.
+func nextQualityProfile(current string) string {
+	profiles := config.AppConfig.Player.QualityProfiles
+	for i, v := range profiles {
+		if v.Name == current {
+			return profiles[(i+1)%len(profiles)].Name
+		}
+	}
+	if len(profiles) == 0 {
+		return ""
+	}
+	return profiles[0].Name
+}
+
 func (w *Window) navBarCtrl(key tcell.Key) bool {
 	navBar := config.KeyBinds.NavigationBar
 	switch key {
@@ -331,6 +434,12 @@ func (w *Window) navBarCtrl(key tcell.Key) bool {
 		for _, v := range items {
 			duration += v.Duration
 		}
+	case navBar.Equalizer:
+		if w.hasModal {
+			return false
+		}
+		w.equalizer.Load()
+		w.showModal(w.equalizer, 20, 60, false)
 	case navBar.Dump:
 		w.debugDump()
 	default:
@@ -448,6 +557,14 @@ func (w *Window) closeHelp() {
 	w.app.SetFocus(w.layout)
 }
 
+func (w *Window) closeEqualizer() {
+	w.closeModal(w.equalizer)
+	err := config.SaveConfig()
+	if err != nil {
+		logrus.Errorf("save equalizer settings: %v", err)
+	}
+}
+
 func (w *Window) wrapCloseModal(modal modal.Modal) func() {
 	return func() {
 		w.closeModal(modal)
@@ -829,6 +946,31 @@ func (w *Window) showMessage(msg string, height, width int, lockSize bool) {
 	w.showModal(w.message, uint(height), uint(width), lockSize)
 }
 
+// offerResume asks whether to resume session from previous run. Declining discards the session.
+func (w *Window) offerResume(session *models.Session) {
+	w.resume = modal.NewConfirm("Resume", func(accepted bool) {
+		if !accepted {
+			w.mediaPlayer.DiscardSession()
+			return
+		}
+		go func() {
+			err := w.mediaPlayer.ResumeSession()
+			if err != nil {
+				logrus.Errorf("resume session: %v", err)
+			}
+		}()
+	})
+	w.resume.SetDoneFunc(w.wrapCloseModal(w.resume))
+
+	text := fmt.Sprintf("Resume previous session?\n\n%d songs in queue", len(session.Queue))
+	if len(session.Queue) > 0 {
+		text += fmt.Sprintf("\n%s at %s", session.Queue[0].Name,
+			util.SecToString(int(session.Position.Seconds())))
+	}
+	w.resume.SetQuestion(text)
+	w.showModal(w.resume, 10, 50, false)
+}
+
 func (w *Window) selectGenre(id models.IdName) {
 
 	albums, err := w.mediaItems.GetGenreAlbums(id)